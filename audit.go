@@ -0,0 +1,371 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ---------- access log / audit trail ----------
+
+const (
+	accessLogName   = "access.jsonl" // next to the stack's process logs
+	maxCapturedBody = 64 << 10
+	redactedValue   = "[REDACTED]"
+)
+
+var defaultRedactFields = []string{"password", "passwd", "secret", "token", "api_key", "apikey", "authorization", "access_token", "refresh_token"}
+
+// accessRecord is one JSON line in .mcp-launch/logs/<stack>/access.jsonl.
+type accessRecord struct {
+	Time        string          `json:"ts"`
+	Stack       string          `json:"stack"`
+	Server      string          `json:"server,omitempty"`
+	OperationID string          `json:"operation_id,omitempty"`
+	Method      string          `json:"method"`
+	Path        string          `json:"path"`
+	Status      int             `json:"status"`
+	LatencyMS   float64         `json:"latency_ms"`
+	ReqBytes    int64           `json:"request_bytes"`
+	RespBytes   int64           `json:"response_bytes"`
+	ClientIP    string          `json:"client_ip,omitempty"`
+	Country     string          `json:"country,omitempty"` // CF-IPCountry
+	Ray         string          `json:"cf_ray,omitempty"`
+	KeyID       string          `json:"key_id,omitempty"` // sha256 prefix of the X-API-Key used
+	ReqBody     json.RawMessage `json:"request_body,omitempty"`
+	RespBody    json.RawMessage `json:"response_body,omitempty"`
+}
+
+// accessLogConfig controls what the front proxy records.
+type accessLogConfig struct {
+	CaptureBodies bool
+	Redact        []string // field names (case-insensitive) replaced with [REDACTED] in captured JSON
+}
+
+func accessLogPath(stack string) string {
+	return filepath.Join(logsDir(), stack, accessLogName)
+}
+
+// newAccessLog opens the stack's access log (JSON lines, rotated like the process logs).
+func newAccessLog(stack string) *rotatingLog {
+	return &rotatingLog{path: accessLogPath(stack), raw: true}
+}
+
+// accessRecorder captures status, size and (optionally) the body of a response.
+type accessRecorder struct {
+	http.ResponseWriter
+	status  int
+	bytes   int64
+	capture bool
+	body    bytes.Buffer
+}
+
+func (a *accessRecorder) WriteHeader(code int) {
+	if a.status == 0 {
+		a.status = code
+	}
+	a.ResponseWriter.WriteHeader(code)
+}
+
+func (a *accessRecorder) Write(p []byte) (int, error) {
+	if a.status == 0 {
+		a.status = http.StatusOK
+	}
+	n, err := a.ResponseWriter.Write(p)
+	a.bytes += int64(n)
+	if a.capture && a.body.Len() < maxCapturedBody {
+		a.body.Write(p[:min(n, maxCapturedBody-a.body.Len())])
+	}
+	return n, err
+}
+
+func (a *accessRecorder) Unwrap() http.ResponseWriter { return a.ResponseWriter }
+
+func (a *accessRecorder) Flush() {
+	if f, ok := a.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// withAccessLog wraps the proxy's handler so every request lands in the stack's access log.
+func (f *frontProxy) withAccessLog(next http.Handler, sink *rotatingLog, cfg accessLogConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		var reqBody []byte
+		if cfg.CaptureBodies && r.Body != nil {
+			reqBody, _ = io.ReadAll(io.LimitReader(r.Body, maxCapturedBody))
+			rest := r.Body
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(reqBody), rest), rest}
+		}
+		cr := &countingReader{ReadCloser: r.Body}
+		if r.Body != nil {
+			r.Body = cr
+		}
+		rec := &accessRecorder{ResponseWriter: w, capture: cfg.CaptureBodies}
+		next.ServeHTTP(rec, r)
+
+		p := path.Clean("/" + r.URL.Path)
+		ar := accessRecord{
+			Time:        start.UTC().Format(time.RFC3339Nano),
+			Stack:       f.name,
+			Server:      serverFromPath(p),
+			OperationID: f.operationFor(r.Method, p),
+			Method:      r.Method,
+			Path:        p,
+			Status:      rec.status,
+			LatencyMS:   float64(time.Since(start).Microseconds()) / 1000,
+			ReqBytes:    max(cr.n, r.ContentLength),
+			RespBytes:   rec.bytes,
+			ClientIP:    clientIP(r),
+			Country:     r.Header.Get("CF-IPCountry"),
+			Ray:         r.Header.Get("CF-Ray"),
+			KeyID:       keyID(requestKey(r)),
+		}
+		if cfg.CaptureBodies {
+			ar.ReqBody = capturedBody(reqBody, ar.ReqBytes, cfg.Redact)
+			ar.RespBody = capturedBody(rec.body.Bytes(), ar.RespBytes, cfg.Redact)
+		}
+		line, _ := json.Marshal(ar)
+		sink.WriteLine(string(line))
+	})
+}
+
+// operationFor resolves the operationId of a route from the merged spec.
+func (f *frontProxy) operationFor(method, p string) string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.ops[routeKey(method, p)]
+}
+
+// operationIndex maps routeKey → operationId for every operation in a merged spec.
+func operationIndex(spec []byte) map[string]string {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil
+	}
+	out := map[string]string{}
+	for p, item := range doc.Paths {
+		for method, raw := range item {
+			if _, ok := httpMethods[strings.ToLower(method)]; !ok {
+				continue
+			}
+			var op struct {
+				OperationID string `json:"operationId"`
+			}
+			if json.Unmarshal(raw, &op) == nil && op.OperationID != "" {
+				out[routeKey(method, p)] = op.OperationID
+			}
+		}
+	}
+	return out
+}
+
+// serverFromPath returns the MCP server segment of /<server>/..., skipping our own endpoints.
+func serverFromPath(p string) string {
+	seg, _, _ := strings.Cut(strings.TrimPrefix(p, "/"), "/")
+	switch seg {
+	case "", "openapi.json", "healthz", "readyz", "metrics", "docs", "mcp":
+		return ""
+	}
+	return seg
+}
+
+// clientIP prefers Cloudflare's header, then the first X-Forwarded-For hop, then the peer.
+func clientIP(r *http.Request) string {
+	if ip := r.Header.Get("CF-Connecting-IP"); ip != "" {
+		return ip
+	}
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		first, _, _ := strings.Cut(xff, ",")
+		return strings.TrimSpace(first)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// keyID identifies which key was used without recording the key itself.
+func keyID(key string) string {
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// capturedBody returns redacted JSON, or the raw text as a JSON string for non-JSON bodies.
+// A body cut off at maxCapturedBody cannot be parsed, and so not redacted either; it is only
+// recorded as {"truncated":true,"bytes":total}.
+func capturedBody(b []byte, total int64, redact []string) json.RawMessage {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	if int64(len(b)) < total {
+		return mustJSON(map[string]any{"truncated": true, "bytes": total})
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		s, _ := json.Marshal(string(b))
+		return s
+	}
+	redactFields(v, redact)
+	out, _ := json.Marshal(v)
+	return out
+}
+
+func redactFields(v any, fields []string) {
+	switch n := v.(type) {
+	case map[string]any:
+		for k, child := range n {
+			if slices.ContainsFunc(fields, func(f string) bool { return strings.EqualFold(f, k) }) {
+				n[k] = redactedValue
+				continue
+			}
+			redactFields(child, fields)
+		}
+	case []any:
+		for _, child := range n {
+			redactFields(child, fields)
+		}
+	}
+}
+
+// ---------- `mcp-launch audit` ----------
+
+func cmdAudit() {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	fs.Usage = func() { helpTopic("audit") }
+	stack := fs.String("stack", "", "Only this stack")
+	tool := fs.String("tool", "", "Only calls whose operationId or server matches this glob")
+	since := fs.Duration("since", 0, "Only records newer than this (e.g. 1h)")
+	fromS := fs.String("from", "", "Only records at/after this RFC3339 time")
+	toS := fs.String("to", "", "Only records before this RFC3339 time")
+	asJSON := fs.Bool("json", false, "Print matching records as JSON lines")
+	_ = fs.Parse(os.Args[2:])
+
+	var from, to time.Time
+	if *since > 0 {
+		from = time.Now().Add(-*since)
+	}
+	for _, t := range []struct {
+		raw string
+		dst *time.Time
+	}{{*fromS, &from}, {*toS, &to}} {
+		if t.raw == "" {
+			continue
+		}
+		v, err := time.Parse(time.RFC3339, t.raw)
+		if err != nil {
+			fmt.Println("Invalid time:", err)
+			os.Exit(2)
+		}
+		*t.dst = v
+	}
+
+	var recs []accessRecord
+	stacks, _ := os.ReadDir(logsDir())
+	for _, sd := range stacks {
+		if !sd.IsDir() || (*stack != "" && sd.Name() != *stack) {
+			continue
+		}
+		for _, p := range rotatedFiles(accessLogPath(sd.Name())) {
+			recs = append(recs, readAccessRecords(p)...)
+		}
+	}
+	recs = slices.DeleteFunc(recs, func(r accessRecord) bool {
+		at, err := r.at()
+		if err != nil || (!from.IsZero() && at.Before(from)) || (!to.IsZero() && !at.Before(to)) {
+			return true
+		}
+		if *tool != "" {
+			okOp, _ := path.Match(*tool, r.OperationID)
+			okSrv, _ := path.Match(*tool, r.Server)
+			return !okOp && !okSrv
+		}
+		return false
+	})
+	sortAccessRecords(recs)
+
+	if *asJSON {
+		for _, r := range recs {
+			b, _ := json.Marshal(r)
+			fmt.Println(string(b))
+		}
+		return
+	}
+	if len(recs) == 0 {
+		fmt.Println("No matching access records.")
+		return
+	}
+	for _, r := range recs {
+		at, _ := r.at()
+		op := r.OperationID
+		if op == "" {
+			op = r.Method + " " + r.Path
+		}
+		fmt.Printf("%s  %-12s %-40s %3d %8.1fms  %s\n", at.Local().Format("2006-01-02 15:04:05"), r.Stack, op, r.Status, r.LatencyMS, r.ClientIP)
+	}
+}
+
+func (r accessRecord) at() (time.Time, error) { return time.Parse(time.RFC3339Nano, r.Time) }
+
+// sortAccessRecords orders records by time. RFC3339Nano drops trailing zeros, so the strings
+// themselves do not sort in time order.
+func sortAccessRecords(recs []accessRecord) {
+	slices.SortStableFunc(recs, func(a, b accessRecord) int {
+		ta, _ := a.at()
+		tb, _ := b.at()
+		return ta.Compare(tb)
+	})
+}
+
+func readAccessRecords(p string) []accessRecord {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var out []accessRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64<<10), 4*maxCapturedBody)
+	for sc.Scan() {
+		var r accessRecord
+		if json.Unmarshal(sc.Bytes(), &r) == nil {
+			out = append(out, r)
+		}
+	}
+	return out
+}
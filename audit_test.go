@@ -0,0 +1,51 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCapturedBody(t *testing.T) {
+	big := `{"password":"hunter2","pad":"` + strings.Repeat("x", maxCapturedBody) + `"}`
+	tests := []struct {
+		name  string
+		body  string
+		total int64
+		want  string
+	}{
+		{"empty", "", 0, ""},
+		{"json redacted", `{"user":"a","password":"p","nested":[{"Token":"t"}]}`, 52, `{"nested":[{"Token":"[REDACTED]"}],"password":"[REDACTED]","user":"a"}`},
+		{"text kept", "plain text", 10, `"plain text"`},
+		{"truncated json", big[:maxCapturedBody], int64(len(big)), `{"bytes":` + strconv.Itoa(len(big)) + `,"truncated":true}`},
+		{"truncated text", "secret=abc", 100, `{"bytes":100,"truncated":true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(capturedBody([]byte(tt.body), tt.total, []string{"password", "token"}))
+			if got != tt.want {
+				t.Errorf("capturedBody = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortAccessRecords(t *testing.T) {
+	// As strings, "…:05Z" sorts after "…:05.5Z" although it is earlier.
+	recs := []accessRecord{
+		{Time: "2026-10-16T10:00:05.5Z", Path: "/b"},
+		{Time: "2026-10-16T10:00:05Z", Path: "/a"},
+		{Time: "2026-10-16T10:00:05.25Z", Path: "/c"},
+		{Time: "2026-10-16T09:59:59.999999999Z", Path: "/first"},
+	}
+	sortAccessRecords(recs)
+	var got []string
+	for _, r := range recs {
+		got = append(got, r.Path)
+	}
+	if want := "/first /a /c /b"; strings.Join(got, " ") != want {
+		t.Errorf("order = %v, want %s", got, want)
+	}
+}
@@ -0,0 +1,548 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ---------- native backend (`mcp-launch bridge`) ----------
+//
+// `up --backend native` runs `mcp-launch bridge` where it would run mcpo. The bridge keeps
+// mcpo's contract with the rest of mcp-launch: it listens on the stack's mcpo port, checks the
+// same API key, answers /docs once started, serves /<server>/openapi.json shaped like mcpo's
+// (one POST /<tool> per tool, request body from the tool's input schema) and translates
+// POST /<server>/<tool> into tools/call. Its stdio servers are its children, in the process
+// group `up` kills; sse and streamable-http servers are dialed directly (remote.go).
+
+const (
+	backendMcpo   = "mcpo"
+	backendNative = "native"
+
+	bridgeKeyEnv      = "MCP_LAUNCH_BRIDGE_KEY" // the API key, kept off the command line
+	bridgeInitTimeout = 60 * time.Second
+)
+
+func parseBackend(s string) (string, error) {
+	switch s {
+	case "", backendMcpo:
+		return backendMcpo, nil
+	case backendNative:
+		return backendNative, nil
+	}
+	return "", fmt.Errorf("unknown backend %q (expected mcpo or native)", s)
+}
+
+// bridgeServer is one MCP server behind the bridge.
+type bridgeServer struct {
+	name string
+	conf MCPServer // as configured, to tell edits apart on reload
+
+	mu          sync.Mutex
+	client      *mcpClient
+	tools       []mcpTool
+	err         error     // last connect error
+	lastAttempt time.Time // throttles reconnects
+	closed      bool
+}
+
+type bridge struct {
+	apiKey string
+	logw   io.Writer
+
+	mu      sync.RWMutex
+	servers map[string]*bridgeServer
+}
+
+func cmdBridge() {
+	fs := flag.NewFlagSet("bridge", flag.ExitOnError)
+	fs.Usage = func() { helpTopic("bridge") }
+	config := fs.String("config", "", "Config file (default: mcp.config.json / .yaml / .toml)")
+	port := fs.Int("port", defaultMcpoPort, "Port to listen on (127.0.0.1)")
+	apiKey := fs.String("api-key", os.Getenv(bridgeKeyEnv), "API key callers must send as X-API-Key (default: $"+bridgeKeyEnv+")")
+	hotReload := fs.Bool("hot-reload", false, "Apply config file edits while running")
+	_ = fs.Parse(os.Args[2:])
+	if *config == "" {
+		*config = defaultConfigPath()
+	}
+	cfg, err := loadConfig(*config)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	b := &bridge{apiKey: *apiKey, logw: os.Stderr, servers: map[string]*bridgeServer{}}
+	b.apply(cfg.enabled())
+	srv := &http.Server{Addr: fmt.Sprintf("127.0.0.1:%d", *port), Handler: b}
+	if *hotReload {
+		go b.watch(*config)
+	}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}()
+	fmt.Printf("mcp-launch bridge listening on http://%s\n", srv.Addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Println(err)
+		b.closeAll()
+		os.Exit(1)
+	}
+	b.closeAll()
+}
+
+// apply starts servers that are new or changed and stops those removed or changed, then waits
+// for the new ones to finish their handshake.
+func (b *bridge) apply(cfg MCPConfig) {
+	b.mu.Lock()
+	var stop, start []*bridgeServer
+	for name, bs := range b.servers {
+		if s, ok := cfg.MCPServers[name]; !ok || !reflect.DeepEqual(s, bs.conf) {
+			stop = append(stop, bs)
+			delete(b.servers, name)
+		}
+	}
+	for name, s := range cfg.MCPServers {
+		if _, ok := b.servers[name]; !ok {
+			bs := &bridgeServer{name: name, conf: s}
+			b.servers[name] = bs
+			start = append(start, bs)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, bs := range stop {
+		bs.close()
+		fmt.Fprintf(b.logw, "[%s] stopped\n", bs.name)
+	}
+	var wg sync.WaitGroup
+	for _, bs := range start {
+		wg.Add(1)
+		go func(bs *bridgeServer) {
+			defer wg.Done()
+			bs.mu.Lock()
+			defer bs.mu.Unlock()
+			if err := bs.connect(b.logw); err != nil {
+				fmt.Fprintf(b.logw, "[%s] failed to connect: %v\n", bs.name, err)
+			}
+		}(bs)
+	}
+	wg.Wait()
+}
+
+// watch re-applies the config when the file changes, like mcpo --hot-reload.
+func (b *bridge) watch(path string) {
+	last, _ := statConfig(path)
+	for range time.Tick(2 * time.Second) {
+		cur, ok := statConfig(path)
+		if !ok || cur == last {
+			continue
+		}
+		last = cur
+		cfg, err := loadConfig(path)
+		if err != nil {
+			fmt.Fprintf(b.logw, "config reload failed: %v\n", err)
+			continue
+		}
+		fmt.Fprintln(b.logw, "config changed; reloading servers")
+		b.apply(cfg.enabled())
+	}
+}
+
+func (b *bridge) closeAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, bs := range b.servers {
+		bs.close()
+	}
+}
+
+// connect (re)starts or dials the server and lists its tools; the caller holds bs.mu.
+func (bs *bridgeServer) connect(logw io.Writer) error {
+	bs.lastAttempt = time.Now()
+	c, err := dialMCP(bs.name, bs.conf, logw, func() { bs.refreshTools(logw) })
+	if err != nil {
+		bs.err = err
+		return err
+	}
+	timeout, _ := bs.conf.connectPolicy()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		c.Close()
+		bs.err = err
+		return err
+	}
+	bs.client, bs.tools, bs.err = c, tools, nil
+	verb := "started"
+	if bs.conf.isRemote() {
+		verb = "connected to"
+	}
+	fmt.Fprintf(logw, "[%s] %s %s %s: %d tool(s)\n", bs.name, verb, c.ServerName, c.ServerVersion, len(tools))
+	return nil
+}
+
+func (bs *bridgeServer) refreshTools(logw io.Writer) {
+	bs.mu.Lock()
+	c := bs.client
+	bs.mu.Unlock()
+	if c == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), bridgeInitTimeout)
+	defer cancel()
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		fmt.Fprintf(logw, "[%s] tools changed but could not be listed: %v\n", bs.name, err)
+		return
+	}
+	bs.mu.Lock()
+	bs.tools = tools
+	bs.mu.Unlock()
+	fmt.Fprintf(logw, "[%s] tools changed: %d tool(s)\n", bs.name, len(tools))
+}
+
+// session returns a live client, restarting a server that exited or redialing a lost remote one (at most every 5s).
+func (bs *bridgeServer) session(logw io.Writer) (*mcpClient, []mcpTool, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.closed {
+		return nil, nil, errors.New("server was removed")
+	}
+	if bs.client != nil && bs.client.Alive() {
+		return bs.client, bs.tools, nil
+	}
+	if bs.client != nil {
+		bs.err, bs.client = bs.client.err, nil
+		fmt.Fprintf(logw, "[%s] %v\n", bs.name, bs.err)
+	}
+	if time.Since(bs.lastAttempt) < 5*time.Second {
+		return nil, nil, bs.err
+	}
+	if err := bs.connect(logw); err != nil {
+		return nil, nil, err
+	}
+	return bs.client, bs.tools, nil
+}
+
+func (bs *bridgeServer) close() {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.closed = true
+	if bs.client != nil {
+		bs.client.Close()
+		bs.client = nil
+	}
+}
+
+// ---------- bridge HTTP ----------
+
+func (b *bridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) == 1 && parts[0] == "docs" {
+		b.serveDocs(w)
+		return
+	}
+	if b.apiKey != "" && subtle.ConstantTimeCompare([]byte(requestKey(r)), []byte(b.apiKey)) != 1 {
+		writeDetail(w, http.StatusUnauthorized, "Invalid API key")
+		return
+	}
+	b.mu.RLock()
+	bs := b.servers[parts[0]]
+	b.mu.RUnlock()
+	if bs == nil || len(parts) != 2 {
+		writeDetail(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	client, tools, err := bs.session(b.logw)
+	if err != nil {
+		writeDetail(w, http.StatusServiceUnavailable, fmt.Sprintf("server %s: %v", bs.name, err))
+		return
+	}
+	if parts[1] == "openapi.json" && r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, bridgeSpec(bs.name, client, tools))
+		return
+	}
+	i := slices.IndexFunc(tools, func(t mcpTool) bool { return t.Name == parts[1] })
+	if i < 0 {
+		writeDetail(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeDetail(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	b.callTool(w, r, client, tools[i])
+}
+
+func (b *bridge) callTool(w http.ResponseWriter, r *http.Request, client *mcpClient, tool mcpTool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeDetail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		body = []byte("{}")
+	}
+	var args map[string]any
+	if err := json.Unmarshal(body, &args); err != nil || args == nil {
+		writeJSON(w, http.StatusUnprocessableEntity, validationError([]string{"body"}, "model_attributes_type",
+			"Input should be a valid dictionary or object to extract fields from"))
+		return
+	}
+	var schema struct {
+		Required []string `json:"required"`
+	}
+	_ = json.Unmarshal(tool.InputSchema, &schema)
+	for _, k := range schema.Required {
+		if _, ok := args[k]; !ok {
+			writeJSON(w, http.StatusUnprocessableEntity, validationError([]string{"body", k}, "missing", "Field required"))
+			return
+		}
+	}
+
+	res, err := client.CallTool(r.Context(), tool.Name, body)
+	var rpcErr *rpcError
+	switch {
+	case errors.As(err, &rpcErr):
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"detail": map[string]any{"message": rpcErr.Message, "data": rpcErr.Data}})
+	case err != nil:
+		writeDetail(w, http.StatusBadGateway, err.Error())
+	case res.IsError:
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"detail": map[string]any{"message": contentText(res.Content)}})
+	default:
+		writeJSON(w, http.StatusOK, toolResponse(res))
+	}
+}
+
+// toolResponse shapes a tools/call result the way mcpo returns it: structured content if any,
+// else each content item (text parsed as JSON when it is JSON), unwrapped when there is one.
+func toolResponse(res *mcpCallResult) any {
+	if len(res.StructuredContent) > 0 {
+		return res.StructuredContent
+	}
+	out := make([]any, 0, len(res.Content))
+	for _, c := range res.Content {
+		switch c.Type {
+		case "text":
+			var v any
+			if json.Unmarshal([]byte(c.Text), &v) == nil {
+				out = append(out, v)
+			} else {
+				out = append(out, c.Text)
+			}
+		case "image", "audio":
+			out = append(out, "data:"+c.MimeType+";base64,"+c.Data)
+		case "resource":
+			out = append(out, c.Resource)
+		default:
+			out = append(out, c)
+		}
+	}
+	if len(out) == 1 {
+		return out[0]
+	}
+	return out
+}
+
+func contentText(content []mcpContent) string {
+	var parts []string
+	for _, c := range content {
+		if c.Type == "text" {
+			parts = append(parts, c.Text)
+		}
+	}
+	if len(parts) == 0 {
+		return "tool reported an error"
+	}
+	return strings.Join(parts, "\n")
+}
+
+func writeDetail(w http.ResponseWriter, code int, detail string) {
+	writeJSON(w, code, map[string]any{"detail": detail})
+}
+
+// validationError is FastAPI's 422 body, which mcpo clients already handle.
+func validationError(loc []string, typ, msg string) map[string]any {
+	return map[string]any{"detail": []any{map[string]any{"type": typ, "loc": loc, "msg": msg}}}
+}
+
+func (b *bridge) serveDocs(w http.ResponseWriter) {
+	b.mu.RLock()
+	names := make([]string, 0, len(b.servers))
+	for name := range b.servers {
+		names = append(names, name)
+	}
+	b.mu.RUnlock()
+	slices.Sort(names)
+	var sb strings.Builder
+	sb.WriteString("<!doctype html><title>mcp-launch bridge</title><h1>MCP servers</h1><ul>")
+	for _, name := range names {
+		n := html.EscapeString(name)
+		fmt.Fprintf(&sb, `<li><a href="/%s/openapi.json">%s</a></li>`, n, n)
+	}
+	sb.WriteString("</ul>")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = io.WriteString(w, sb.String())
+}
+
+// ---------- bridge OpenAPI ----------
+
+// bridgeSpec builds a server's OpenAPI document in mcpo's shape: POST /<tool> with a
+// "<tool>_form_model" request body and FastAPI's 422 response.
+func bridgeSpec(name string, c *mcpClient, tools []mcpTool) map[string]any {
+	schemas := map[string]any{
+		"ValidationError": map[string]any{
+			"title":    "ValidationError",
+			"type":     "object",
+			"required": []any{"loc", "msg", "type"},
+			"properties": map[string]any{
+				"loc":  map[string]any{"title": "Location", "type": "array", "items": map[string]any{"anyOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "integer"}}}},
+				"msg":  map[string]any{"title": "Message", "type": "string"},
+				"type": map[string]any{"title": "Error Type", "type": "string"},
+			},
+		},
+		"HTTPValidationError": map[string]any{
+			"title": "HTTPValidationError",
+			"type":  "object",
+			"properties": map[string]any{
+				"detail": map[string]any{"title": "Detail", "type": "array", "items": map[string]any{"$ref": "#/components/schemas/ValidationError"}},
+			},
+		},
+	}
+	paths := map[string]any{}
+	for _, t := range tools {
+		model := sanitizeForID(t.Name) + "_form_model"
+		input := schemaObject(t.InputSchema)
+		input["title"] = model
+		if _, ok := input["type"]; !ok {
+			input["type"] = "object"
+		}
+		schemas[model] = hoistDefs(input, model, schemas)
+
+		summary := t.Title
+		if summary == "" {
+			summary = titleCase(t.Name)
+		}
+		var output any = map[string]any{"title": "Response " + summary}
+		if len(t.OutputSchema) > 0 {
+			out := sanitizeForID(t.Name) + "_output"
+			schemas[out] = hoistDefs(schemaObject(t.OutputSchema), out, schemas)
+			output = map[string]any{"$ref": "#/components/schemas/" + out}
+		}
+		paths["/"+t.Name] = map[string]any{
+			"post": map[string]any{
+				"summary":     summary,
+				"description": t.Description,
+				"operationId": sanitizeForID(t.Name),
+				"requestBody": map[string]any{
+					"required": true,
+					"content":  map[string]any{"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/" + model}}},
+				},
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "Successful Response",
+						"content":     map[string]any{"application/json": map[string]any{"schema": output}},
+					},
+					"422": map[string]any{
+						"description": "Validation Error",
+						"content":     map[string]any{"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/HTTPValidationError"}}},
+					},
+				},
+			},
+		}
+	}
+	title, version := c.ServerName, c.ServerVersion
+	if title == "" {
+		title = name
+	}
+	if version == "" {
+		version = "1.0.0"
+	}
+	return map[string]any{
+		"openapi":    "3.1.0",
+		"info":       map[string]any{"title": title, "description": name + " MCP Server", "version": version},
+		"servers":    []any{map[string]any{"url": "/" + name}},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}
+}
+
+func schemaObject(raw json.RawMessage) map[string]any {
+	var m map[string]any
+	if json.Unmarshal(raw, &m) != nil || m == nil {
+		m = map[string]any{}
+	}
+	return m
+}
+
+// hoistDefs moves a JSON Schema's $defs/definitions into components.schemas as "<prefix>_<def>"
+// and points its "#/$defs/..." (and root "#") refs there, since the schema no longer sits at a
+// document root once it is embedded in OpenAPI.
+func hoistDefs(schema map[string]any, prefix string, components map[string]any) map[string]any {
+	defs := map[string]any{}
+	for _, key := range []string{"$defs", "definitions"} {
+		if m, ok := schema[key].(map[string]any); ok {
+			for k, v := range m {
+				defs[k] = v
+			}
+			delete(schema, key)
+		}
+	}
+	var rewrite func(v any)
+	rewrite = func(v any) {
+		switch n := v.(type) {
+		case map[string]any:
+			if ref, ok := n["$ref"].(string); ok {
+				switch {
+				case ref == "#":
+					n["$ref"] = "#/components/schemas/" + prefix
+				case strings.HasPrefix(ref, "#/$defs/"), strings.HasPrefix(ref, "#/definitions/"):
+					n["$ref"] = "#/components/schemas/" + prefix + "_" + sanitizeForID(ref[strings.LastIndexByte(ref, '/')+1:])
+				}
+			}
+			for _, c := range n {
+				rewrite(c)
+			}
+		case []any:
+			for _, c := range n {
+				rewrite(c)
+			}
+		}
+	}
+	rewrite(schema)
+	for k, v := range defs {
+		rewrite(v)
+		components[prefix+"_"+sanitizeForID(k)] = v
+	}
+	return schema
+}
+
+// titleCase turns a tool name like "read_file" into "Read File", as mcpo's summaries do.
+func titleCase(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == '.' })
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
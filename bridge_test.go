@@ -0,0 +1,39 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBridgeAPIKey(t *testing.T) {
+	b := &bridge{apiKey: "k3y", logw: io.Discard, servers: map[string]*bridgeServer{}}
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"no key", nil, http.StatusUnauthorized},
+		{"wrong key", map[string]string{"X-API-Key": "nope"}, http.StatusUnauthorized},
+		{"key prefix", map[string]string{"X-API-Key": "k3"}, http.StatusUnauthorized},
+		{"X-API-Key", map[string]string{"X-API-Key": "k3y"}, http.StatusNotFound},
+		{"bearer", map[string]string{"Authorization": "Bearer k3y"}, http.StatusNotFound},
+		{"bearer without scheme", map[string]string{"Authorization": "k3y"}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/missing/tool", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			b.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
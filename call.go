@@ -0,0 +1,706 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ---------- `mcp-launch tools` / `mcp-launch call` ----------
+//
+// Both read a running stack's merged spec (from its front proxy, else the last one written to
+// .mcp-launch/openapi_<stack>.json). `call` builds the request for one operation from --arg and
+// --json, checks it against the operation's schemas and sends it through the front proxy with
+// the stack's key, so it exercises the same path a Custom GPT does.
+
+// specOperation is one operation of a merged spec.
+type specOperation struct {
+	ID, Method, Path, Summary string
+	Params                    []specParam
+	Body                      map[string]any // application/json request body schema; nil if none
+	Response                  map[string]any // application/json schema of the first 2xx response; nil if none
+}
+
+type specParam struct {
+	Name, In string // in: path | query | header
+	Required bool
+	Schema   map[string]any
+}
+
+// stackSpec is a parsed merged spec.
+type stackSpec struct {
+	doc map[string]any // whole document, for $ref lookups
+	ops []specOperation
+}
+
+// loadStackSpec fetches a stack's merged spec from its front proxy, falling back to the file.
+func loadStackSpec(inst Instance) (*stackSpec, error) {
+	data, err := fetchFrontSpec(inst)
+	if err != nil {
+		var ferr error
+		if data, ferr = os.ReadFile(filepath.Join(getStateDir(), fmt.Sprintf("openapi_%s.json", inst.Name))); ferr != nil {
+			return nil, fmt.Errorf("no merged spec for %s: %v", inst.Name, err)
+		}
+	}
+	return parseStackSpec(data)
+}
+
+func fetchFrontSpec(inst Instance) ([]byte, error) {
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/openapi.json", inst.FrontPort), nil)
+	if keys := acceptedKeys(inst); len(keys) > 0 {
+		req.Header.Set("X-API-Key", keys[0])
+	}
+	resp, err := (&http.Client{Timeout: healthProbeTimeout}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("front proxy /openapi.json: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func parseStackSpec(data []byte) (*stackSpec, error) {
+	s := &stackSpec{}
+	if err := json.Unmarshal(data, &s.doc); err != nil {
+		return nil, fmt.Errorf("merged spec: %w", err)
+	}
+	paths, _ := s.doc["paths"].(map[string]any)
+	for p, item := range paths {
+		methods, _ := item.(map[string]any)
+		for method, raw := range methods {
+			op, _ := raw.(map[string]any)
+			if _, ok := httpMethods[strings.ToLower(method)]; !ok || op == nil {
+				continue
+			}
+			so := specOperation{Method: strings.ToUpper(method), Path: p}
+			so.ID, _ = op["operationId"].(string)
+			if so.ID == "" {
+				continue
+			}
+			// mcpo's summary is the title-cased tool name; the description says what it does.
+			so.Summary, _ = op["description"].(string)
+			if so.Summary == "" {
+				so.Summary, _ = op["summary"].(string)
+			}
+			params, _ := op["parameters"].([]any)
+			for _, rp := range params {
+				pm := s.resolve(asObject(rp))
+				name, _ := pm["name"].(string)
+				in, _ := pm["in"].(string)
+				if name == "" || in == "cookie" {
+					continue
+				}
+				req, _ := pm["required"].(bool)
+				so.Params = append(so.Params, specParam{Name: name, In: in, Required: req || in == "path", Schema: asObject(pm["schema"])})
+			}
+			if rb := s.resolve(asObject(op["requestBody"])); rb != nil {
+				content, _ := rb["content"].(map[string]any)
+				if media, ok := content["application/json"].(map[string]any); ok {
+					so.Body = asObject(media["schema"])
+					if so.Body == nil {
+						so.Body = map[string]any{}
+					}
+				}
+			}
+			responses, _ := op["responses"].(map[string]any)
+			codes := make([]string, 0, len(responses))
+			for code := range responses {
+				if strings.HasPrefix(code, "2") {
+					codes = append(codes, code)
+				}
+			}
+			slices.Sort(codes)
+			if len(codes) > 0 {
+				content, _ := s.resolve(asObject(responses[codes[0]]))["content"].(map[string]any)
+				if media, ok := content["application/json"].(map[string]any); ok {
+					so.Response = asObject(media["schema"])
+				}
+			}
+			s.ops = append(s.ops, so)
+		}
+	}
+	slices.SortFunc(s.ops, func(a, b specOperation) int { return strings.Compare(a.ID, b.ID) })
+	return s, nil
+}
+
+func asObject(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func (s *stackSpec) operation(id string) (specOperation, bool) {
+	i := slices.IndexFunc(s.ops, func(o specOperation) bool { return o.ID == id })
+	if i < 0 {
+		return specOperation{}, false
+	}
+	return s.ops[i], true
+}
+
+// resolve follows local $refs ("#/components/schemas/X") until it reaches a schema.
+func (s *stackSpec) resolve(schema map[string]any) map[string]any {
+	for range 16 {
+		ref, ok := schema["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return schema
+		}
+		var cur any = s.doc
+		for _, seg := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			seg = strings.ReplaceAll(strings.ReplaceAll(seg, "~1", "/"), "~0", "~")
+			cur = asObject(cur)[seg]
+		}
+		schema = asObject(cur)
+	}
+	return schema
+}
+
+// typeName renders a schema's type for signatures: "string", "integer[]", "object", "a|b".
+func (s *stackSpec) typeName(schema map[string]any, depth int) string {
+	schema = s.resolve(schema)
+	if schema == nil || depth > 4 {
+		return "any"
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if alts, ok := schema[key].([]any); ok {
+			var names []string
+			for _, alt := range alts {
+				if n := s.typeName(asObject(alt), depth+1); !slices.Contains(names, n) {
+					names = append(names, n)
+				}
+			}
+			return strings.Join(names, "|")
+		}
+	}
+	switch t := schema["type"].(type) {
+	case string:
+		if t == "array" {
+			return s.typeName(asObject(schema["items"]), depth+1) + "[]"
+		}
+		return t
+	case []any:
+		var names []string
+		for _, n := range t {
+			names = append(names, fmt.Sprint(n))
+		}
+		return strings.Join(names, "|")
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	return "any"
+}
+
+// signature renders an operation's inputs: parameters first, then body fields; optional ones end in "?".
+func (s *stackSpec) signature(op specOperation) string {
+	var parts []string
+	for _, p := range op.Params {
+		opt := "?"
+		if p.Required {
+			opt = ""
+		}
+		parts = append(parts, fmt.Sprintf("%s%s: %s", p.Name, opt, s.typeName(p.Schema, 0)))
+	}
+	if body := s.resolve(op.Body); body != nil {
+		props, _ := body["properties"].(map[string]any)
+		if t := s.typeName(body, 0); len(props) == 0 && t != "object" && t != "any" {
+			parts = append(parts, "body: "+t)
+		}
+		required := stringList(body["required"])
+		names := make([]string, 0, len(props))
+		for n := range props {
+			names = append(names, n)
+		}
+		// Required fields first, each group alphabetical.
+		slices.SortFunc(names, func(a, b string) int {
+			ra, rb := slices.Contains(required, a), slices.Contains(required, b)
+			if ra != rb {
+				if ra {
+					return -1
+				}
+				return 1
+			}
+			return strings.Compare(a, b)
+		})
+		for _, n := range names {
+			opt := "?"
+			if slices.Contains(required, n) {
+				opt = ""
+			}
+			parts = append(parts, fmt.Sprintf("%s%s: %s", n, opt, s.typeName(asObject(props[n]), 0)))
+		}
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+func stringList(v any) []string {
+	items, _ := v.([]any)
+	out := make([]string, 0, len(items))
+	for _, it := range items {
+		if s, ok := it.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// validate checks v against a JSON Schema subset (type, enum, required, properties,
+// additionalProperties: false, items, anyOf/oneOf/allOf) and appends "where: problem" lines.
+func (s *stackSpec) validate(at string, v any, schema map[string]any, errs *[]string, depth int) {
+	schema = s.resolve(schema)
+	if len(schema) == 0 || depth > 32 {
+		return
+	}
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, sub := range all {
+			s.validate(at, v, asObject(sub), errs, depth+1)
+		}
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		alts, ok := schema[key].([]any)
+		if !ok {
+			continue
+		}
+		matched := false
+		for _, alt := range alts {
+			var sub []string
+			if s.validate(at, v, asObject(alt), &sub, depth+1); len(sub) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			*errs = append(*errs, fmt.Sprintf("%s: expected %s, got %s", at, s.typeName(schema, 0), jsonTypeOf(v)))
+			return
+		}
+	}
+	if t, ok := schema["type"]; ok && !typeMatches(v, t) {
+		*errs = append(*errs, fmt.Sprintf("%s: expected %s, got %s", at, s.typeName(schema, 0), jsonTypeOf(v)))
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+		*errs = append(*errs, fmt.Sprintf("%s: must be one of %s", at, mustJSON(enum)))
+	}
+	switch val := v.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		for _, r := range stringList(schema["required"]) {
+			if _, ok := val[r]; !ok {
+				*errs = append(*errs, fmt.Sprintf("%s.%s: required", at, r))
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			if ps, ok := props[k]; ok {
+				s.validate(at+"."+k, val[k], asObject(ps), errs, depth+1)
+			} else if ap, ok := schema["additionalProperties"].(bool); ok && !ap {
+				*errs = append(*errs, fmt.Sprintf("%s.%s: unknown field", at, k))
+			}
+		}
+	case []any:
+		if items := asObject(schema["items"]); items != nil {
+			for i, it := range val {
+				s.validate(fmt.Sprintf("%s[%d]", at, i), it, items, errs, depth+1)
+			}
+		}
+	}
+}
+
+func typeMatches(v any, t any) bool {
+	switch tt := t.(type) {
+	case string:
+		switch tt {
+		case "object":
+			_, ok := v.(map[string]any)
+			return ok
+		case "array":
+			_, ok := v.([]any)
+			return ok
+		case "string":
+			_, ok := v.(string)
+			return ok
+		case "boolean":
+			_, ok := v.(bool)
+			return ok
+		case "number":
+			_, ok := v.(float64)
+			return ok
+		case "integer":
+			f, ok := v.(float64)
+			return ok && f == math.Trunc(f)
+		case "null":
+			return v == nil
+		}
+		return true
+	case []any:
+		return slices.ContainsFunc(tt, func(x any) bool { return typeMatches(v, x) })
+	}
+	return true
+}
+
+func jsonTypeOf(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if x == math.Trunc(x) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// argValue turns --arg text into a value: kept as text where the schema wants a string,
+// otherwise parsed as JSON when it is valid JSON (42, true, [1,2], {"a":1}).
+func (s *stackSpec) argValue(raw string, schema map[string]any) any {
+	if schema = s.resolve(schema); schema != nil && schema["type"] == "string" {
+		return raw
+	}
+	var v any
+	if json.Unmarshal([]byte(raw), &v) == nil {
+		return v
+	}
+	return raw
+}
+
+// findStack picks a stack by name; with one stack the name may be omitted.
+func findStack(insts []Instance, name string) (Instance, error) {
+	if name == "" && len(insts) == 1 {
+		return insts[0], nil
+	}
+	var names []string
+	for _, inst := range insts {
+		if inst.Name == name {
+			return inst, nil
+		}
+		names = append(names, inst.Name)
+	}
+	if name == "" {
+		return Instance{}, fmt.Errorf("several stacks are running; name one of: %s", strings.Join(names, ", "))
+	}
+	return Instance{}, fmt.Errorf("no stack %q (running: %s)", name, strings.Join(names, ", "))
+}
+
+func cmdTools() {
+	fs := flag.NewFlagSet("tools", flag.ExitOnError)
+	fs.Usage = func() { helpTopic("tools") }
+	args := os.Args[2:]
+	stack := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		stack, args = args[0], args[1:]
+	}
+	_ = fs.Parse(args)
+
+	st, _ := currentState()
+	if len(st.Instances) == 0 {
+		fmt.Println("No stacks in", statePath(), "(start some with `mcp-launch up`).")
+		os.Exit(1)
+	}
+	insts := st.Instances
+	if stack != "" {
+		inst, err := findStack(insts, stack)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		insts = []Instance{inst}
+	}
+	failed := false
+	for i, inst := range insts {
+		if i > 0 {
+			fmt.Println()
+		}
+		spec, err := loadStackSpec(inst)
+		if err != nil {
+			fmt.Printf("%s: %v\n", inst.Name, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s (%d operation(s)):\n", inst.Name, len(spec.ops))
+		for _, op := range spec.ops {
+			fmt.Printf("  %s%s\n", op.ID, spec.signature(op))
+			line := op.Method + " " + op.Path
+			if op.Summary != "" {
+				line += " — " + firstLine(op.Summary)
+			}
+			fmt.Printf("      %s\n", line)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+func cmdCall() {
+	fs := flag.NewFlagSet("call", flag.ExitOnError)
+	fs.Usage = func() { helpTopic("call") }
+	var argList stringSlice
+	fs.Var(&argList, "arg", "Input as NAME=VALUE (repeatable; VALUE is JSON unless the field is a string)")
+	jsonIn := fs.String("json", "", "Input as a JSON object, @FILE, or @- for stdin (--arg values override it)")
+	noValidate := fs.Bool("no-validate", false, "Send the input without checking it against the schema")
+	public := fs.Bool("public", false, "Call through the stack's public URL (tunnel) instead of 127.0.0.1")
+	raw := fs.Bool("raw", false, "Print only the response body, as received")
+	timeout := fs.Duration("timeout", 2*time.Minute, "Give up after this long")
+
+	// Positional STACK and OPERATION may come before the flags.
+	args := os.Args[2:]
+	var pos []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") && len(pos) < 2 {
+		pos, args = append(pos, args[0]), args[1:]
+	}
+	_ = fs.Parse(args)
+	pos = append(pos, fs.Args()...)
+	var stack, opID string
+	switch len(pos) {
+	case 1:
+		opID = pos[0]
+	case 2:
+		stack, opID = pos[0], pos[1]
+	default:
+		helpTopic("call")
+		os.Exit(2)
+	}
+
+	st, _ := currentState()
+	inst, err := findStack(st.Instances, stack)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	spec, err := loadStackSpec(inst)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	op, ok := spec.operation(opID)
+	if !ok {
+		fmt.Printf("No operation %q in stack %s (see: mcp-launch tools %s)\n", opID, inst.Name, inst.Name)
+		os.Exit(2)
+	}
+
+	input, err := callInput(*jsonIn, argList, spec, op)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	req, problems, err := buildCallRequest(spec, op, input, !*noValidate)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("Invalid input for %s%s:\n", op.ID, spec.signature(op))
+		for _, p := range problems {
+			fmt.Println("  -", p)
+		}
+		fmt.Println("(send it anyway with --no-validate)")
+		os.Exit(2)
+	}
+
+	base := fmt.Sprintf("http://127.0.0.1:%d", inst.FrontPort)
+	if *public {
+		if inst.PublicURL == "" {
+			fmt.Printf("Stack %s has no public URL.\n", inst.Name)
+			os.Exit(2)
+		}
+		base = inst.PublicURL
+	}
+	u, err := url.Parse(base + req.URL.String())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	req.URL, req.Host = u, u.Host
+	if keys := acceptedKeys(inst); len(keys) > 0 {
+		req.Header.Set("X-API-Key", keys[0])
+	}
+
+	start := time.Now()
+	resp, err := (&http.Client{Timeout: *timeout}).Do(req)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	elapsed := time.Since(start)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if *raw {
+		_, _ = os.Stdout.Write(body)
+	} else {
+		fmt.Printf("%s %s → %s in %s\n", op.Method, req.URL.RequestURI(), resp.Status, elapsed.Round(10*time.Microsecond))
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, body, "", "  ") == nil {
+			body = pretty.Bytes()
+		}
+		fmt.Println(string(body))
+	}
+	if resp.StatusCode >= 400 {
+		os.Exit(1)
+	}
+}
+
+// callInput merges --json and --arg into one input object.
+func callInput(jsonIn string, argList []string, spec *stackSpec, op specOperation) (map[string]any, error) {
+	input := map[string]any{}
+	if jsonIn != "" {
+		data := []byte(jsonIn)
+		if p, ok := strings.CutPrefix(jsonIn, "@"); ok {
+			var err error
+			if p == "-" {
+				data, err = io.ReadAll(os.Stdin)
+			} else {
+				data, err = os.ReadFile(p)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("--json: %w", err)
+			}
+		}
+		if err := json.Unmarshal(data, &input); err != nil {
+			return nil, fmt.Errorf("--json: expected a JSON object: %w", err)
+		}
+	}
+	body := spec.resolve(op.Body)
+	props, _ := body["properties"].(map[string]any)
+	for _, a := range argList {
+		k, v, ok := strings.Cut(a, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("--arg %q: expected NAME=VALUE", a)
+		}
+		schema := asObject(props[k])
+		if i := slices.IndexFunc(op.Params, func(p specParam) bool { return p.Name == k }); i >= 0 {
+			schema = op.Params[i].Schema
+		}
+		input[k] = spec.argValue(v, schema)
+	}
+	return input, nil
+}
+
+// buildCallRequest splits input into path, query and header parameters and the JSON body, and
+// returns a request relative to the stack's base URL plus any schema violations.
+func buildCallRequest(spec *stackSpec, op specOperation, input map[string]any, validate bool) (*http.Request, []string, error) {
+	var problems []string
+	rest := make(map[string]any, len(input))
+	for k, v := range input {
+		rest[k] = v
+	}
+	p := op.Path
+	query := url.Values{}
+	header := http.Header{}
+	for _, prm := range op.Params {
+		v, ok := rest[prm.Name]
+		delete(rest, prm.Name)
+		if !ok {
+			if prm.Required && validate {
+				problems = append(problems, fmt.Sprintf("%s.%s: required", prm.In, prm.Name))
+			}
+			continue
+		}
+		if validate {
+			spec.validate(prm.In+"."+prm.Name, v, prm.Schema, &problems, 0)
+		}
+		switch prm.In {
+		case "path":
+			p = strings.ReplaceAll(p, "{"+prm.Name+"}", url.PathEscape(paramText(v)))
+		case "query":
+			if items, ok := v.([]any); ok {
+				for _, it := range items {
+					query.Add(prm.Name, paramText(it))
+				}
+			} else {
+				query.Set(prm.Name, paramText(v))
+			}
+		case "header":
+			header.Set(prm.Name, paramText(v))
+		}
+	}
+
+	var body io.Reader
+	switch {
+	case op.Body != nil:
+		if validate {
+			spec.validate("body", rest, op.Body, &problems, 0)
+			// Servers usually drop unknown fields silently; a typo should not go unnoticed.
+			if props, _ := spec.resolve(op.Body)["properties"].(map[string]any); len(props) > 0 {
+				var unknown []string
+				for k := range rest {
+					if _, ok := props[k]; !ok {
+						unknown = append(unknown, k)
+					}
+				}
+				slices.Sort(unknown)
+				for _, k := range unknown {
+					problems = append(problems, fmt.Sprintf("body.%s: unknown field", k))
+				}
+			}
+		}
+		body = bytes.NewReader(mustJSON(rest))
+		header.Set("Content-Type", "application/json")
+	case len(rest) > 0:
+		var names []string
+		for k := range rest {
+			names = append(names, k)
+		}
+		slices.Sort(names)
+		return nil, nil, fmt.Errorf("%s takes no %s (its inputs: %s)", op.ID, strings.Join(names, ", "), spec.signature(op))
+	}
+	target := p
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(op.Method, target, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return req, problems, nil
+}
+
+// paramText renders a parameter value for a URL or header.
+func paramText(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case nil:
+		return ""
+	case float64, bool:
+		return fmt.Sprint(x)
+	}
+	return string(mustJSON(v))
+}
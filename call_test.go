@@ -0,0 +1,100 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testCallSpec = `{
+  "openapi": "3.1.0",
+  "paths": {
+    "/srv/items/{id}": {"get": {"operationId": "srv__get_item", "parameters": [
+      {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}},
+      {"name": "tags", "in": "query", "schema": {"type": "array", "items": {"type": "string"}}},
+      {"name": "limit", "in": "query", "schema": {"type": "integer"}},
+      {"name": "X-Trace", "in": "header", "schema": {"type": "string"}}
+    ]}},
+    "/srv/items": {"post": {"operationId": "srv__add_item",
+      "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}}}}
+  },
+  "components": {"schemas": {"Item": {"type": "object", "required": ["name"], "properties": {
+    "name": {"type": "string"}, "qty": {"type": "integer"}, "size": {"enum": ["s", "m"]}}}}}
+}`
+
+func TestBuildCallRequest(t *testing.T) {
+	spec, err := parseStackSpec([]byte(testCallSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		op       string
+		input    map[string]any
+		validate bool
+		wantURI  string
+		wantHdr  map[string]string
+		wantBody string
+		problems []string
+		wantErr  bool
+	}{
+		{"path, query and header", "srv__get_item",
+			map[string]any{"id": "42", "tags": []any{"a", "b"}, "limit": float64(10), "X-Trace": "t1"}, true,
+			"/srv/items/42?limit=10&tags=a&tags=b", map[string]string{"X-Trace": "t1"}, "", nil, false},
+		{"path value is escaped", "srv__get_item", map[string]any{"id": "a b/c"}, true,
+			"/srv/items/a%20b%2Fc", nil, "", nil, false},
+		{"missing path parameter", "srv__get_item", map[string]any{}, true,
+			"/srv/items/%7Bid%7D", nil, "", []string{"path.id: required"}, false},
+		{"parameter type", "srv__get_item", map[string]any{"id": "1", "limit": "ten"}, true,
+			"/srv/items/1?limit=ten", nil, "", []string{"query.limit: expected integer, got string"}, false},
+		{"no validation", "srv__get_item", map[string]any{"limit": "ten"}, false,
+			"/srv/items/%7Bid%7D?limit=ten", nil, "", nil, false},
+		{"json body", "srv__add_item", map[string]any{"name": "pen", "qty": float64(2)}, true,
+			"/srv/items", map[string]string{"Content-Type": "application/json"}, `{"name":"pen","qty":2}`, nil, false},
+		{"body problems", "srv__add_item", map[string]any{"qty": 1.5, "size": "xl", "colour": "red"}, true,
+			"/srv/items", nil, `{"colour":"red","qty":1.5,"size":"xl"}`,
+			[]string{"body.name: required", "body.qty: expected integer, got number", `body.size: must be one of ["s","m"]`, "body.colour: unknown field"}, false},
+		{"input without a body to put it in", "srv__get_item", map[string]any{"id": "1", "colour": "red"}, true,
+			"", nil, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, ok := spec.operation(tt.op)
+			if !ok {
+				t.Fatalf("no operation %s", tt.op)
+			}
+			req, problems, err := buildCallRequest(spec, op, tt.input, tt.validate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildCallRequest error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := req.URL.RequestURI(); got != tt.wantURI {
+				t.Errorf("uri = %s, want %s", got, tt.wantURI)
+			}
+			if req.Method != op.Method {
+				t.Errorf("method = %s, want %s", req.Method, op.Method)
+			}
+			for k, v := range tt.wantHdr {
+				if got := req.Header.Get(k); got != v {
+					t.Errorf("header %s = %q, want %q", k, got, v)
+				}
+			}
+			var body string
+			if req.Body != nil {
+				b, _ := io.ReadAll(req.Body)
+				body = strings.TrimSpace(string(b))
+			}
+			if body != tt.wantBody {
+				t.Errorf("body = %s, want %s", body, tt.wantBody)
+			}
+			if !reflect.DeepEqual(problems, tt.problems) {
+				t.Errorf("problems = %q, want %q", problems, tt.problems)
+			}
+		})
+	}
+}
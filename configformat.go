@@ -0,0 +1,210 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ---------- YAML / TOML configs ----------
+//
+// .yaml/.yml/.toml configs describe the same MCPConfig as Claude-style JSON. They are decoded
+// into plain maps and re-encoded as JSON, so the json tags stay the single schema; mcpo only
+// ever reads the generated JSON under .mcp-launch/run/.
+
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatTOML = "toml"
+)
+
+// defaultConfigNames are tried in order when no --config is given.
+var defaultConfigNames = []string{defaultConfig, "mcp.config.yaml", "mcp.config.yml", "mcp.config.toml"}
+
+// defaultConfigPath returns the first default config that exists (mcp.config.json if none do).
+func defaultConfigPath() string {
+	for _, name := range defaultConfigNames {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+	}
+	return defaultConfig
+}
+
+// configFormat picks the format from the file extension; anything unknown is JSON.
+func configFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".toml":
+		return formatTOML
+	}
+	return formatJSON
+}
+
+// decodeConfigDoc parses data in the given format into generic JSON-compatible values.
+func decodeConfigDoc(data []byte, format string) (any, error) {
+	var doc any
+	switch format {
+	case formatYAML:
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	case formatTOML:
+		m := map[string]any{}
+		if _, err := toml.Decode(string(data), &m); err != nil {
+			return nil, err
+		}
+		doc = m
+	default:
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// configJSON returns the config's contents as JSON, translating YAML/TOML.
+func configJSON(path string, data []byte) ([]byte, error) {
+	format := configFormat(path)
+	if format == formatJSON {
+		return data, nil
+	}
+	doc, err := decodeConfigDoc(data, format)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// loadConfig reads a JSON, YAML or TOML config.
+func loadConfig(path string) (MCPConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return MCPConfig{}, err
+	}
+	js, err := configJSON(path, data)
+	if err != nil {
+		return MCPConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+	var cfg MCPConfig
+	if err := json.Unmarshal(js, &cfg); err != nil {
+		return MCPConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// encodeConfigDoc writes a generic config document in the given format.
+func encodeConfigDoc(doc any, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case formatYAML:
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+		_ = enc.Close()
+	case formatTOML:
+		if _, ok := doc.(map[string]any); !ok {
+			return nil, errors.New("TOML needs a table at the top level")
+		}
+		if err := toml.NewEncoder(&buf).Encode(doc); err != nil {
+			return nil, err
+		}
+	default:
+		b, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(append(b, '\n'))
+	}
+	return buf.Bytes(), nil
+}
+
+// ---------- `mcp-launch config` ----------
+
+func cmdConfig() {
+	if len(os.Args) < 3 {
+		helpTopic("config")
+		os.Exit(2)
+	}
+	switch os.Args[2] {
+	case "convert":
+		cmdConfigConvert(os.Args[3:])
+	default:
+		helpTopic("config")
+		os.Exit(2)
+	}
+}
+
+func cmdConfigConvert(args []string) {
+	fs := flag.NewFlagSet("config convert", flag.ExitOnError)
+	fs.Usage = func() { helpTopic("config") }
+	to := fs.String("to", "", "Output format: json|yaml|toml (default: from -o, else json)")
+	out := fs.String("o", "", "Write to this file instead of stdout")
+
+	// Allow the input path before or after the flags.
+	in := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		in, args = args[0], args[1:]
+	}
+	_ = fs.Parse(args)
+	if in == "" && fs.NArg() > 0 {
+		in = fs.Arg(0)
+	}
+	if in == "" {
+		helpTopic("config")
+		os.Exit(2)
+	}
+
+	format := *to
+	if format == "" && *out != "" {
+		format = configFormat(*out)
+	}
+	switch format {
+	case "":
+		format = formatJSON
+	case "yml":
+		format = formatYAML
+	case formatJSON, formatYAML, formatTOML:
+	default:
+		fmt.Println("Unknown format:", format, "(expected json, yaml or toml)")
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(in)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	doc, err := decodeConfigDoc(data, configFormat(in))
+	if err != nil {
+		fmt.Printf("%s: %v\n", in, err)
+		os.Exit(1)
+	}
+	b, err := encodeConfigDoc(doc, format)
+	if err != nil {
+		fmt.Printf("convert to %s: %v\n", format, err)
+		os.Exit(1)
+	}
+	if *out == "" {
+		_, _ = os.Stdout.Write(b)
+		return
+	}
+	if err := os.WriteFile(*out, b, 0644); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s (%s). Comments are not carried over.\n", *out, format)
+}
@@ -0,0 +1,77 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// A config converted JSON → YAML → TOML → JSON describes the same servers at every step.
+func TestConfigFormatRoundTrip(t *testing.T) {
+	retries := 3
+	want := MCPConfig{
+		MCPServers: map[string]MCPServer{
+			"time": {Command: "uvx", Args: []string{"mcp-server-time", "--local-timezone=UTC", ""}},
+			"github": {
+				Command: "npx", Args: []string{"-y", "@modelcontextprotocol/server-github"},
+				Env: map[string]string{
+					"GITHUB_TOKEN": "${GITHUB_TOKEN}",
+					"DEBUG":        "true", // strings YAML/TOML would otherwise retype
+					"PORT":         "8080",
+					"MODE":         "yes",
+					"EMPTY":        "",
+					"MULTILINE":    "a\nb: c # d",
+				},
+				EnvFile: ".env",
+				Tools:   &ToolFilter{Include: []string{"search_*"}, Exclude: []string{"delete_*"}},
+			},
+			"remote": {
+				Type: "streamable-http", URL: "https://mcp.example.com/mcp",
+				Headers:        map[string]string{"Authorization": "Bearer ${TOKEN}", "X-Empty": ""},
+				ConnectTimeout: "10s", ConnectRetries: &retries,
+			},
+			"old": {Type: "sse", URL: "https://old.example.com/sse", Disabled: true},
+		},
+		Tools: &ToolFilter{Exclude: []string{"*__admin_*"}},
+	}
+
+	dir := t.TempDir()
+	data, _ := json.MarshalIndent(want, "", "  ")
+	prev := filepath.Join(dir, "start.json")
+	if err := os.WriteFile(prev, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []string{"step.yaml", "step.toml", "end.json"} {
+		in, err := os.ReadFile(prev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := decodeConfigDoc(in, configFormat(prev))
+		if err != nil {
+			t.Fatalf("decode %s: %v", filepath.Base(prev), err)
+		}
+		next := filepath.Join(dir, step)
+		out, err := encodeConfigDoc(doc, configFormat(next))
+		if err != nil {
+			t.Fatalf("encode %s: %v", step, err)
+		}
+		if err := os.WriteFile(next, out, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := loadConfig(next)
+		if err != nil {
+			t.Fatalf("%v\n%s", err, out)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s does not match the original:\n got %+v\nwant %+v\n%s", step, got, want, out)
+		}
+		if enabled := got.enabled(); len(enabled.MCPServers) != 3 || enabled.MCPServers["old"].URL != "" {
+			t.Errorf("%s: disabled server not excluded from enabled(): %v", step, enabled.MCPServers)
+		}
+		prev = next
+	}
+}
@@ -0,0 +1,362 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ---------- detached mode / control socket ----------
+
+const (
+	daemonEnv       = "MCP_LAUNCH_DAEMON" // set on the re-executed `up` child
+	controlSockName = "control.sock"
+	daemonPIDName   = "daemon.pid"
+	daemonLogName   = "daemon.log"
+)
+
+func controlSockPath() string { return filepath.Join(getStateDir(), controlSockName) }
+func daemonPIDPath() string   { return filepath.Join(getStateDir(), daemonPIDName) }
+func daemonLogPath() string   { return filepath.Join(getStateDir(), daemonLogName) }
+
+func isDaemonChild() bool { return os.Getenv(daemonEnv) == "1" }
+
+// daemonStatus is what GET /v1/state returns.
+type daemonStatus struct {
+	PID   int   `json:"pid"`
+	State State `json:"state"`
+}
+
+type openAPIRequest struct {
+	PublicURLs []string `json:"public_urls,omitempty"`
+}
+
+type keysRequest struct {
+	Stack  string   `json:"stack"`
+	Add    []string `json:"add,omitempty"`
+	Remove []string `json:"remove,omitempty"`
+}
+
+type openAPIResult struct {
+	Name           string   `json:"name"`
+	FrontPort      int      `json:"front_port"`
+	File           string   `json:"file,omitempty"`
+	OperationCount int      `json:"operation_count"`
+	Warnings       []string `json:"warnings,omitempty"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// spawnDaemon re-executes `up` with args in a new session, detached from the terminal,
+// and blocks until its control socket answers (or the child dies).
+func spawnDaemon(args []string) error {
+	if st, err := daemonState(); err == nil {
+		return fmt.Errorf("a detached mcp-launch is already running (pid %d); run `mcp-launch down` first", st.PID)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	ensureStateDir()
+	logf, err := os.OpenFile(daemonLogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer logf.Close()
+
+	cmd := exec.Command(self, append([]string{"up"}, args...)...)
+	cmd.Env = append(os.Environ(), daemonEnv+"=1")
+	cmd.Stdin = nil
+	cmd.Stdout = logf
+	cmd.Stderr = logf
+	if runtime.GOOS != "windows" {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	deadline := time.After(5 * time.Minute)
+	tick := time.NewTicker(300 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case err := <-exited:
+			return fmt.Errorf("daemon exited during startup (%s); see %s", describeExit(err), daemonLogPath())
+		case <-deadline:
+			return fmt.Errorf("daemon (pid %d) did not become ready; see %s", cmd.Process.Pid, daemonLogPath())
+		case <-tick.C:
+			st, err := daemonState()
+			if err != nil {
+				continue
+			}
+			printShareBlock(st.State.Instances)
+			fmt.Printf("Detached (pid %d). Control socket: %s\n", st.PID, controlSockPath())
+			fmt.Printf("Logs: %s — stop with `mcp-launch down`.\n", daemonLogPath())
+			_ = cmd.Process.Release()
+			return nil
+		}
+	}
+}
+
+// stripDetach removes --detach / -detach (and an explicit =true/=false form) from args.
+func stripDetach(args []string) []string {
+	out := make([]string, 0, len(args))
+	for _, a := range args {
+		name := strings.TrimLeft(a, "-")
+		if name == "detach" || strings.HasPrefix(name, "detach=") {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// ---------- server side (inside the daemon) ----------
+
+// controlServer serves the control API on the Unix-domain socket for a detached `up`.
+type controlServer struct {
+	srv *http.Server
+	ln  net.Listener
+
+	mu        *sync.Mutex // the `up` state lock
+	st        *State
+	instances []Instance
+	runs      []*stackRun
+	down      chan struct{}
+	downOnce  sync.Once
+}
+
+func startControlServer(mu *sync.Mutex, st *State, instances []Instance, runs []*stackRun) (*controlServer, error) {
+	path := controlSockPath()
+	// A socket left behind by a crashed daemon refuses connections; clear it.
+	if _, err := daemonState(); err == nil {
+		return nil, errors.New("another daemon is already serving " + path)
+	}
+	_ = os.Remove(path)
+	// Create the socket owner-only from the start; a chmod after Listen leaves a window in
+	// which other local users could connect and rotate keys or stop the stacks.
+	old := syscall.Umask(0o077)
+	ln, err := net.Listen("unix", path)
+	syscall.Umask(old)
+	if err != nil {
+		return nil, err
+	}
+	c := &controlServer{ln: ln, mu: mu, st: st, instances: instances, runs: runs, down: make(chan struct{})}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/state", c.handleState)
+	mux.HandleFunc("/v1/down", c.handleDown)
+	mux.HandleFunc("/v1/openapi", c.handleOpenAPI)
+	mux.HandleFunc("/v1/keys", c.handleKeys)
+	c.srv = &http.Server{Handler: mux}
+	go func() { _ = c.srv.Serve(ln) }()
+
+	_ = os.WriteFile(daemonPIDPath(), []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+	return c, nil
+}
+
+// Down is closed when a client asks the daemon to stop.
+func (c *controlServer) Down() <-chan struct{} { return c.down }
+
+func (c *controlServer) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = c.srv.Shutdown(ctx)
+	_ = os.Remove(controlSockPath())
+	_ = os.Remove(daemonPIDPath())
+}
+
+func (c *controlServer) snapshot() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := *c.st
+	st.Instances = slices.Clone(c.instances)
+	return st
+}
+
+func (c *controlServer) handleState(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, daemonStatus{PID: os.Getpid(), State: c.snapshot()})
+}
+
+func (c *controlServer) handleDown(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, daemonStatus{PID: os.Getpid(), State: c.snapshot()})
+	c.downOnce.Do(func() { close(c.down) })
+}
+
+func (c *controlServer) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	var req openAPIRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	var results []openAPIResult
+	for i, run := range c.runs {
+		c.mu.Lock()
+		inst := *run.inst
+		c.mu.Unlock()
+		baseURL := alignedPublicURL(req.PublicURLs, i, inst)
+		res := openAPIResult{Name: inst.Name, FrontPort: inst.FrontPort}
+		spec, filtered, out, err := writeMergedOpenAPI(inst, baseURL)
+		if err != nil {
+			res.Error = err.Error()
+			results = append(results, res)
+			continue
+		}
+		run.proxy.SetOpenAPI(spec)
+		run.proxy.SetFiltered(filtered)
+		res.File = out
+		res.OperationCount = countOperations(spec)
+		res.Warnings = findDanglingComponentRefs(spec)
+		c.mu.Lock()
+		run.inst.OperationCount = res.OperationCount
+		saveStateMulti(c.st, c.instances)
+		c.mu.Unlock()
+		results = append(results, res)
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// handleKeys adds/removes accepted X-API-Keys on a live stack, so keys rotate without a restart.
+func (c *controlServer) handleKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	var req keysRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, run := range c.runs {
+		c.mu.Lock()
+		if run.inst.Name != req.Stack {
+			c.mu.Unlock()
+			continue
+		}
+		keys := slices.Clone(acceptedKeys(*run.inst))
+		for _, k := range req.Add {
+			if k != "" && !slices.Contains(keys, k) {
+				keys = append(keys, k)
+			}
+		}
+		keys = slices.DeleteFunc(keys, func(k string) bool { return slices.Contains(req.Remove, k) })
+		if len(keys) == 0 {
+			c.mu.Unlock()
+			http.Error(w, "refusing to remove the last accepted key", http.StatusConflict)
+			return
+		}
+		run.inst.AcceptedKeys = keys
+		run.proxy.SetKeys(keys, run.inst.ProtectSpec)
+		saveStateMulti(c.st, c.instances)
+		inst := *run.inst
+		c.mu.Unlock()
+		writeJSON(w, http.StatusOK, inst)
+		return
+	}
+	http.Error(w, "no such stack: "+req.Stack, http.StatusNotFound)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// ---------- client side ----------
+
+func controlClient() *http.Client {
+	return &http.Client{
+		Timeout: 2 * time.Minute,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", controlSockPath())
+			},
+		},
+	}
+}
+
+// daemonCall sends a request to the live daemon. It fails fast when no daemon is listening.
+func daemonCall(method, path string, in, out any) error {
+	if _, err := os.Stat(controlSockPath()); err != nil {
+		return err
+	}
+	var body io.Reader
+	if in != nil {
+		b, _ := json.Marshal(in)
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, "http://mcp-launch"+path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := controlClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("daemon %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(b)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// daemonState returns the live daemon's view of the stacks, or an error if none is running.
+func daemonState() (daemonStatus, error) {
+	var ds daemonStatus
+	err := daemonCall(http.MethodGet, "/v1/state", nil, &ds)
+	return ds, err
+}
+
+// currentState prefers the live daemon and falls back to state.json.
+func currentState() (State, int) {
+	if ds, err := daemonState(); err == nil {
+		return ds.State, ds.PID
+	}
+	return loadState(), 0
+}
+
+// stopDaemon asks the daemon to shut down and waits for its socket to go away.
+func stopDaemon() (daemonStatus, bool) {
+	var ds daemonStatus
+	if err := daemonCall(http.MethodPost, "/v1/down", nil, &ds); err != nil {
+		return ds, false
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(controlSockPath()); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return ds, true
+}
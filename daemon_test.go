@@ -0,0 +1,103 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestControlServerRoundTrip(t *testing.T) {
+	chdirTemp(t)
+	ensureStateDir()
+	var mu sync.Mutex
+	st := State{Version: stateVersion}
+	instances := []Instance{{Name: "code", APIKey: "k1", FrontPort: 8800}}
+	runs := []*stackRun{{inst: &instances[0], proxy: newFrontProxy("code", 0, 0, "upstream")}}
+	runs[0].proxy.SetKeys([]string{"k1"}, false)
+
+	ctl, err := startControlServer(&mu, &st, instances, runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(ctl.Close)
+
+	fi, err := os.Stat(controlSockPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm&0o077 != 0 {
+		t.Errorf("control socket mode = %v, want owner-only", perm)
+	}
+	if _, err := startControlServer(&mu, &st, instances, runs); err == nil {
+		t.Error("a second control server started on a live socket")
+	}
+
+	ds, err := daemonState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds.PID != os.Getpid() || len(ds.State.Instances) != 1 || ds.State.Instances[0].Name != "code" {
+		t.Errorf("state = %+v", ds)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		req      keysRequest
+		wantKeys []string
+		wantErr  string
+	}{
+		{"add", http.MethodPost, keysRequest{Stack: "code", Add: []string{"k2", "k1", ""}}, []string{"k1", "k2"}, ""},
+		{"remove", http.MethodPost, keysRequest{Stack: "code", Remove: []string{"k1"}}, []string{"k2"}, ""},
+		{"last key", http.MethodPost, keysRequest{Stack: "code", Remove: []string{"k2"}}, nil, "409"},
+		{"unknown stack", http.MethodPost, keysRequest{Stack: "docs", Add: []string{"k3"}}, nil, "404"},
+		{"GET", http.MethodGet, keysRequest{Stack: "code"}, nil, "405"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Instance
+			err := daemonCall(tt.method, "/v1/keys", tt.req, &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.AcceptedKeys, tt.wantKeys) {
+				t.Errorf("keys = %q, want %q", got.AcceptedKeys, tt.wantKeys)
+			}
+			for _, k := range []string{"k1", "k2"} {
+				if want := slices.Contains(tt.wantKeys, k); runs[0].proxy.keyAccepted(k) != want {
+					t.Errorf("proxy accepts %s = %v, want %v", k, !want, want)
+				}
+			}
+			if saved := loadState(); len(saved.Instances) != 1 || !reflect.DeepEqual(saved.Instances[0].AcceptedKeys, tt.wantKeys) {
+				t.Errorf("state.json keys = %+v", saved.Instances)
+			}
+		})
+	}
+
+	if err := daemonCall(http.MethodPost, "/v1/down", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctl.Down():
+	case <-time.After(time.Second):
+		t.Fatal("Down not signalled after /v1/down")
+	}
+	ctl.Close()
+	if _, err := daemonState(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("daemonState after Close = %v, want no socket", err)
+	}
+}
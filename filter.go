@@ -0,0 +1,53 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// ---------- tool allow/deny filters ----------
+
+// ToolFilter selects operations by glob. Patterns containing "/" match the route path,
+// all others match the operationId. Include (if set) keeps only matches; Exclude then drops matches.
+type ToolFilter struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+func (f *ToolFilter) empty() bool {
+	return f == nil || (len(f.Include) == 0 && len(f.Exclude) == 0)
+}
+
+// allows reports whether an operation survives the filter. ids and paths are the candidate
+// names to match (e.g. both the server-local and the namespaced operationId).
+func (f *ToolFilter) allows(ids, paths []string) bool {
+	if f.empty() {
+		return true
+	}
+	if len(f.Include) > 0 && !matchAny(f.Include, ids, paths) {
+		return false
+	}
+	return !matchAny(f.Exclude, ids, paths)
+}
+
+func matchAny(patterns, ids, paths []string) bool {
+	for _, p := range patterns {
+		cands := ids
+		if strings.Contains(p, "/") {
+			cands = paths
+		}
+		for _, c := range cands {
+			if ok, _ := path.Match(p, c); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// routeKey identifies a proxied route for enforcement, e.g. "POST /time/get_current_time".
+func routeKey(method, p string) string {
+	return strings.ToUpper(method) + " " + p
+}
@@ -0,0 +1,51 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import "testing"
+
+func TestToolFilterAllows(t *testing.T) {
+	ids := []string{"get_current_time", "time__get_current_time"}
+	paths := []string{"/time/get_current_time"}
+	tests := []struct {
+		name   string
+		filter *ToolFilter
+		want   bool
+	}{
+		{"nil", nil, true},
+		{"empty", &ToolFilter{}, true},
+		{"include by id", &ToolFilter{Include: []string{"get_*"}}, true},
+		{"include by namespaced id", &ToolFilter{Include: []string{"time__*"}}, true},
+		{"include misses", &ToolFilter{Include: []string{"convert_*"}}, false},
+		{"include by path", &ToolFilter{Include: []string{"/time/*"}}, true},
+		{"path pattern ignores ids", &ToolFilter{Include: []string{"/get_*"}}, false},
+		{"id pattern ignores paths", &ToolFilter{Exclude: []string{"time"}}, true},
+		{"exclude by id", &ToolFilter{Exclude: []string{"*current*"}}, false},
+		{"exclude by path", &ToolFilter{Exclude: []string{"/time/get_*"}}, false},
+		{"exclude wins over include", &ToolFilter{Include: []string{"*"}, Exclude: []string{"get_current_time"}}, false},
+		{"glob does not cross slashes", &ToolFilter{Exclude: []string{"/*"}}, true},
+		{"bad pattern never matches", &ToolFilter{Include: []string{"[get"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.allows(ids, paths); got != tt.want {
+				t.Errorf("allows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteKey(t *testing.T) {
+	tests := []struct {
+		method, path, want string
+	}{
+		{"post", "/time/now", "POST /time/now"},
+		{"GET", "/a/b", "GET /a/b"},
+		{"Delete", "/x", "DELETE /x"},
+	}
+	for _, tt := range tests {
+		if got := routeKey(tt.method, tt.path); got != tt.want {
+			t.Errorf("routeKey(%q, %q) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ---------- health / readiness ----------
+
+const (
+	healthProbeTimeout = 3 * time.Second
+	// healthCacheTTL is how long a probe result is reused for anonymous remote callers, so
+	// /healthz and /readyz cannot be used to load every backend server through the tunnel.
+	healthCacheTTL = 5 * time.Second
+)
+
+// serverHealth is the probe result for one MCP server behind mcpo (/<name>/openapi.json).
+type serverHealth struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Status int    `json:"status,omitempty"` // HTTP status from mcpo
+	Error  string `json:"error,omitempty"`
+}
+
+// stackHealth is what /healthz and /readyz return (details only for local or authorized callers).
+type stackHealth struct {
+	Status  string         `json:"status"` // ok | degraded | down
+	Stack   string         `json:"stack,omitempty"`
+	McpoUp  bool           `json:"mcpo_up"`
+	Error   string         `json:"error,omitempty"`
+	Servers []serverHealth `json:"servers,omitempty"`
+}
+
+// ready is true when mcpo answers and every MCP server serves its spec.
+func (h stackHealth) ready() bool { return h.Status == "ok" }
+
+// probeMcpo checks that mcpo itself answers on /docs.
+func probeMcpo(mcpoPort int) error {
+	client := &http.Client{Timeout: healthProbeTimeout}
+	resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/docs", mcpoPort))
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("mcpo /docs: %s", resp.Status)
+	}
+	return nil
+}
+
+// probeStack checks mcpo and, if it is up, each server's /<name>/openapi.json in parallel.
+func probeStack(mcpoPort int, apiKey string, servers []string) stackHealth {
+	h := stackHealth{Status: "ok", McpoUp: true}
+	if err := probeMcpo(mcpoPort); err != nil {
+		h.Status, h.McpoUp, h.Error = "down", false, err.Error()
+		return h
+	}
+	h.Servers = make([]serverHealth, len(servers))
+	var wg sync.WaitGroup
+	for i, name := range servers {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			h.Servers[i] = probeServer(mcpoPort, apiKey, name)
+		}(i, name)
+	}
+	wg.Wait()
+	for _, s := range h.Servers {
+		if !s.OK {
+			h.Status = "degraded"
+		}
+	}
+	return h
+}
+
+// probeServer fetches one server's /<name>/openapi.json from the backend. A failure carries the
+// backend's FastAPI-style "detail" message when there is one (the native bridge says why).
+func probeServer(mcpoPort int, apiKey, name string) serverHealth {
+	sh := serverHealth{Name: name}
+	client := &http.Client{Timeout: healthProbeTimeout}
+	req, _ := http.NewRequest("GET", fmt.Sprintf("http://127.0.0.1:%d/%s/openapi.json", mcpoPort, name), nil)
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		sh.Error = err.Error()
+		return sh
+	}
+	defer resp.Body.Close()
+	sh.Status = resp.StatusCode
+	sh.OK = resp.StatusCode == http.StatusOK
+	if sh.OK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return sh
+	}
+	sh.Error = "openapi.json: " + resp.Status
+	var body struct {
+		Detail any `json:"detail"`
+	}
+	if json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&body) == nil {
+		if d, ok := body.Detail.(string); ok && d != "" {
+			sh.Error += ": " + d
+		}
+	}
+	return sh
+}
+
+// SetServers sets the MCP servers /readyz probes (for split stacks, only that part's servers).
+func (f *frontProxy) SetServers(servers []string) {
+	f.mu.Lock()
+	f.servers = servers
+	f.mu.Unlock()
+	f.healthMu.Lock()
+	f.healthAt = time.Time{}
+	f.healthMu.Unlock()
+}
+
+// SetDown marks the stack as permanently down (its mcpo was given up on), so /healthz and
+// /readyz fail fast with reason instead of probing a port nothing will answer on again.
+func (f *frontProxy) SetDown(reason string) {
+	f.mu.Lock()
+	f.down = reason
+	f.mu.Unlock()
+}
+
+// stackHealth probes the stack. Local and keyed callers always get a fresh probe; anonymous
+// remote ones share a result up to healthCacheTTL old, and wait for one probe at a time.
+func (f *frontProxy) stackHealth(servers []string, fresh bool) stackHealth {
+	f.healthMu.Lock()
+	defer f.healthMu.Unlock()
+	if !fresh && !f.healthAt.IsZero() && time.Since(f.healthAt) < healthCacheTTL {
+		return f.health
+	}
+	f.health = probeStack(f.mcpoPort, f.upstreamKey, servers)
+	f.healthAt = time.Now()
+	return f.health
+}
+
+// handleHealth serves /healthz (liveness: mcpo answers) and /readyz (every server is up too).
+func (f *frontProxy) handleHealth(readiness bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.RLock()
+		servers := f.servers
+		hasSpec := len(f.spec) > 0
+		down := f.down
+		f.mu.RUnlock()
+
+		trusted := isLocalRequest(r) || f.authorized(r)
+		var h stackHealth
+		if down != "" {
+			h = stackHealth{Status: "down", Error: down}
+		} else {
+			h = f.stackHealth(servers, trusted)
+		}
+		h.Stack = f.name
+		code := http.StatusOK
+		switch {
+		case !h.McpoUp:
+			code = http.StatusServiceUnavailable
+		case readiness && !h.ready():
+			code = http.StatusServiceUnavailable
+		case readiness && !hasSpec:
+			h.Status, h.Error = "degraded", "merged OpenAPI spec not generated yet"
+			code = http.StatusServiceUnavailable
+		}
+		// Server names and errors are for operators; anonymous remote callers only get the verdict.
+		if !trusted {
+			h = stackHealth{Status: h.Status, McpoUp: h.McpoUp}
+		}
+		writeJSON(w, code, h)
+	}
+}
+
+// reportStackHealth prints why a stack is not (fully) up after startup, one line per server.
+func reportStackHealth(name string, h stackHealth) {
+	if !h.McpoUp {
+		fmt.Printf("[health#%s] mcpo is not answering: %s\n", name, h.Error)
+		fmt.Printf("[health#%s] see `mcp-launch logs %s --proc mcpo`\n", name, name)
+		return
+	}
+	for _, s := range h.Servers {
+		if s.OK {
+			continue
+		}
+		fmt.Printf("[health#%s] server %q failed: %s\n", name, s.Name, s.Error)
+	}
+	if !h.ready() {
+		fmt.Printf("[health#%s] see `mcp-launch logs %s --proc mcpo` for the failing servers' output\n", name, name)
+	}
+}
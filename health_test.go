@@ -0,0 +1,65 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// Anonymous remote callers share cached probe results; local and keyed callers probe afresh.
+func TestHealthProbeCache(t *testing.T) {
+	chdirTemp(t)
+	var probes atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/srv/openapi.json" {
+			probes.Add(1)
+		}
+		writeJSON(w, http.StatusOK, map[string]string{})
+	}))
+	defer backend.Close()
+	u, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(u.Port())
+	f := newFrontProxy("healthtest", 0, port, "")
+	f.SetKeys([]string{"client-key"}, false)
+	f.SetServers([]string{"srv"})
+
+	get := func(remote, key string) int {
+		r := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		r.RemoteAddr = remote + ":4711"
+		if key != "" {
+			r.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		f.srv.Handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /healthz = %d %s", w.Code, w.Body)
+		}
+		return int(probes.Load())
+	}
+	tests := []struct {
+		name, remote, key string
+		wantProbes        int
+	}{
+		{"first anonymous call probes", "203.0.113.9", "", 1},
+		{"anonymous calls reuse it", "203.0.113.9", "", 1},
+		{"another anonymous caller too", "198.51.100.7", "", 1},
+		{"keyed caller probes afresh", "203.0.113.9", "client-key", 2},
+		{"local caller probes afresh", "127.0.0.1", "", 3},
+		{"wrong key counts as anonymous", "203.0.113.9", "nope", 3},
+	}
+	for _, tt := range tests {
+		if got := get(tt.remote, tt.key); got != tt.wantProbes {
+			t.Errorf("%s: %d probe(s) so far, want %d", tt.name, got, tt.wantProbes)
+		}
+	}
+
+	f.SetServers([]string{"srv"}) // a new server list invalidates the cache
+	if got := get("203.0.113.9", ""); got != 4 {
+		t.Errorf("after SetServers: %d probe(s), want 4", got)
+	}
+}
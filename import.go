@@ -0,0 +1,435 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// ---------- `mcp-launch import` ----------
+
+// importPaths returns the default config locations for --from, most specific first.
+func importPaths(from string) []string {
+	home, _ := os.UserHomeDir()
+	switch from {
+	case "claude":
+		switch runtime.GOOS {
+		case "darwin":
+			return []string{filepath.Join(home, "Library", "Application Support", "Claude", "claude_desktop_config.json")}
+		case "windows":
+			return []string{filepath.Join(os.Getenv("APPDATA"), "Claude", "claude_desktop_config.json")}
+		}
+		return []string{filepath.Join(home, ".config", "Claude", "claude_desktop_config.json")}
+	case "cursor":
+		return []string{filepath.Join(".cursor", "mcp.json"), filepath.Join(home, ".cursor", "mcp.json")}
+	case "vscode":
+		return []string{filepath.Join(".vscode", "mcp.json")}
+	}
+	return nil
+}
+
+// importedServer is the union of the server fields used by Claude Desktop, Cursor and VS Code.
+type importedServer struct {
+	Type      string            `json:"type"`
+	Command   string            `json:"command"`
+	Args      []string          `json:"args"`
+	Env       map[string]any    `json:"env"`
+	EnvFile   string            `json:"envFile"`
+	URL       string            `json:"url"`
+	ServerURL string            `json:"serverUrl"`
+	Headers   map[string]string `json:"headers"`
+	Disabled  bool              `json:"disabled"`
+}
+
+// readImportSource returns the raw server entries of a Claude/Cursor ("mcpServers") or
+// VS Code ("servers", or "mcp.servers" in settings.json) file.
+func readImportSource(path string) (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		MCPServers map[string]json.RawMessage `json:"mcpServers"`
+		Servers    map[string]json.RawMessage `json:"servers"`
+		MCP        *struct {
+			Servers map[string]json.RawMessage `json:"servers"`
+		} `json:"mcp"`
+	}
+	if err := json.Unmarshal(stripJSONComments(data), &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	switch {
+	case len(doc.MCPServers) > 0:
+		return doc.MCPServers, nil
+	case len(doc.Servers) > 0:
+		return doc.Servers, nil
+	case doc.MCP != nil && len(doc.MCP.Servers) > 0:
+		return doc.MCP.Servers, nil
+	}
+	return nil, fmt.Errorf("%s: no servers found (expected \"mcpServers\" or \"servers\")", path)
+}
+
+// stripJSONComments drops // and /* */ comments and trailing commas outside strings (VS Code
+// files are JSONC).
+func stripJSONComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inStr, esc := false, false
+	comma := -1 // index in out of a ',' not yet followed by a value
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if !inStr && comma >= 0 {
+			switch c {
+			case '}', ']':
+				out = append(out[:comma], out[comma+1:]...)
+				comma = -1
+			case ' ', '\t', '\r', '\n', '/':
+			default:
+				comma = -1
+			}
+		}
+		switch {
+		case inStr:
+			out = append(out, c)
+			if esc {
+				esc = false
+			} else if c == '\\' {
+				esc = true
+			} else if c == '"' {
+				inStr = false
+			}
+		case c == '"':
+			inStr = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := strings.Index(string(data[i+2:]), "*/")
+			if end < 0 {
+				return out
+			}
+			i += end + 3
+		case c == ',':
+			comma = len(out)
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+var (
+	editorVarRe = regexp.MustCompile(`\$\{(env|input):([^}]+)\}`)
+	badNameRe   = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+	badVarRe    = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+)
+
+// normalizeVars rewrites editor variables into mcp-launch ${...} references.
+func normalizeVars(s, baseDir string, notes *[]string) string {
+	home, _ := os.UserHomeDir()
+	s = strings.NewReplacer("${workspaceFolder}", baseDir, "${userHome}", home).Replace(s)
+	return editorVarRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := editorVarRe.FindStringSubmatch(m)
+		if sub[1] == "env" {
+			return "${" + sub[2] + "}"
+		}
+		// VS Code prompts for inputs; we read them from the environment instead.
+		v := strings.ToUpper(badVarRe.ReplaceAllString(sub[2], "_"))
+		*notes = append(*notes, fmt.Sprintf("${input:%s} → ${%s} (set it in the environment or an envFile)", sub[2], v))
+		return "${" + v + "}"
+	})
+}
+
+// normalizeServer converts one imported entry to our model; notes explain what changed.
+func normalizeServer(raw json.RawMessage, baseDir string) (MCPServer, []string, error) {
+	var in importedServer
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return MCPServer{}, nil, err
+	}
+	var notes []string
+	if in.Disabled {
+		return MCPServer{}, nil, errors.New("disabled in the source config")
+	}
+	out := MCPServer{
+		Command: normalizeVars(in.Command, baseDir, &notes),
+		URL:     normalizeVars(in.URL, baseDir, &notes),
+		EnvFile: normalizeVars(in.EnvFile, baseDir, &notes),
+	}
+	if out.URL == "" && in.ServerURL != "" {
+		out.URL = normalizeVars(in.ServerURL, baseDir, &notes)
+	}
+	for _, a := range in.Args {
+		out.Args = append(out.Args, normalizeVars(a, baseDir, &notes))
+	}
+	if len(in.Env) > 0 {
+		out.Env = make(map[string]string, len(in.Env))
+		for k, v := range in.Env {
+			s, ok := v.(string)
+			if !ok {
+				s = strings.Trim(string(mustJSON(v)), `"`)
+			}
+			out.Env[k] = normalizeVars(s, baseDir, &notes)
+		}
+	}
+	if len(in.Headers) > 0 {
+		out.Headers = make(map[string]string, len(in.Headers))
+		for k, v := range in.Headers {
+			out.Headers[k] = normalizeVars(v, baseDir, &notes)
+		}
+	}
+
+	switch strings.ToLower(in.Type) {
+	case "", "stdio":
+		if out.Command == "" && out.URL != "" {
+			// Cursor/Claude omit the type for remote servers.
+			out.Type = "streamable-http"
+			if strings.HasSuffix(strings.TrimRight(out.URL, "/"), "/sse") {
+				out.Type = "sse"
+			}
+			notes = append(notes, "type "+out.Type+" inferred from url")
+		}
+	case "sse":
+		out.Type = "sse"
+	case "http", "streamable-http", "streamablehttp", "streamable_http":
+		out.Type = "streamable-http"
+	default:
+		return MCPServer{}, nil, fmt.Errorf("unsupported type %q", in.Type)
+	}
+	if out.Command == "" && out.URL == "" {
+		return MCPServer{}, nil, errors.New("neither command nor url")
+	}
+	return out, notes, nil
+}
+
+func mustJSON(v any) []byte {
+	b, _ := json.Marshal(v)
+	return b
+}
+
+// importName makes a source server name valid for /<name>/ paths and operationIds.
+func importName(name string) string {
+	n := strings.Trim(badNameRe.ReplaceAllString(name, "-"), "-_")
+	for strings.Contains(n, "__") {
+		n = strings.ReplaceAll(n, "__", "_")
+	}
+	if slices.Contains(reservedServerNames, strings.ToLower(n)) {
+		n += "-server"
+	}
+	if n == "" {
+		n = "server"
+	}
+	return n
+}
+
+// importNames maps source server names to distinct importName results. Names that are already
+// valid keep them; others that normalize onto a taken name get a -2, -3, … suffix. srcNames
+// must be sorted so repeated imports pick the same names.
+func importNames(srcNames []string) map[string]string {
+	out := make(map[string]string, len(srcNames))
+	taken := map[string]bool{}
+	for _, n := range srcNames {
+		if importName(n) == n {
+			out[n] = n
+			taken[n] = true
+		}
+	}
+	for _, n := range srcNames {
+		if _, ok := out[n]; ok {
+			continue
+		}
+		base := importName(n)
+		name := base
+		for i := 2; taken[name]; i++ {
+			name = fmt.Sprintf("%s-%d", base, i)
+		}
+		out[n] = name
+		taken[name] = true
+	}
+	return out
+}
+
+func cmdImport() {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.Usage = func() { helpTopic("import") }
+	from := fs.String("from", "", "Source: claude | cursor | vscode")
+	src := fs.String("path", "", "Source file (default: the tool's usual location)")
+	target := fs.String("config", "", "Config to merge into (default: mcp.config.json / .yaml / .toml)")
+	force := fs.Bool("force", false, "Replace servers that already exist in the target")
+	dryRun := fs.Bool("dry-run", false, "Only show what would change")
+	_ = fs.Parse(os.Args[2:])
+
+	if *from != "claude" && *from != "cursor" && *from != "vscode" {
+		helpTopic("import")
+		os.Exit(2)
+	}
+	path := *src
+	if path == "" {
+		for _, p := range importPaths(*from) {
+			if _, err := os.Stat(p); err == nil {
+				path = p
+				break
+			}
+		}
+		if path == "" {
+			fmt.Printf("No %s config found (looked in: %s). Use --path.\n", *from, strings.Join(importPaths(*from), ", "))
+			os.Exit(1)
+		}
+	}
+	rawServers, err := readImportSource(path)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	// ${workspaceFolder} is the folder that holds .vscode/ or .cursor/.
+	baseDir, _ := filepath.Abs(filepath.Dir(path))
+	if b := filepath.Base(baseDir); b == ".vscode" || b == ".cursor" {
+		baseDir = filepath.Dir(baseDir)
+	}
+
+	if *target == "" {
+		*target = defaultConfigPath()
+	}
+	doc := map[string]any{}
+	if data, err := os.ReadFile(*target); err == nil {
+		d, err := decodeConfigDoc(data, configFormat(*target))
+		if err != nil {
+			fmt.Printf("%s: %v\n", *target, err)
+			os.Exit(1)
+		}
+		if m, ok := d.(map[string]any); ok {
+			doc = m
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	existing, _ := doc["mcpServers"].(map[string]any)
+	if existing == nil {
+		existing = map[string]any{}
+	}
+
+	names := make([]string, 0, len(rawServers))
+	for name := range rawServers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	targets := importNames(names)
+	fmt.Printf("Importing from %s into %s:\n", path, *target)
+	added, literals := 0, false
+	for _, srcName := range names {
+		s, notes, err := normalizeServer(rawServers[srcName], baseDir)
+		if err != nil {
+			fmt.Printf("  - %-20s skipped: %v\n", srcName, err)
+			continue
+		}
+		name := targets[srcName]
+		if base := importName(srcName); name != base {
+			notes = append(notes, fmt.Sprintf("%s is taken by another server in %s", base, path))
+		}
+		label := name
+		if name != srcName {
+			label = fmt.Sprintf("%s (from %q)", name, srcName)
+		}
+		if _, ok := existing[name]; ok && !*force {
+			fmt.Printf("  = %-20s already in %s; kept (use --force to replace)\n", label, *target)
+			continue
+		}
+		what := s.Command + " " + strings.Join(s.Args, " ")
+		if s.Type != "" {
+			what = s.Type + " " + s.URL
+		}
+		verb := "+"
+		if _, ok := existing[name]; ok {
+			verb = "~"
+		}
+		fmt.Printf("  %s %-20s %s\n", verb, label, strings.TrimSpace(what))
+		if len(s.Env) > 0 {
+			keys := make([]string, 0, len(s.Env))
+			for k := range s.Env {
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+			fmt.Printf("      env: %s\n", strings.Join(keys, ", "))
+		}
+		for _, n := range notes {
+			fmt.Println("      note:", n)
+		}
+		for _, v := range s.Env {
+			literals = literals || !strings.Contains(v, "${")
+		}
+		for _, v := range s.Headers {
+			literals = literals || !strings.Contains(v, "${")
+		}
+		var generic any
+		_ = json.Unmarshal(mustJSON(s), &generic)
+		existing[name] = generic
+		added++
+	}
+	if added == 0 {
+		fmt.Println("Nothing to import.")
+		return
+	}
+	if *dryRun {
+		fmt.Printf("Dry run: %d server(s) would be written to %s.\n", added, *target)
+		return
+	}
+	doc["mcpServers"] = existing
+	out, err := encodeConfigDoc(doc, configFormat(*target))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := writeFileAtomic(*target, out, 0644); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %d server(s) to %s.", added, *target)
+	if configFormat(*target) != formatJSON {
+		fmt.Print(" (comments in the file were not preserved)")
+	}
+	fmt.Println()
+	if literals {
+		fmt.Println("Some env/header values are literals; if they are tokens, consider moving them to ${VAR} or an envFile.")
+	}
+}
+
+// writeFileAtomic writes via a temp file in the same directory and renames it into place.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
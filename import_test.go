@@ -0,0 +1,135 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStripJSONComments(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"line comment", "{\"a\":1} // note", "{\"a\":1} "},
+		{"line comment keeps the newline", "{\n// note\n\"a\":1\n}", "{\n\n\"a\":1\n}"},
+		{"block comment", `{/* x */"a":1}`, `{"a":1}`},
+		{"unterminated block comment", `{"a":1 /* x`, `{"a":1 `},
+		{"comment markers in a string", `{"u":"http://x/*y*/"}`, `{"u":"http://x/*y*/"}`},
+		{"escaped quote in a string", `{"s":"a\"//b"}`, `{"s":"a\"//b"}`},
+		{"trailing comma in an object", `{"a":1,}`, `{"a":1}`},
+		{"trailing comma in an array", `[1,2,]`, `[1,2]`},
+		{"trailing comma before a comment", "[1,2, // last\n]", "[1,2 \n]"},
+		{"nested trailing commas", `{"a":[1,],"b":{"c":2,},}`, `{"a":[1],"b":{"c":2}}`},
+		{"comma and brace in a string", `{"a":",}",}`, `{"a":",}"}`},
+		{"comma before a value stays", "[1,\n2]", "[1,\n2]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(stripJSONComments([]byte(tt.in))); got != tt.want {
+				t.Errorf("stripJSONComments(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportSources(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	tests := []struct {
+		name     string
+		file     string // source file contents
+		server   string
+		want     MCPServer
+		wantNote string // substring of one of the notes
+		wantErr  bool
+	}{
+		{"claude desktop stdio",
+			`{"mcpServers":{"fs":{"command":"npx","args":["-y","server-filesystem","${userHome}/docs"],"env":{"DEBUG":true,"TOKEN":"${env:GH_TOKEN}"}}}}`,
+			"fs", MCPServer{Command: "npx", Args: []string{"-y", "server-filesystem", home + "/docs"}, Env: map[string]string{"DEBUG": "true", "TOKEN": "${GH_TOKEN}"}}, "", false},
+		{"claude desktop disabled", `{"mcpServers":{"old":{"command":"x","disabled":true}}}`, "old", MCPServer{}, "", true},
+		{"cursor sse without a type", `{"mcpServers":{"linear":{"url":"https://mcp.example.com/sse/"}}}`,
+			"linear", MCPServer{Type: "sse", URL: "https://mcp.example.com/sse/"}, "type sse inferred", false},
+		{"cursor http without a type",
+			`{"mcpServers":{"api":{"url":"https://mcp.example.com/mcp","headers":{"Authorization":"Bearer ${env:API_TOKEN}"}}}}`,
+			"api", MCPServer{Type: "streamable-http", URL: "https://mcp.example.com/mcp", Headers: map[string]string{"Authorization": "Bearer ${API_TOKEN}"}},
+			"type streamable-http inferred", false},
+		{"cursor serverUrl", `{"mcpServers":{"api":{"serverUrl":"https://mcp.example.com/mcp"}}}`,
+			"api", MCPServer{Type: "streamable-http", URL: "https://mcp.example.com/mcp"}, "", false},
+		{"vscode jsonc with inputs", `{
+  // Prompted by VS Code; read from the environment here.
+  "inputs": [{"type": "promptString", "id": "github-token", "password": true},],
+  "servers": {
+    "github": {"type": "http", "url": "https://api.example.com/mcp/", "headers": {"Authorization": "Bearer ${input:github-token}"},},
+  },
+}`, "github", MCPServer{Type: "streamable-http", URL: "https://api.example.com/mcp/", Headers: map[string]string{"Authorization": "Bearer ${GITHUB_TOKEN}"}},
+			"${input:github-token} → ${GITHUB_TOKEN}", false},
+		{"vscode stdio in the workspace",
+			`{"servers":{"local":{"type":"stdio","command":"${workspaceFolder}/bin/srv","args":["--root","${workspaceFolder}"],"envFile":"${workspaceFolder}/.env"}}}`,
+			"local", MCPServer{Command: "/work/bin/srv", Args: []string{"--root", "/work"}, EnvFile: "/work/.env"}, "", false},
+		{"vscode settings.json", `{"editor.tabSize": 2, "mcp": {"servers": {"time": {"command": "uvx", "args": ["mcp-server-time"]}}}}`,
+			"time", MCPServer{Command: "uvx", Args: []string{"mcp-server-time"}}, "", false},
+		{"unsupported type", `{"servers":{"ws":{"type":"websocket","url":"wss://x"}}}`, "ws", MCPServer{}, "", true},
+		{"neither command nor url", `{"servers":{"empty":{"type":"stdio"}}}`, "empty", MCPServer{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "mcp.json")
+			if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			raw, err := readImportSource(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, notes, err := normalizeServer(raw[tt.server], "/work")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeServer error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("server = %+v, want %+v", got, tt.want)
+			}
+			if tt.wantNote != "" && !strings.Contains(strings.Join(notes, "\n"), tt.wantNote) {
+				t.Errorf("notes = %q, want one containing %q", notes, tt.wantNote)
+			}
+		})
+	}
+}
+
+func TestImportName(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"github", "github"},
+		{"my.server", "my-server"},
+		{"My Server (beta)", "My-Server-beta"},
+		{"a__b___c", "a_b_c"},
+		{"-edge_", "edge"},
+		{"docs", "docs-server"},
+		{"MCP", "MCP-server"},
+		{"...", "server"},
+	}
+	for _, tt := range tests {
+		if got := importName(tt.in); got != tt.want {
+			t.Errorf("importName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// Source names that normalize to the same name get distinct names; already-valid ones win.
+func TestImportNames(t *testing.T) {
+	got := importNames([]string{"my server", "my-server", "my.server", "my-server-2", "time"})
+	want := map[string]string{
+		"my-server":   "my-server",
+		"my-server-2": "my-server-2",
+		"time":        "time",
+		"my server":   "my-server-3",
+		"my.server":   "my-server-4",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("importNames = %v, want %v", got, want)
+	}
+}
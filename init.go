@@ -0,0 +1,485 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ---------- `mcp-launch init` ----------
+//
+// init writes a config from a built-in catalog of common MCP servers, plus an mcp-launch.yaml
+// manifest with the tunnel choice. Interactive on a terminal; scripts use --add/--set. Nothing
+// here touches the network: the catalog only records how each server is launched.
+
+// catalogParam is a value substituted for {key} in a catalog entry's args/env.
+type catalogParam struct {
+	Key      string
+	Prompt   string
+	Default  func() string
+	Optional bool // empty is allowed; args/env that use it are dropped
+}
+
+type catalogEntry struct {
+	Name    string
+	Desc    string
+	Command string
+	Args    []string
+	Env     map[string]string
+	Params  []catalogParam
+	Default bool // selected when nothing is chosen
+}
+
+func cwdDefault() string {
+	wd, _ := os.Getwd()
+	return wd
+}
+
+// localTimezone returns the IANA name of the local zone (TZ, else /etc/localtime), or UTC.
+func localTimezone() string {
+	if tz := strings.TrimPrefix(os.Getenv("TZ"), ":"); tz != "" && !filepath.IsAbs(tz) {
+		return tz
+	}
+	if p, err := os.Readlink("/etc/localtime"); err == nil {
+		if _, zone, ok := strings.Cut(p, "zoneinfo/"); ok {
+			return zone
+		}
+	}
+	return "UTC"
+}
+
+var serverCatalog = []catalogEntry{
+	{
+		Name: "filesystem", Desc: "Read/write files under a root directory",
+		Command: "npx", Args: []string{"-y", "@modelcontextprotocol/server-filesystem", "{root}"},
+		Params:  []catalogParam{{Key: "root", Prompt: "root path", Default: cwdDefault}},
+		Default: true,
+	},
+	{
+		Name: "git", Desc: "Inspect and edit a git repository",
+		Command: "uvx", Args: []string{"mcp-server-git", "--repository", "{repo}"},
+		Params: []catalogParam{{Key: "repo", Prompt: "repository path", Default: cwdDefault}},
+	},
+	{
+		Name: "time", Desc: "Current time and timezone conversion",
+		Command: "uvx", Args: []string{"mcp-server-time", "--local-timezone={timezone}"},
+		Params:  []catalogParam{{Key: "timezone", Prompt: "timezone", Default: localTimezone}},
+		Default: true,
+	},
+	{
+		Name: "fetch", Desc: "Fetch web pages as markdown",
+		Command: "uvx", Args: []string{"mcp-server-fetch"},
+	},
+	{
+		Name: "sqlite", Desc: "Query a SQLite database",
+		Command: "uvx", Args: []string{"mcp-server-sqlite", "--db-path", "{db}"},
+		Params: []catalogParam{{Key: "db", Prompt: "database file", Default: func() string { return "data.db" }}},
+	},
+	{
+		Name: "memory", Desc: "Knowledge-graph memory",
+		Command: "npx", Args: []string{"-y", "@modelcontextprotocol/server-memory"},
+		Env:    map[string]string{"MEMORY_FILE_PATH": "{file}"},
+		Params: []catalogParam{{Key: "file", Prompt: "memory file (empty: server default)", Default: func() string { return "" }, Optional: true}},
+	},
+	{
+		Name: "github", Desc: "GitHub issues, PRs and code (token from $GITHUB_TOKEN)",
+		Command: "npx", Args: []string{"-y", "@modelcontextprotocol/server-github"},
+		Env: map[string]string{"GITHUB_PERSONAL_ACCESS_TOKEN": "${GITHUB_TOKEN}"},
+	},
+	{
+		Name: "serena", Desc: "Semantic code navigation and editing",
+		Command: "uvx", Args: []string{"--from", "git+https://github.com/oraios/serena", "serena", "start-mcp-server", "--context", "ide-assistant", "--project", "{project}"},
+		Params: []catalogParam{{Key: "project", Prompt: "project path", Default: cwdDefault}},
+	},
+}
+
+func catalogLookup(name string) *catalogEntry {
+	for i := range serverCatalog {
+		if serverCatalog[i].Name == name {
+			return &serverCatalog[i]
+		}
+	}
+	return nil
+}
+
+func catalogNames() []string {
+	names := make([]string, len(serverCatalog))
+	for i, e := range serverCatalog {
+		names[i] = e.Name
+	}
+	return names
+}
+
+// build renders the entry with vals; args or env values that reference an empty optional param are dropped.
+func (e catalogEntry) build(vals map[string]string) MCPServer {
+	subst := func(s string) (string, bool) {
+		for _, p := range e.Params {
+			ref := "{" + p.Key + "}"
+			if strings.Contains(s, ref) {
+				if vals[p.Key] == "" {
+					return "", false
+				}
+				s = strings.ReplaceAll(s, ref, vals[p.Key])
+			}
+		}
+		return s, true
+	}
+	s := MCPServer{Command: e.Command}
+	for _, a := range e.Args {
+		if v, ok := subst(a); ok {
+			s.Args = append(s.Args, v)
+		}
+	}
+	for k, v := range e.Env {
+		if v, ok := subst(v); ok {
+			if s.Env == nil {
+				s.Env = map[string]string{}
+			}
+			s.Env[k] = v
+		}
+	}
+	return s
+}
+
+// parseSets turns --set [server.]key=val into per-server values; a bare key applies to every
+// selected server that has that parameter.
+func parseSets(sets []string, selected []string) (map[string]map[string]string, error) {
+	out := map[string]map[string]string{}
+	for _, name := range selected {
+		out[name] = map[string]string{}
+	}
+	for _, kv := range sets {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("--set %q: expected key=value", kv)
+		}
+		server, key, scoped := strings.Cut(k, ".")
+		if !scoped {
+			server, key = "", k
+		}
+		matched := false
+		for _, name := range selected {
+			if scoped && name != server {
+				continue
+			}
+			if slices.ContainsFunc(catalogLookup(name).Params, func(p catalogParam) bool { return p.Key == key }) {
+				out[name][key] = v
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("--set %s: no selected server has a %q parameter", k, key)
+		}
+	}
+	return out, nil
+}
+
+// initPrompter asks questions on a terminal; answers fall back to the shown default.
+type initPrompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func (p initPrompter) ask(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	line, _ := p.in.ReadString('\n') // EOF reads as an empty answer
+	if line = strings.TrimSpace(line); line == "" {
+		return def
+	}
+	return line
+}
+
+// pickServers shows the catalog and reads a selection by number or name.
+func (p initPrompter) pickServers() []string {
+	fmt.Fprintln(p.out, "Available MCP servers:")
+	var defs []string
+	for i, e := range serverCatalog {
+		fmt.Fprintf(p.out, "  %2d) %-11s %s\n", i+1, e.Name, e.Desc)
+		if e.Default {
+			defs = append(defs, e.Name)
+		}
+	}
+	for {
+		ans := p.ask("Servers to add (numbers or names, comma-separated)", strings.Join(defs, ","))
+		var picked []string
+		bad := ""
+		for _, f := range strings.FieldsFunc(ans, func(r rune) bool { return r == ',' || r == ' ' }) {
+			name := f
+			if n, err := strconv.Atoi(f); err == nil && n >= 1 && n <= len(serverCatalog) {
+				name = serverCatalog[n-1].Name
+			}
+			if catalogLookup(name) == nil {
+				bad = f
+				break
+			}
+			if !slices.Contains(picked, name) {
+				picked = append(picked, name)
+			}
+		}
+		if bad == "" && len(picked) > 0 {
+			return picked
+		}
+		fmt.Fprintf(p.out, "  unknown server %q; pick from 1-%d or a name above\n", bad, len(serverCatalog))
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// initManifest renders the manifest at manifestPath for a single stack using configPath. The
+// config is written relative to the manifest's directory, which is how loadManifest reads it.
+func initManifest(manifestPath, configPath, tunnel, tunnelName, hostname string) []byte {
+	ref := configPath
+	if !filepath.IsAbs(configPath) {
+		dir, err1 := filepath.Abs(filepath.Dir(manifestPath))
+		cfg, err2 := filepath.Abs(configPath)
+		if rel, err := filepath.Rel(dir, cfg); err1 == nil && err2 == nil && err == nil {
+			ref = rel
+		}
+	}
+	m := manifest{
+		Tunnel:     tunnel,
+		TunnelName: tunnelName,
+		Stacks:     []manifestStack{{Name: nameFromPath(configPath, 0), Config: ref, Hostname: hostname}},
+	}
+	const header = "# mcp-launch stack manifest: `mcp-launch up` reads it when no --config is given.\n" +
+		"# Flags on the command line override these values.\n"
+	format := configFormat(manifestPath)
+	if format != formatYAML {
+		var doc any
+		_ = json.Unmarshal(mustJSON(m), &doc)
+		out, _ := encodeConfigDoc(doc, format)
+		if format == formatTOML {
+			out = append([]byte(header), out...)
+		}
+		return out
+	}
+	// The manifest's keys are its json tags; decoding the JSON into a node keeps them in field order.
+	var doc yaml.Node
+	_ = yaml.Unmarshal(mustJSON(m), &doc)
+	blockStyle(&doc)
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	_ = enc.Encode(&doc)
+	_ = enc.Close()
+	return buf.Bytes()
+}
+
+// blockStyle clears the flow and quoting styles a node decoded from JSON carries, so it encodes
+// as ordinary block YAML (strings that need quotes still get them).
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+// writeNew writes data to path unless it exists (and force is off); it reports what happened.
+func writeNew(path string, data []byte, force bool) bool {
+	if _, err := os.Stat(path); err == nil && !force {
+		fmt.Println(path, "already exists; not overwriting (use --force)")
+		return false
+	}
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("Wrote", path)
+	return true
+}
+
+func cmdInit() {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	fs.Usage = func() { helpTopic("init") }
+	var adds, sets stringSlice
+	fs.Var(&adds, "add", "Catalog server to add (repeatable); skips the questions")
+	fs.Var(&sets, "set", "Parameter for added servers: key=val or server.key=val (repeatable)")
+	config := fs.String("config", defaultConfig, "Config to write (.json, .yaml or .toml)")
+	manifestPath := fs.String("manifest", defaultManifestNames[0], "Manifest to write")
+	noManifest := fs.Bool("no-manifest", false, "Do not write a manifest")
+	tunnel := fs.String("tunnel", "", "Tunnel for the manifest: quick | named | none (default quick)")
+	tunnelName := fs.String("tunnel-name", "", "Named tunnel (with --tunnel named)")
+	hostname := fs.String("hostname", "", "Public hostname for the named tunnel")
+	yes := fs.Bool("yes", false, "Accept defaults without asking")
+	force := fs.Bool("force", false, "Overwrite existing files")
+	list := fs.Bool("list", false, "List the server catalog and exit")
+	_ = fs.Parse(os.Args[2:])
+
+	if *list {
+		for _, e := range serverCatalog {
+			var params []string
+			for _, p := range e.Params {
+				params = append(params, p.Key)
+			}
+			fmt.Printf("  %-11s %s", e.Name, e.Desc)
+			if len(params) > 0 {
+				fmt.Printf(" (--set %s=...)", strings.Join(params, "=... --set "))
+			}
+			fmt.Println()
+		}
+		return
+	}
+
+	interactive := len(adds) == 0 && !*yes && isTerminal(os.Stdin)
+	p := initPrompter{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+
+	var selected []string
+	switch {
+	case len(adds) > 0:
+		for _, a := range adds {
+			for _, name := range strings.Split(a, ",") {
+				if catalogLookup(name) == nil {
+					fmt.Printf("Unknown server %q (catalog: %s)\n", name, strings.Join(catalogNames(), ", "))
+					os.Exit(2)
+				}
+				if !slices.Contains(selected, name) {
+					selected = append(selected, name)
+				}
+			}
+		}
+	case interactive:
+		selected = p.pickServers()
+	default:
+		for _, e := range serverCatalog {
+			if e.Default {
+				selected = append(selected, e.Name)
+			}
+		}
+	}
+	vals, err := parseSets(sets, selected)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	cfg := MCPConfig{MCPServers: map[string]MCPServer{}}
+	for _, name := range selected {
+		e := catalogLookup(name)
+		for _, prm := range e.Params {
+			if _, ok := vals[name][prm.Key]; ok {
+				continue
+			}
+			def := prm.Default()
+			if interactive {
+				vals[name][prm.Key] = p.ask("  "+name+": "+prm.Prompt, def)
+			} else {
+				vals[name][prm.Key] = def
+			}
+		}
+		for _, prm := range e.Params {
+			if vals[name][prm.Key] == "" && !prm.Optional {
+				fmt.Printf("%s: %s is required (--set %s.%s=...)\n", name, prm.Prompt, name, prm.Key)
+				os.Exit(2)
+			}
+		}
+		cfg.MCPServers[name] = e.build(vals[name])
+	}
+
+	if !*noManifest {
+		if *tunnel == "" {
+			*tunnel = "quick"
+			if interactive {
+				*tunnel = p.ask("Tunnel (quick | named | none)", "quick")
+			}
+		}
+		switch *tunnel {
+		case "quick", "none":
+		case "named":
+			if interactive && *tunnelName == "" {
+				*tunnelName = p.ask("  Cloudflare tunnel name", "mcp-launch")
+			}
+			if interactive && *hostname == "" {
+				*hostname = p.ask("  Public hostname (e.g. tools.example.com)", "")
+			}
+		default:
+			fmt.Println("Unknown tunnel:", *tunnel, "(expected quick, named or none)")
+			os.Exit(2)
+		}
+	}
+
+	var out []byte
+	if format := configFormat(*config); format == formatJSON {
+		out, _ = json.MarshalIndent(cfg, "", "  ")
+		out = append(out, '\n')
+	} else {
+		var doc any
+		_ = json.Unmarshal(mustJSON(cfg), &doc)
+		if out, err = encodeConfigDoc(doc, format); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	writeNew(*config, out, *force)
+	if !*noManifest {
+		writeNew(*manifestPath, initManifest(*manifestPath, *config, *tunnel, *tunnelName, *hostname), *force)
+	}
+	for _, name := range selected {
+		if s := cfg.MCPServers[name]; slices.ContainsFunc(mapValues(s.Env), func(v string) bool { return strings.Contains(v, "${") }) {
+			fmt.Printf("Note: %s reads secrets from the environment (%s); set them before 'up'.\n", name, strings.Join(sortedEnvRefs(s.Env), ", "))
+		}
+	}
+
+	if _, err := os.Stat(statePath()); errors.Is(err, os.ErrNotExist) {
+		ensureStateDir()
+		st := State{
+			StartedAt: time.Now().Format(time.RFC3339),
+			Instances: nil,
+		}
+		saveState(&st)
+		fmt.Println("Initialized .mcp-launch/state.json with defaults")
+	}
+	fmt.Println("Next: mcp-launch doctor && mcp-launch up")
+}
+
+func mapValues(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for _, v := range m {
+		out = append(out, v)
+	}
+	return out
+}
+
+// sortedEnvRefs lists the ${VAR} names referenced by env values.
+func sortedEnvRefs(env map[string]string) []string {
+	var refs []string
+	for _, v := range env {
+		for rest := v; ; {
+			i := strings.Index(rest, "${")
+			if i < 0 {
+				break
+			}
+			j := strings.IndexByte(rest[i:], '}')
+			if j < 0 {
+				break
+			}
+			if name, _, _ := strings.Cut(rest[i+2:i+j], ":-"); !slices.Contains(refs, name) {
+				refs = append(refs, name)
+			}
+			rest = rest[i+j+1:]
+		}
+	}
+	slices.Sort(refs)
+	return refs
+}
@@ -0,0 +1,86 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInitManifestConfigPath(t *testing.T) {
+	dir := chdirTemp(t)
+	tests := []struct {
+		manifest, config string
+		want             string
+	}{
+		{"mcp-launch.yaml", "mcp.config.json", "mcp.config.json"},
+		{"sub/mcp-launch.yaml", "sub/x.json", "x.json"},
+		{"sub/mcp-launch.yaml", "x.json", filepath.Join("..", "x.json")},
+		{"mcp-launch.yaml", "sub/x.json", filepath.Join("sub", "x.json")},
+		{filepath.Join(dir, "sub", "m.yaml"), "sub/x.json", "x.json"},
+		{"sub/m.yaml", "/etc/mcp/x.json", "/etc/mcp/x.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.manifest+" "+tt.config, func(t *testing.T) {
+			out := string(initManifest(tt.manifest, tt.config, "none", "", ""))
+			if !strings.Contains(out, "config: "+tt.want+"\n") {
+				t.Fatalf("manifest does not point at %s:\n%s", tt.want, out)
+			}
+			// What loadManifest makes of it must be the config init wrote.
+			if err := os.MkdirAll(filepath.Dir(tt.manifest), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(tt.manifest, []byte(out), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			m, err := loadManifest(tt.manifest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := filepath.Abs(m.Stacks[0].Config)
+			want, _ := filepath.Abs(tt.config)
+			if got != want {
+				t.Errorf("loadManifest resolves config to %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestInitManifestRoundTrip(t *testing.T) {
+	chdirTemp(t)
+	tests := []struct {
+		name                         string
+		manifest, config             string
+		tunnel, tunnelName, hostname string
+		wantName                     string
+	}{
+		{"quick tunnel", "mcp-launch.yaml", "mcp.config.json", "quick", "", "", "mcp-config"},
+		{"named tunnel", "mcp-launch.yaml", "tools.yaml", "named", "my-tunnel", "gpt.example.com", "tools"},
+		{"values YAML would retype", "mcp-launch.yml", "true.json", "none", "yes", "", "true"},
+		{"special characters", "mcp-launch.yaml", "a #b: c.json", "none", "", "", "a--b--c"},
+		{"json manifest", "mcp-launch.json", "mcp.config.json", "named", "t", "h.example.com", "mcp-config"},
+		{"toml manifest", "mcp-launch.toml", "mcp.config.json", "quick", "", "", "mcp-config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := initManifest(tt.manifest, tt.config, tt.tunnel, tt.tunnelName, tt.hostname)
+			if err := os.WriteFile(tt.manifest, out, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			m, err := loadManifest(tt.manifest)
+			if err != nil {
+				t.Fatalf("%v\n%s", err, out)
+			}
+			want := manifest{Tunnel: tt.tunnel, TunnelName: tt.tunnelName, Stacks: []manifestStack{{Name: tt.wantName, Config: tt.config, Hostname: tt.hostname}}}
+			if tt.hostname != "" {
+				want.Stacks[0].PublicURL = "https://" + tt.hostname
+			}
+			if !reflect.DeepEqual(*m, want) {
+				t.Errorf("loadManifest = %+v, want %+v\n%s", *m, want, out)
+			}
+		})
+	}
+}
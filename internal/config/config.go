@@ -4,12 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
 // Claude-style config: {"mcpServers": { "name": {"command": "...", "args": ["..."], ...}, ... }}
 // We only need the names to know which subpaths mcpo exposes.
 type Config struct {
-	MCPServers map[string]any `json:"mcpServers"`
+	MCPServers map[string]any `json:"mcpServers" yaml:"mcpServers" toml:"mcpServers"`
 }
 
 func Load(path string) (*Config, error) {
@@ -18,8 +23,19 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("read config: %w", err)
 	}
 	var c Config
-	if err := json.Unmarshal(data, &c); err != nil {
-		return nil, fmt.Errorf("parse config JSON: %w", err)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("parse config YAML: %w", err)
+		}
+	case ".toml":
+		if _, err := toml.Decode(string(data), &c); err != nil {
+			return nil, fmt.Errorf("parse config TOML: %w", err)
+		}
+	default:
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("parse config JSON: %w", err)
+		}
 	}
 	if len(c.MCPServers) == 0 {
 		return nil, fmt.Errorf("config has no mcpServers")
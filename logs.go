@@ -0,0 +1,362 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ---------- per-stack, per-process log files ----------
+
+const (
+	logsDirName     = "logs"
+	logTimeLayout   = "2006-01-02T15:04:05.000Z07:00"
+	defaultLogLines = 100
+)
+
+// logRotation controls when .mcp-launch/logs/<stack>/<proc>.log is rotated to <proc>.log.1 …
+type logRotation struct {
+	MaxSize int64         // bytes; 0 = no size limit
+	MaxAge  time.Duration // age of the first line; 0 = no age limit
+	Keep    int           // rotated files to keep
+}
+
+var logRotate = logRotation{MaxSize: 10 << 20, MaxAge: 7 * 24 * time.Hour, Keep: 5}
+
+func logsDir() string { return filepath.Join(getStateDir(), logsDirName) }
+
+func procLogPath(stack, proc string) string {
+	return filepath.Join(logsDir(), stack, proc+".log")
+}
+
+// rotatingLog appends timestamped lines to one process log and rotates it by size/age.
+type rotatingLog struct {
+	mu      sync.Mutex
+	path    string
+	f       *os.File
+	size    int64
+	started time.Time // timestamp of the first line in the current file
+	partial []byte    // Write() data not yet terminated by a newline
+	raw     bool      // write lines as-is (JSON lines carry their own timestamp)
+}
+
+var (
+	procLogsMu sync.Mutex
+	procLogs   = map[string]*rotatingLog{}
+)
+
+// procLogFor returns the log for a "proc#stack" tag (e.g. "mcpo#code"), or nil for other tags.
+func procLogFor(tag string) *rotatingLog {
+	proc, stack, ok := strings.Cut(tag, "#")
+	if !ok || proc == "" || stack == "" {
+		return nil
+	}
+	procLogsMu.Lock()
+	defer procLogsMu.Unlock()
+	if l, ok := procLogs[tag]; ok {
+		return l
+	}
+	l := &rotatingLog{path: procLogPath(stack, proc)}
+	procLogs[tag] = l
+	return l
+}
+
+// logProcLine records one of our own messages in a process log (e.g. restart notices).
+func logProcLine(tag, line string) {
+	if l := procLogFor(tag); l != nil {
+		l.WriteLine(line)
+	}
+}
+
+func (l *rotatingLog) WriteLine(line string) {
+	line = redactSecrets(line)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.f == nil {
+		if err := l.open(now); err != nil {
+			return
+		}
+	}
+	if (logRotate.MaxSize > 0 && l.size >= logRotate.MaxSize) ||
+		(logRotate.MaxAge > 0 && l.size > 0 && now.Sub(l.started) >= logRotate.MaxAge) {
+		l.rotate(now)
+		if l.f == nil {
+			return
+		}
+	}
+	if l.size == 0 {
+		l.started = now
+	}
+	var n int
+	if l.raw {
+		n, _ = fmt.Fprintln(l.f, line)
+	} else {
+		n, _ = fmt.Fprintf(l.f, "%s %s\n", now.Format(logTimeLayout), line)
+	}
+	l.size += int64(n)
+}
+
+// Write lets a rotatingLog back a log.Logger; complete lines are timestamped individually.
+func (l *rotatingLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	buf := append(l.partial, p...)
+	var lines []string
+	for {
+		i := slices.Index(buf, '\n')
+		if i < 0 {
+			break
+		}
+		lines = append(lines, string(buf[:i]))
+		buf = buf[i+1:]
+	}
+	l.partial = slices.Clone(buf)
+	l.mu.Unlock()
+	for _, line := range lines {
+		l.WriteLine(line)
+	}
+	return len(p), nil
+}
+
+func (l *rotatingLog) open(now time.Time) error {
+	// Subprocess output can echo keys and tokens: keep it owner-only.
+	_ = os.MkdirAll(filepath.Dir(l.path), 0o700)
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	l.f = f
+	l.size = 0
+	l.started = now
+	if fi, err := f.Stat(); err == nil {
+		l.size = fi.Size()
+	}
+	if l.size > 0 && !l.raw {
+		if ts, ok := firstLineTime(l.path); ok {
+			l.started = ts
+		}
+	}
+	return nil
+}
+
+// rotate shifts <proc>.log → .1 → .2 … dropping anything beyond Keep, then reopens.
+func (l *rotatingLog) rotate(now time.Time) {
+	_ = l.f.Close()
+	l.f = nil
+	keep := max(logRotate.Keep, 1)
+	_ = os.Remove(fmt.Sprintf("%s.%d", l.path, keep))
+	for i := keep - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+	}
+	_ = os.Rename(l.path, l.path+".1")
+	_ = l.open(now)
+}
+
+func firstLineTime(path string) (time.Time, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+	line, _ := bufio.NewReader(f).ReadString('\n')
+	ts, _, ok := splitLogLine(line)
+	return ts, ok
+}
+
+func splitLogLine(line string) (time.Time, string, bool) {
+	line = strings.TrimRight(line, "\n")
+	stamp, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		return time.Time{}, line, false
+	}
+	ts, err := time.Parse(logTimeLayout, stamp)
+	if err != nil {
+		return time.Time{}, line, false
+	}
+	return ts, rest, true
+}
+
+// ---------- `mcp-launch logs` ----------
+
+type logEntry struct {
+	at   time.Time
+	tag  string
+	text string
+}
+
+// logSource is one <stack>/<proc>.log being read (and followed with -f).
+type logSource struct {
+	tag    string
+	path   string
+	offset int64
+}
+
+func cmdLogs() {
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	fs.Usage = func() { helpTopic("logs") }
+	procName := fs.String("proc", "", "Only this process: mcpo | cloudflared | proxy")
+	follow := fs.Bool("f", false, "Follow: keep printing new lines")
+	since := fs.Duration("since", 0, "Only lines newer than this (e.g. 10m, 2h)")
+	grep := fs.String("grep", "", "Only lines matching this regular expression")
+	lines := fs.Int("n", defaultLogLines, "Without --since, print at most the last N lines (0 = all)")
+
+	// Allow the stack name before or after the flags.
+	args := os.Args[2:]
+	stack := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		stack, args = args[0], args[1:]
+	}
+	_ = fs.Parse(args)
+	if stack == "" && fs.NArg() > 0 {
+		stack = fs.Arg(0)
+	}
+
+	var re *regexp.Regexp
+	if *grep != "" {
+		var err error
+		if re, err = regexp.Compile(*grep); err != nil {
+			fmt.Println("Invalid --grep:", err)
+			os.Exit(2)
+		}
+	}
+	sources := findLogSources(stack, *procName)
+	if len(sources) == 0 {
+		fmt.Println("No logs found under", logsDir())
+		return
+	}
+
+	match := func(e logEntry) bool {
+		if *since > 0 && e.at.Before(time.Now().Add(-*since)) {
+			return false
+		}
+		return re == nil || re.MatchString(e.text)
+	}
+	var entries []logEntry
+	for _, src := range sources {
+		for _, p := range rotatedFiles(src.path) {
+			entries = append(entries, readLogEntries(src.tag, p, 0, nil)...)
+		}
+		if fi, err := os.Stat(src.path); err == nil {
+			src.offset = fi.Size()
+		}
+	}
+	entries = slices.DeleteFunc(entries, func(e logEntry) bool { return !match(e) })
+	slices.SortStableFunc(entries, func(a, b logEntry) int { return a.at.Compare(b.at) })
+	if *since == 0 && *lines > 0 && len(entries) > *lines {
+		entries = entries[len(entries)-*lines:]
+	}
+	for _, e := range entries {
+		printLogEntry(e)
+	}
+	if !*follow {
+		return
+	}
+	for {
+		time.Sleep(500 * time.Millisecond)
+		for _, src := range sources {
+			fi, err := os.Stat(src.path)
+			if err != nil {
+				continue
+			}
+			if fi.Size() < src.offset {
+				src.offset = 0 // rotated underneath us
+			}
+			if fi.Size() == src.offset {
+				continue
+			}
+			for _, e := range readLogEntries(src.tag, src.path, src.offset, &src.offset) {
+				if match(e) {
+					printLogEntry(e)
+				}
+			}
+		}
+	}
+}
+
+func printLogEntry(e logEntry) {
+	fmt.Printf("%s [%s] %s\n", e.at.Local().Format("2006-01-02 15:04:05"), e.tag, e.text)
+}
+
+// findLogSources lists <stack>/<proc>.log files, optionally narrowed to one stack and/or process.
+func findLogSources(stack, proc string) []*logSource {
+	var out []*logSource
+	stacks, _ := os.ReadDir(logsDir())
+	for _, sd := range stacks {
+		if !sd.IsDir() || (stack != "" && sd.Name() != stack) {
+			continue
+		}
+		files, _ := os.ReadDir(filepath.Join(logsDir(), sd.Name()))
+		for _, f := range files {
+			name, ok := strings.CutSuffix(f.Name(), ".log")
+			if !ok || (proc != "" && name != proc) {
+				continue
+			}
+			out = append(out, &logSource{tag: name + "#" + sd.Name(), path: filepath.Join(logsDir(), sd.Name(), f.Name())})
+		}
+	}
+	return out
+}
+
+// rotatedFiles returns path.N … path.1, path (oldest first) that exist. It looks at what is on
+// disk rather than at --log-keep, which only the `up` that wrote the files knows.
+func rotatedFiles(path string) []string {
+	matches, _ := filepath.Glob(path + ".*")
+	type rotated struct {
+		n    int
+		path string
+	}
+	var files []rotated
+	for _, m := range matches {
+		if n, err := strconv.Atoi(strings.TrimPrefix(m, path+".")); err == nil && n > 0 {
+			files = append(files, rotated{n, m})
+		}
+	}
+	slices.SortFunc(files, func(a, b rotated) int { return b.n - a.n })
+	out := make([]string, 0, len(files)+1)
+	for _, f := range files {
+		out = append(out, f.path)
+	}
+	return append(out, path)
+}
+
+// readLogEntries reads lines from offset; if next is non-nil it receives the new offset.
+func readLogEntries(tag, path string, offset int64, next *int64) []logEntry {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil
+	}
+	var out []logEntry
+	r := bufio.NewReader(f)
+	pos := offset
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break // keep a trailing partial line for the next poll
+		}
+		pos += int64(len(line))
+		ts, text, ok := splitLogLine(line)
+		if !ok && len(out) > 0 {
+			ts = out[len(out)-1].at
+		}
+		out = append(out, logEntry{at: ts, tag: tag, text: text})
+	}
+	if next != nil {
+		*next = pos
+	}
+	return out
+}
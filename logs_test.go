@@ -0,0 +1,127 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingLogRotates(t *testing.T) {
+	old := logRotate
+	t.Cleanup(func() { logRotate = old })
+	logRotate = logRotation{MaxSize: 64, Keep: 2}
+
+	path := filepath.Join(t.TempDir(), "stack", "mcpo.log")
+	l := &rotatingLog{path: path}
+	for i := 0; i < 8; i++ {
+		l.WriteLine(fmt.Sprintf("line %d %s", i, strings.Repeat("x", 20)))
+	}
+	// Each line is ~55 bytes, so every second line starts a new file; only Keep old ones remain.
+	for _, tt := range []struct {
+		file   string
+		exists bool
+		lines  string
+	}{
+		{path, true, "line 6,line 7"},
+		{path + ".1", true, "line 4,line 5"},
+		{path + ".2", true, "line 2,line 3"},
+		{path + ".3", false, ""},
+	} {
+		var got []string
+		for _, e := range readLogEntries("mcpo#stack", tt.file, 0, nil) {
+			got = append(got, strings.Join(strings.Fields(e.text)[:2], " "))
+		}
+		if _, err := os.Stat(tt.file); (err == nil) != tt.exists {
+			t.Errorf("%s exists = %v, want %v", filepath.Base(tt.file), err == nil, tt.exists)
+		}
+		if strings.Join(got, ",") != tt.lines {
+			t.Errorf("%s holds %q, want %s", filepath.Base(tt.file), got, tt.lines)
+		}
+		if fi, err := os.Stat(tt.file); err == nil && fi.Mode().Perm() != 0o600 {
+			t.Errorf("%s mode = %v, want 0600", filepath.Base(tt.file), fi.Mode().Perm())
+		}
+	}
+	if fi, _ := os.Stat(filepath.Dir(path)); fi.Mode().Perm() != 0o700 {
+		t.Errorf("log dir mode = %v, want 0700", fi.Mode().Perm())
+	}
+}
+
+// A partial Write is held back until its newline arrives.
+func TestRotatingLogWritePartialLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proxy.log")
+	l := &rotatingLog{path: path}
+	_, _ = l.Write([]byte("first\nsec"))
+	_, _ = l.Write([]byte("ond\n"))
+	var got []string
+	for _, e := range readLogEntries("proxy#s", path, 0, nil) {
+		got = append(got, e.text)
+	}
+	if want := []string{"first", "second"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestReadLogEntriesOffsets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcpo.log")
+	t1 := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Second)
+	head := t1.Format(logTimeLayout) + " one\n" + "  continued\n" + t2.Format(logTimeLayout) + " two\n"
+	if err := os.WriteFile(path, []byte(head+"par"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var next int64
+	entries := readLogEntries("mcpo#s", path, 0, &next)
+	if next != int64(len(head)) {
+		t.Errorf("next = %d, want %d (before the partial line)", next, len(head))
+	}
+	want := []logEntry{{t1, "mcpo#s", "one"}, {t1, "mcpo#s", "  continued"}, {t2, "mcpo#s", "two"}}
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %+v", entries)
+	}
+	for i := range want {
+		if !entries[i].at.Equal(want[i].at) || entries[i].text != want[i].text {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("tial\n")
+	f.Close()
+	entries = readLogEntries("mcpo#s", path, next, &next)
+	if len(entries) != 1 || entries[0].text != "partial" {
+		t.Errorf("after completing the line: %+v", entries)
+	}
+	if fi, _ := os.Stat(path); next != fi.Size() {
+		t.Errorf("next = %d, want end of file %d", next, fi.Size())
+	}
+}
+
+func TestRotatedFiles(t *testing.T) {
+	old := logRotate
+	t.Cleanup(func() { logRotate = old })
+	logRotate.Keep = 5 // what `logs` sees, whatever `up --log-keep` was
+
+	path := filepath.Join(t.TempDir(), "mcpo.log")
+	for _, name := range []string{"mcpo.log", "mcpo.log.1", "mcpo.log.2", "mcpo.log.12", "mcpo.log.0", "mcpo.log.bak", "mcpo.log.1.tmp"} {
+		if err := os.WriteFile(filepath.Join(filepath.Dir(path), name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	for _, p := range rotatedFiles(path) {
+		got = append(got, filepath.Base(p))
+	}
+	if want := []string{"mcpo.log.12", "mcpo.log.2", "mcpo.log.1", "mcpo.log"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rotatedFiles = %q, want %q", got, want)
+	}
+}
@@ -7,11 +7,13 @@ import (
 	"bufio"
 	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"log"
 	"math"
 	"net"
 	"net/http"
@@ -20,9 +22,11 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"path"
 	"path/filepath"
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -42,50 +46,63 @@ const (
 // ---------- config models ----------
 
 type MCPServer struct {
-	Command string            `json:"command,omitempty"`
-	Args    []string          `json:"args,omitempty"`
-	Type    string            `json:"type,omitempty"` // sse | streamable-http
-	URL     string            `json:"url,omitempty"`  // for sse/streamable-http
-	Headers map[string]string `json:"headers,omitempty"`
+	Command  string            `json:"command,omitempty"`
+	Args     []string          `json:"args,omitempty"`
+	Type     string            `json:"type,omitempty"` // sse | streamable-http
+	URL      string            `json:"url,omitempty"`  // for sse/streamable-http
+	Headers  map[string]string `json:"headers,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`      // passed to the server process; values may use ${VAR}
+	EnvFile  string            `json:"envFile,omitempty"`  // mcp-launch only: KEY=VALUE file merged under env
+	Tools    *ToolFilter       `json:"tools,omitempty"`    // mcp-launch only: operations exposed from this server
+	Disabled bool              `json:"disabled,omitempty"` // mcp-launch only: kept in the file but not started
+
+	ConnectTimeout string `json:"connectTimeout,omitempty"` // mcp-launch only: per connect attempt, e.g. "10s"
+	ConnectRetries *int   `json:"connectRetries,omitempty"` // mcp-launch only: attempts after the first
 }
 
 type MCPConfig struct {
 	MCPServers map[string]MCPServer `json:"mcpServers"`
+	Tools      *ToolFilter          `json:"tools,omitempty"` // mcp-launch only: stack-wide filter
 }
 
 // ---------- runtime / state ----------
 
 type Instance struct {
-	Name           string   `json:"name"` // derived from config filename
-	ConfigPath     string   `json:"config_path"`
-	FrontPort      int      `json:"front_port"`
-	McpoPort       int      `json:"mcpo_port"`
-	APIKey         string   `json:"api_key"`
-	PublicURL      string   `json:"public_url"`
-	TunnelMode     string   `json:"tunnel_mode"` // quick|named|none
-	TunnelName     string   `json:"tunnel_name,omitempty"`
-	CloudflaredPID int      `json:"cloudflared_pid"`
-	McpoPID        int      `json:"mcpo_pid"`
-	ToolNames      []string `json:"tool_names"`
-	OperationCount int      `json:"operation_count"` // total OpenAPI operations after merge
-}
-
-type State struct {
-	// Legacy single-instance fields (kept for backward compatibility)
-	APIKey         string   `json:"api_key,omitempty"`
-	ConfigPath     string   `json:"config_path,omitempty"`
-	FrontPort      int      `json:"front_port,omitempty"`
-	McpoPort       int      `json:"mcpo_port,omitempty"`
-	PublicURL      string   `json:"public_url,omitempty"`
-	TunnelMode     string   `json:"tunnel_mode,omitempty"`
-	TunnelName     string   `json:"tunnel_name,omitempty"`
-	CloudflaredPID int      `json:"cloudflared_pid,omitempty"`
-	McpoPID        int      `json:"mcpo_pid,omitempty"`
-	ToolNames      []string `json:"tool_names,omitempty"`
-
-	// Multi-instance (preferred)
-	Instances []Instance `json:"instances"`
-	StartedAt string     `json:"started_at"`
+	Name            string      `json:"name"` // derived from config filename
+	ConfigPath      string      `json:"config_path"`
+	FrontPort       int         `json:"front_port"`
+	McpoPort        int         `json:"mcpo_port"`
+	APIKey          string      `json:"api_key"`
+	PublicURL       string      `json:"public_url"`
+	TunnelMode      string      `json:"tunnel_mode"` // quick|named|none
+	TunnelName      string      `json:"tunnel_name,omitempty"`
+	CloudflaredPID  int         `json:"cloudflared_pid"`
+	McpoPID         int         `json:"mcpo_pid"`
+	McpoProc        *procStamp  `json:"mcpo_proc,omitempty"`        // identifies McpoPID (start time, cmdline)
+	CloudflaredProc *procStamp  `json:"cloudflared_proc,omitempty"` // identifies CloudflaredPID
+	ToolNames       []string    `json:"tool_names"`
+	OperationCount  int         `json:"operation_count"`          // total OpenAPI operations after merge
+	RestartPolicy   string      `json:"restart_policy,omitempty"` // never|on-failure|always
+	Restarts        int         `json:"restarts"`                 // mcpo restarts during this run
+	LastRestartAt   string      `json:"last_restart_at,omitempty"`
+	LastExit        string      `json:"last_exit,omitempty"`       // how mcpo last exited
+	AcceptedKeys    []string    `json:"accepted_keys,omitempty"`   // X-API-Key values the front proxy accepts; default [api_key]
+	ProtectSpec     bool        `json:"protect_openapi,omitempty"` // /openapi.json also requires a key
+	SplitOf         string      `json:"split_of,omitempty"`        // set on --auto-split parts that share another stack's mcpo
+	Routes          []string    `json:"routes,omitempty"`          // when set, only these "METHOD /path" routes are served
+	Tools           *ToolFilter `json:"tools,omitempty"`           // stack filter from the manifest, on top of the config's
+	Reload          bool        `json:"reload,omitempty"`          // config edits are picked up while running
+	Backend         string      `json:"backend,omitempty"`         // mcpo (default) | native (mcp-launch bridge)
+	MCPEndpoint     bool        `json:"mcp_endpoint,omitempty"`    // the front proxy serves /mcp
+}
+
+// stackRun is one started stack inside `up`.
+type stackRun struct {
+	inst   *Instance
+	proxy  *frontProxy
+	mcpo   *exec.Cmd
+	policy restartPolicy
+	parts  []*stackRun // --auto-split parts that share this stack's mcpo
 }
 
 // ---------- CLI ----------
@@ -119,42 +136,84 @@ func main() {
 		cmdOpenAPI()
 	case "share":
 		cmdShare()
+	case "keys":
+		cmdKeys()
+	case "logs":
+		cmdLogs()
+	case "tools":
+		cmdTools()
+	case "call":
+		cmdCall()
+	case "audit":
+		cmdAudit()
+	case "validate":
+		cmdValidate()
+	case "config":
+		cmdConfig()
+	case "import":
+		cmdImport()
+	case "server":
+		cmdServer()
+	case "gc":
+		cmdGC()
+	case "bridge":
+		cmdBridge()
 	default:
 		usage()
 	}
 }
 
 func usage() {
-	fmt.Println(`mcp-launch ` + Version + `
+	fmt.Print(`mcp-launch ` + Version + `
 One URL per config for many MCP servers (via mcpo). Serves /openapi.json per stack and proxies everything else to its mcpo.
 
 USAGE
   mcp-launch <command> [options]
 
 COMMANDS
-  init         Scaffold mcp.config.json and default state
+  init         Pick servers from a built-in catalog; writes mcp.config.json and mcp-launch.yaml
   up           Start one or more stacks (mcpo + proxy + optional Cloudflare) and generate merged OpenAPI per stack
-  status       Show ports, URLs, tools, API keys
-  openapi      Regenerate merged OpenAPI for running stacks (uses current/--public-url)
+  status       Show ports, URLs, tools, API keys, remote server connections
+  openapi      Regenerate merged OpenAPI for running stacks; 'openapi diff' compares saved spec revisions
   share        Print the URL(s) you paste into ChatGPT (Custom GPT → Actions → Import from URL)
-  down         Stop all stacks (mcpo trees and cloudflared)
-  doctor       Check dependencies (mcpo, cloudflared, plus uvx/npx if referenced in config)
+  tools        List each running stack's operations with their summaries and inputs
+  call         Call one operation of a running stack through its front proxy, with timing
+  down         Stop all stacks (mcpo trees and cloudflared); recorded PIDs are verified first
+  gc           Drop stacks whose processes are gone from state.json and clean stale runtime files
+  logs         Show or follow per-stack mcpo/cloudflared/proxy logs (.mcp-launch/logs)
+  audit        Query the access log of proxied tool calls by stack, tool or time
+  keys         List or rotate the X-API-Keys each front proxy accepts
+  server       List, add, remove, enable or disable servers in a config (edits are atomic)
+  import       Add servers from Claude Desktop, Cursor or VS Code MCP configs to your config
+  config       Convert configs between JSON, YAML and TOML (config convert)
+  validate     Check config files (JSON syntax, fields, server names, executables); non-zero exit on errors
+  doctor       Check dependencies (mcpo, cloudflared, plus uvx/npx if referenced in config) and remote servers
+  bridge       Built-in MCP→OpenAPI server used by 'up --backend native' in place of mcpo
   help         Show help (try: mcp-launch help up)
   version      Print version
 
 NOTES
   • Ctrl-C on 'up' will stop all started stacks: mcpo (+ spawned MCP servers), front proxies, and cloudflared.
+  • 'up --detach' runs stacks in a background daemon; 'down' stops it via .mcp-launch/control.sock.
   • Default output is minimal; use -v or -vv to stream detailed logs. Use --log-file to tee logs to a file.
+  • Subprocess output is always kept per stack in .mcp-launch/logs/<stack>/<proc>.log (see: mcp-launch logs).
 `)
 }
 
 func helpTopic(name string) {
 	switch name {
 	case "up":
-		fmt.Println(`USAGE
+		fmt.Print(`USAGE
   mcp-launch up [--config PATH ...] [--port N] [--mcpo-port N] [--api-key KEY] [--shared-key]
                  [--tunnel quick|named|none] [--public-url URL ...] [--tunnel-name NAME]
-                 [-v | -vv] [--stream] [--log-file PATH]
+                 [--restart never|on-failure|always ...] [--restart-max N] [--restart-window DUR]
+                 [--restart-backoff DUR] [--accept-key [STACK=]KEY ...] [--protect-openapi]
+                 [--auto-split[=N]] [--detach] [-v | -vv] [--stream] [--log-file PATH]
+                 [--log-max-size MB] [--log-max-age DUR] [--log-keep N]
+                 [--access-log=false] [--audit-bodies] [--audit-redact FIELDS]
+                 [--metrics=false] [--metrics-addr [HOST:]PORT] [--mcp=false]
+                 [--reload=false] [--spec-poll DURATION] [--manifest PATH]
+                 [--backend mcpo|native]
 
 DESCRIPTION
   Starts one or more independent "stacks" (one per --config):
@@ -163,17 +222,65 @@ DESCRIPTION
 
 OPTIONS
   --config PATH          Repeatable. Claude-style config(s). Default: mcp.config.json if omitted.
+  --manifest PATH        Stack manifest (default: mcp-launch.yaml if present). It lists stacks with
+                         their config or inline servers, ports, tunnel, hostname, keys, tool filters
+                         and restart policy; flags given here override it, and --config replaces
+                         its stack list.
   --port N               Base front proxy port (default: 8000). Subsequent stacks use N+1, N+2, ...
   --mcpo-port N          Base internal mcpo port (default: 8800). Subsequent stacks use N+1, N+2, ...
+  --backend NAME         mcpo (default) or native: run the built-in 'mcp-launch bridge' instead of mcpo.
+                         It spawns stdio servers and dials sse/streamable-http ones itself, so
+                         Python/mcpo is not needed.
   --api-key KEY          API key. With --shared-key this is used for all stacks; otherwise keys are generated per stack.
   --shared-key           Use a single API key for all stacks (safer default is per-stack keys).
   --tunnel MODE          quick | named | none (default: quick)
   --public-url URL       Repeatable. For named/none, provide one per --config (or one applied to all).
-  --tunnel-name NAME     Named tunnel to run (cloudflared tunnel run NAME) for each stack
+  --tunnel-name NAME     Named tunnel to run. Stacks on the same tunnel share one cloudflared whose
+                         generated ingress (.mcp-launch/cloudflared/NAME.yml) maps each --public-url
+                         hostname to that stack's front port.
+  --restart POLICY       Repeatable. never | on-failure | always; one per --config (or one applied to all).
+                         Default: never (an mcpo exit stops every stack). Otherwise only that stack's
+                         mcpo is restarted; its front proxy and tunnel (and public URL) keep running.
+  --restart-max N        Give up on a stack after N restarts within --restart-window (default: 5; 0 = unlimited)
+  --restart-window DUR   Sliding window for --restart-max (default: 10m)
+  --restart-backoff DUR  First restart delay, doubled per consecutive crash up to 1m (default: 1s)
+  --accept-key [STACK=]KEY
+                         Repeatable. Extra X-API-Key accepted by the front proxy (all stacks, or only STACK).
+                         STACK= is a scope only when STACK is a stack of this run; otherwise the whole
+                         value (= included) is the key.
+  --protect-openapi      Require X-API-Key for /openapi.json as well (default: public, as GPT import expects)
+  --auto-split[=N]       After fetching per-server specs, split any stack with more than N operations
+                         (default 30) into several virtual stacks sharing its mcpo, each with its own
+                         front port, /openapi.json, API key and tunnel. Servers stay whole when they fit;
+                         larger servers are cut by operation. The plan (which GPT gets which tools) is printed.
+                         With --tunnel named, part N is served at the stack's hostname with "-N" added to
+                         its first label (tools.example.com → tools-2.example.com); route that name's DNS
+                         to the tunnel. Without --public-url a named-tunnel stack is not split.
+  --detach               Start a background daemon and return once stacks are up. The daemon writes
+                         .mcp-launch/daemon.pid and serves .mcp-launch/control.sock; status, share,
+                         openapi and down talk to it (output goes to .mcp-launch/daemon.log).
   -v                     Verbose INFO logs and stream subprocess output
   -vv                    DEBUG logs (also streams subprocess output)
   --stream               Stream subprocess logs without changing verbosity
   --log-file PATH        Append logs to file (created if missing)
+  --log-max-size MB      Rotate .mcp-launch/logs/<stack>/<proc>.log after MB megabytes (default: 10)
+  --log-max-age DUR      Rotate those logs once their first line is older than DUR (default: 168h)
+  --log-keep N           Rotated files to keep per process (default: 5)
+  --access-log           Record every request to .mcp-launch/logs/<stack>/access.jsonl (default: true)
+  --audit-bodies         Also capture request/response bodies (JSON, up to 64 KiB each)
+  --audit-redact FIELDS  Comma-separated JSON field names replaced with [REDACTED] in captured bodies
+                         (default: password,passwd,secret,token,api_key,apikey,authorization,...)
+  --reload               Watch each config file and re-merge /openapi.json when it changes (default: true).
+                         mcpo hot-reloads the config itself; the front port and tunnel URL stay the same
+                         and the added/removed operations are printed.
+  --spec-poll DURATION   Also re-fetch every server's spec this often (default: 30s; 0 = only on edits)
+  --metrics              Serve Prometheus /metrics on each front proxy (default: true). Loopback callers
+                         that did not come through Cloudflare need no key; everyone else needs X-API-Key.
+  --metrics-addr ADDR    Also serve /metrics for all stacks on a separate admin listener
+                         (a bare port such as 9464 binds to 127.0.0.1 only)
+  --mcp                  Serve an aggregated MCP endpoint (streamable HTTP) at /mcp on each front proxy
+                         (default: true). It offers the stack's operations as tools named
+                         <server>__<tool> and takes the stack's key as X-API-Key or Authorization: Bearer.
 
 WHY MULTI-CONFIG?
   OpenAI Custom GPTs currently support ~30 tools per Action. Split your servers into multiple configs,
@@ -194,13 +301,281 @@ EXAMPLES
     --api-key SECRET --shared-key
 `)
 	case "openapi":
-		fmt.Println(`USAGE
+		fmt.Print(`USAGE
   mcp-launch openapi [--public-url URL ...]
+  mcp-launch openapi history [STACK]
+  mcp-launch openapi diff [STACK] [--from REV] [--to REV] [--json]
 
 DESCRIPTION
   Rebuild the merged OpenAPI document for each running stack from its per-tool specs,
   and set servers[0].url to the provided --public-url(s) (one per stack or one for all)
   or the instance's current public URL if not provided.
+
+  Every spec a front proxy starts serving (at up, on reload, after 'openapi') is saved as
+  .mcp-launch/specs/<stack>/<UTC time>.json unless only servers[] changed; the newest 100 are
+  kept. 'history' lists them. 'diff' compares two revisions of each stack (or just STACK) and
+  reports added, removed, renamed and moved operations, and changed parameters, body fields,
+  required flags, enums and response schemas:
+    tools: 20261016T091500.120Z → 20261016T093012.045Z
+      ~ time__convert_time: body.time: now required   [BREAKING]
+      + time__list_zones: POST /time/list_zones
+      2 change(s), 1 breaking.
+
+  Breaking changes are those that can fail a client built against the older spec: a removed,
+  renamed or moved operation, an input that is removed, newly required, retyped or loses enum
+  values, and an output field that is removed, retyped or no longer required.
+  Exits 1 if any change is breaking (for CI), 2 if a revision cannot be found.
+
+REVISIONS
+  latest, previous     The newest and second-newest saved revisions (defaults: --from previous --to latest)
+  -N                   The N-th newest (-1 = latest)
+  live                 What the running stack serves right now
+  ID                   A revision ID from 'history', or a unique prefix of one (e.g. 20261016T09)
+  FILE                 Any spec file, e.g. one checked into your repository
+`)
+	case "tools":
+		fmt.Print(`USAGE
+  mcp-launch tools [STACK]
+
+DESCRIPTION
+  Lists the operations of each running stack (or just STACK) from its merged OpenAPI spec: the
+  operationId with its inputs, then the method, path and summary. Inputs are path/query
+  parameters followed by body fields, required ones first; optional ones end in '?':
+    time__convert_time(source_timezone: string, target_timezone: string, time: string)
+        POST /time/convert_time — Convert time between timezones
+`)
+	case "call":
+		fmt.Print(`USAGE
+  mcp-launch call [STACK] OPERATION [--arg NAME=VALUE ...] [--json JSON | @FILE | @-]
+                  [--no-validate] [--public] [--raw] [--timeout DUR]
+
+DESCRIPTION
+  Calls one operation (an operationId from 'mcp-launch tools') of a running stack through its
+  front proxy with the stack's X-API-Key, and prints the status, the time taken and the
+  pretty-printed response. STACK may be omitted when only one stack is running.
+
+  Input comes from --json (an object) with --arg values on top. Each input goes where the spec
+  puts it (path, query, header or JSON body). An --arg VALUE is taken as text for string fields
+  and as JSON otherwise (a=2, tags='["x","y"]', opts='{"deep":true}'). Before sending, the input
+  is checked against the operation's schemas (types, required fields, enums); problems are listed
+  and nothing is sent.
+
+  Exits 1 if the call fails or returns an HTTP error, 2 on bad input.
+
+OPTIONS
+  --arg NAME=VALUE  One input (repeatable)
+  --json SRC        Input object as JSON text, @FILE, or @- for stdin
+  --no-validate     Send the input even if it does not match the schema
+  --public          Go through the stack's public URL (tunnel) instead of 127.0.0.1
+  --raw             Print only the response body, as received (for piping into jq)
+  --timeout DUR     Give up after DUR (default: 2m)
+`)
+	case "logs":
+		fmt.Print(`USAGE
+  mcp-launch logs [STACK] [--proc mcpo|cloudflared|proxy] [-f] [--since DUR] [--grep REGEX] [-n N]
+
+DESCRIPTION
+  Every stack's subprocess output is written to .mcp-launch/logs/<stack>/<proc>.log (rotated by
+  size/age, see 'help up'), including detached and past runs. This prints those lines merged
+  in time order, optionally narrowed to one stack and/or process.
+
+OPTIONS
+  --proc NAME    mcpo | cloudflared | proxy
+  -f             Follow: keep printing new lines (Ctrl-C to stop)
+  --since DUR    Only lines newer than DUR (e.g. 10m, 2h)
+  --grep REGEX   Only lines matching REGEX
+  -n N           Without --since, show only the last N lines (default: 100; 0 = all)
+`)
+	case "audit":
+		fmt.Print(`USAGE
+  mcp-launch audit [--stack NAME] [--tool GLOB] [--since DUR | --from RFC3339] [--to RFC3339] [--json]
+
+DESCRIPTION
+  Each front proxy appends one JSON line per request to .mcp-launch/logs/<stack>/access.jsonl:
+  timestamp, stack, MCP server, operationId (resolved from the merged spec), status, latency,
+  request/response size, client IP (CF-Connecting-IP / X-Forwarded-For), CF-IPCountry, CF-Ray and
+  a short hash of the X-API-Key used (never the key). With 'up --audit-bodies', bodies are captured
+  with the --audit-redact fields replaced by [REDACTED]; a body over 64 KiB is only recorded as
+  {"truncated":true,"bytes":N}, since it cannot be parsed to redact it.
+
+OPTIONS
+  --stack NAME   Only this stack
+  --tool GLOB    Match operationId (e.g. 'serena__find_*') or server name (e.g. 'time')
+  --since DUR    Only records newer than DUR (e.g. 24h)
+  --from/--to    Time range (RFC3339)
+  --json         Print matching records as JSON lines
+`)
+	case "validate":
+		fmt.Print(`USAGE
+  mcp-launch validate [--config PATH ...] [--strict] [PATH ...]
+
+DESCRIPTION
+  Checks configs without starting anything and reports each problem as FILE:LINE:COL:
+    • JSON syntax and type errors
+    • unknown fields (warnings; mcp-launch and mcpo ignore them)
+    • "command" missing for stdio servers, or "url" missing/invalid for sse / streamable-http
+    • server names that would make bad /<name>/ paths or <name>__ operationIds
+    • the same server name in several configs of one run (warning)
+    • ${VAR} / envFile references that do not resolve, and executables not found in PATH
+  Exits 1 when there are errors (or warnings with --strict), so it can gate CI.
+  'up' runs the same checks first and refuses to start on errors.
+
+OPTIONS
+  --config PATH  Repeatable. Default: mcp.config.json
+  --strict       Treat warnings as errors
+`)
+	case "init":
+		fmt.Print(`USAGE
+  mcp-launch init [--add NAME ...] [--set [NAME.]KEY=VAL ...] [--config PATH] [--manifest PATH]
+                  [--no-manifest] [--tunnel quick|named|none] [--tunnel-name NAME] [--hostname HOST]
+                  [--yes] [--force] [--list]
+
+DESCRIPTION
+  Writes a config with servers from a built-in catalog and an mcp-launch.yaml manifest for 'up'.
+  On a terminal it lists the catalog and asks for the servers, their parameters (root path,
+  timezone, ...) and the tunnel. With --add (or --yes, or no terminal) it asks nothing: missing
+  parameters take their defaults (current directory, local timezone). No network access needed.
+
+  Catalog: filesystem, git, time, fetch, sqlite, memory, github, serena (see --list).
+
+OPTIONS
+  --add NAME         Catalog server to add (repeatable or comma-separated)
+  --set KEY=VAL      Parameter value; NAME.KEY=VAL targets one server (e.g. --set filesystem.root=/srv)
+  --config PATH      Config to write; .yaml/.toml pick the format. Default: mcp.config.json
+  --manifest PATH    Manifest to write. Default: mcp-launch.yaml (--no-manifest to skip)
+  --tunnel MODE      quick (default) | named | none; named takes --tunnel-name and --hostname
+  --yes              Accept all defaults without asking
+  --force            Overwrite existing files
+  --list             Print the catalog and its parameters
+
+EXAMPLES
+  mcp-launch init
+  mcp-launch init --add filesystem,git --set root=/srv/code --set git.repo=/srv/code --tunnel none
+`)
+	case "down":
+		fmt.Print(`USAGE
+  mcp-launch down [--force]
+
+DESCRIPTION
+  Stops every stack: a detached daemon over its control socket, a foreground 'up' with SIGTERM,
+  and anything a crashed run left in .mcp-launch/state.json. A recorded PID is only signalled
+  if its process start time still matches what 'up' recorded, so after a reboot 'down' never
+  kills an unrelated process that happens to reuse the PID.
+
+OPTIONS
+  --force   Also stop running PIDs whose identity was never recorded (state written by an
+            older mcp-launch), and forget PIDs that now belong to another process (they are
+            never signalled); check them in 'mcp-launch status' first
+`)
+	case "bridge":
+		fmt.Print(`USAGE
+  mcp-launch bridge [--config PATH] [--port N] [--api-key KEY] [--hot-reload]
+
+DESCRIPTION
+  The native backend behind 'up --backend native', usable on its own for debugging. It spawns the
+  config's stdio MCP servers, connects to its sse/streamable-http ones (per their connectTimeout and
+  connectRetries), runs the initialize and tools/list handshake, and serves mcpo's routes
+  on 127.0.0.1:PORT:
+    GET  /docs                     index of servers
+    GET  /<server>/openapi.json    one POST operation per tool; request body = the tool's input schema
+    POST /<server>/<tool>          JSON body → tools/call; text results that are JSON are returned as JSON
+  Requests other than /docs need X-API-Key (or Authorization: Bearer) when a key is set; 'up' passes it
+  in $` + bridgeKeyEnv + ` so it does not show in ps. A server that exits, or a remote one whose
+  connection drops, is restarted or redialed on its next request.
+
+OPTIONS
+  --config PATH     Config to serve (default: mcp.config.json / .yaml / .toml)
+  --port N          Listen port (default: 8800)
+  --api-key KEY     Required X-API-Key (default: $` + bridgeKeyEnv + `)
+  --hot-reload      Restart servers whose entry changed when the config file is edited
+`)
+	case "gc":
+		fmt.Print(`USAGE
+  mcp-launch gc [--dry-run]
+
+DESCRIPTION
+  Removes stacks from .mcp-launch/state.json whose mcpo and cloudflared are no longer running
+  (e.g. after a reboot or a crash), plus a stale control socket, daemon.pid and resolved runtime
+  configs. Stacks with live processes are kept; stop them with 'mcp-launch down'. Refuses to run
+  while an 'up' owns the state.
+`)
+	case "server":
+		fmt.Print(`USAGE
+  mcp-launch server list    [--config PATH]
+  mcp-launch server add     NAME [--config PATH] -- CMD [ARGS...]
+  mcp-launch server add     NAME [--config PATH] --url URL [--type sse|streamable-http] [--header K=V ...]
+  mcp-launch server add     NAME [--config PATH] [--set KEY=VAL ...]     (NAME from the init catalog)
+  mcp-launch server rm      NAME... [--config PATH]
+  mcp-launch server enable  NAME... [--config PATH]
+  mcp-launch server disable NAME... [--config PATH]
+
+DESCRIPTION
+  Edits the config (JSON, YAML or TOML) through a temp file and a rename, so mcpo and 'up' never
+  read a half-written file. Disabled servers stay in the file with "disabled": true; mcp-launch
+  leaves them out of the config it hands to mcpo and out of the merged OpenAPI.
+  A running 'up' (with --reload, the default) notices the edit, mcpo reloads, and the stack's
+  /openapi.json is re-merged within a few seconds.
+
+OPTIONS (add)
+  --command CMD / --arg A   Alternative to '-- CMD ARGS...'
+  --env K=V                 Server environment (repeatable; values may use ${VAR})
+  --env-file PATH           KEY=VALUE file merged under env
+  --disabled                Add the server disabled
+  --force                   Replace a server with the same name
+`)
+	case "import":
+		fmt.Print(`USAGE
+  mcp-launch import --from claude|cursor|vscode [--path FILE] [--config PATH] [--force] [--dry-run]
+
+DESCRIPTION
+  Reads the MCP servers another client already knows about and merges them into your config
+  (JSON, YAML or TOML). Servers whose name already exists are kept unless --force is given.
+  Default source files:
+    claude   claude_desktop_config.json (~/Library/Application Support/Claude, %APPDATA%\Claude
+             or ~/.config/Claude)
+    cursor   .cursor/mcp.json, then ~/.cursor/mcp.json
+    vscode   .vscode/mcp.json ("servers" key; settings.json with "mcp.servers" works via --path)
+  Normalization:
+    • type "http" → streamable-http; remote servers without a type → sse if the URL ends in /sse,
+      else streamable-http
+    • ${env:VAR} → ${VAR}; ${input:id} → ${ID} (set it in the environment or an envFile);
+      ${workspaceFolder} and ${userHome} are expanded
+    • names are made path-safe (e.g. "my.server" → "my-server"); disabled servers are skipped
+
+OPTIONS
+  --from SRC     claude | cursor | vscode
+  --path FILE    Source file instead of the default location
+  --config PATH  Config to merge into. Default: mcp.config.json (or .yaml/.toml if present)
+  --force        Replace servers that already exist in the target
+  --dry-run      Show what would be added without writing
+`)
+	case "config":
+		fmt.Print(`USAGE
+  mcp-launch config convert IN [--to json|yaml|toml] [-o OUT]
+
+DESCRIPTION
+  Configs may be JSON (Claude-style), YAML (.yaml/.yml) or TOML (.toml); all describe the same
+  {"mcpServers": {...}} model. mcp-launch hands mcpo a generated JSON copy under .mcp-launch/run/.
+  'config convert' rewrites IN in another format (to stdout unless -o is given). Comments are
+  not carried over.
+
+OPTIONS
+  --to FORMAT    json | yaml | toml (default: from -o's extension, else json)
+  -o PATH        Write to PATH instead of stdout
+`)
+	case "keys":
+		fmt.Print(`USAGE
+  mcp-launch keys [list]
+  mcp-launch keys add STACK [KEY]
+  mcp-launch keys rm STACK KEY
+
+DESCRIPTION
+  Each front proxy checks X-API-Key itself (constant-time) and answers 401 with a JSON body
+  for missing or unknown keys; /healthz and /readyz are always open and /openapi.json is open unless
+  'up --protect-openapi' was used. A stack may accept several keys at once, so you can
+  rotate without downtime: add a new key, update your GPTs, then remove the old one.
+  add/rm change a live detached stack (up --detach); KEY is generated when omitted.
+  Keys are independent of mcpo's own --api-key, which the proxy injects upstream.
 `)
 	default:
 		usage()
@@ -209,50 +584,14 @@ DESCRIPTION
 
 // ---------- commands ----------
 
-func cmdInit() {
-	// Write default config if not exists
-	if _, err := os.Stat(defaultConfig); errors.Is(err, os.ErrNotExist) {
-		d := `{
-  "mcpServers": {
-    "serena": {
-      "command": "uvx",
-      "args": ["--from", "git+https://github.com/oraios/serena", "serena", "start-mcp-server", "--context", "ide-assistant"]
-    },
-    "time": {
-      "command": "uvx",
-      "args": ["mcp-server-time", "--local-timezone=America/Phoenix"]
-    },
-    "filesystem": {
-      "command": "npx",
-      "args": ["-y", "@modelcontextprotocol/server-filesystem", "/workspaces/projects"]
-    }
-  }
-}`
-		_ = os.WriteFile(defaultConfig, []byte(d), 0644)
-		fmt.Println("Wrote", defaultConfig)
-	} else {
-		fmt.Println(defaultConfig, "already exists; not overwriting")
-	}
-	ensureStateDir()
-	st := State{
-		StartedAt: time.Now().Format(time.RFC3339),
-		Instances: nil,
-	}
-	saveState(&st)
-	fmt.Println("Initialized .mcp-launch/state.json with defaults")
-}
-
 func cmdDoctor() {
 	// Read *a* config (if present) to suggest uvx/npx checks; otherwise just mcpo/cloudflared.
 	st := loadState()
-	cfg := MCPConfig{}
+	cfgPath := defaultConfigPath()
 	if len(st.Instances) > 0 {
-		cfg = readConfig(st.Instances[0].ConfigPath)
-	} else if st.ConfigPath != "" {
-		cfg = readConfig(st.ConfigPath)
-	} else {
-		cfg = readConfig(defaultConfig)
+		cfgPath = st.Instances[0].ConfigPath
 	}
+	cfg := readConfig(cfgPath)
 	checks := []string{"mcpo", "cloudflared"}
 	need := map[string]bool{}
 	for _, s := range cfg.MCPServers {
@@ -270,7 +609,10 @@ func cmdDoctor() {
 			continue
 		}
 		_, err := exec.LookPath(bin)
-		if err != nil {
+		if err != nil && bin == "mcpo" {
+			fmt.Println("  ✗ mcpo not found in PATH (not needed with 'up --backend native')")
+			ok = false
+		} else if err != nil {
 			fmt.Printf("  ✗ %s not found in PATH\n", bin)
 			ok = false
 		} else {
@@ -282,6 +624,16 @@ func cmdDoctor() {
 	} else {
 		fmt.Println("Missing executables detected. Install the items marked ✗ and retry.")
 	}
+	if remote := checkRemoteServers(cfgPath); len(remote) > 0 {
+		fmt.Println("Remote servers:")
+		for _, rc := range remote {
+			if rc.Err != nil {
+				fmt.Printf("  ✗ %s (%s %s): %s\n", rc.Name, rc.Type, redactSecrets(rc.URL), redactSecrets(rc.Err.Error()))
+			} else {
+				fmt.Printf("  ✓ %s (%s %s): %s\n", rc.Name, rc.Type, redactSecrets(rc.URL), rc.Server)
+			}
+		}
+	}
 }
 
 type stringSlice []string
@@ -309,16 +661,89 @@ func cmdUp() {
 	debug := fs.Bool("vv", false, "Debug logs (DEBUG)")
 	stream := fs.Bool("stream", false, "Stream subprocess logs without changing verbosity")
 	logPath := fs.String("log-file", "", "Append logs to file (created if missing)")
+	var restartModes stringSlice
+	fs.Var(&restartModes, "restart", "Restart policy never|on-failure|always (repeatable; align with --config or single for all)")
+	restartMax := fs.Int("restart-max", 5, "Max mcpo restarts per stack within --restart-window (0 = unlimited)")
+	restartWindow := fs.Duration("restart-window", 10*time.Minute, "Window for --restart-max")
+	restartBackoff := fs.Duration("restart-backoff", time.Second, "Initial restart delay (doubles per consecutive crash)")
+	var extraKeys stringSlice
+	fs.Var(&extraKeys, "accept-key", "Additional accepted X-API-Key, as KEY or STACK=KEY (repeatable; for rotation)")
+	protectSpec := fs.Bool("protect-openapi", false, "Require X-API-Key for /openapi.json too")
+	logMaxSize := fs.Int64("log-max-size", logRotate.MaxSize>>20, "Rotate per-process logs after this many MB (0 = never)")
+	logMaxAge := fs.Duration("log-max-age", logRotate.MaxAge, "Rotate per-process logs older than this (0 = never)")
+	logKeep := fs.Int("log-keep", logRotate.Keep, "Rotated per-process log files to keep")
+	accessLog := fs.Bool("access-log", true, "Record every proxied request in .mcp-launch/logs/<stack>/access.jsonl")
+	auditBodies := fs.Bool("audit-bodies", false, "Also capture request/response bodies in the access log")
+	auditRedact := fs.String("audit-redact", strings.Join(defaultRedactFields, ","), "Comma-separated JSON fields to redact in captured bodies")
+	reload := fs.Bool("reload", true, "Re-merge /openapi.json when a config file changes (mcpo hot-reloads it)")
+	specPoll := fs.Duration("spec-poll", 30*time.Second, "Also re-fetch server specs this often to catch changes (0 = only on config edits)")
+	frontMetrics := fs.Bool("metrics", true, "Serve Prometheus /metrics on each front proxy (local callers, or with X-API-Key)")
+	mcpEndpoint := fs.Bool("mcp", true, "Serve an aggregated MCP endpoint (streamable HTTP) at /mcp on each front proxy")
+	metricsAddr := fs.String("metrics-addr", "", "Also serve /metrics for all stacks on this admin address (bare port = 127.0.0.1)")
+	var autoSplit autoSplitFlag
+	fs.Var(&autoSplit, "auto-split", "Split stacks over N operations (default 30) into several GPT-sized stacks")
+	manifestPath := fs.String("manifest", "", "Stack manifest (default: mcp-launch.yaml if present)")
+	detach := fs.Bool("detach", false, "Run stacks in a background daemon controlled via .mcp-launch/control.sock")
+	backend := fs.String("backend", backendMcpo, "Backend per stack: mcpo | native (built-in bridge)")
 	_ = fs.Parse(os.Args[2:])
 
-	ensureStateDir()
-	st := loadState()
+	// Stack manifest: fills in every flag not given on the command line.
+	var stackSpecs []*manifestStack
+	if mp := findManifest(*manifestPath); mp != "" {
+		m, err := loadManifest(mp)
+		if err != nil {
+			fmt.Println("Invalid manifest:", err)
+			os.Exit(2)
+		}
+		stackSpecs, err = applyManifest(fs, m, mp, &configs, &publicURLs, &restartModes, &extraKeys)
+		if err != nil {
+			fmt.Println("Invalid manifest:", err)
+			os.Exit(2)
+		}
+	}
 
 	// Which configs?
 	if len(configs) == 0 {
-		configs = append(configs, defaultConfig)
+		configs = append(configs, defaultConfigPath())
+	}
+	if issues := validateConfigs(configs); len(issues) > 0 {
+		errs, _ := countIssues(issues)
+		for _, i := range issues {
+			if !i.Warning || *verbose || *debug {
+				fmt.Println(i)
+			}
+		}
+		if errs > 0 {
+			fmt.Println("Refusing to start: fix the config error(s) above (see: mcp-launch validate).")
+			os.Exit(1)
+		}
+	}
+
+	if !isDaemonChild() {
+		if ds, err := daemonState(); err == nil {
+			fmt.Printf("A detached mcp-launch is already running (pid %d); run `mcp-launch down` first.\n", ds.PID)
+			os.Exit(1)
+		}
+		if *detach {
+			if err := spawnDaemon(stripDetach(os.Args[2:])); err != nil {
+				fmt.Println("Could not start daemon:", err)
+				os.Exit(1)
+			}
+			return
+		}
 	}
 
+	// One `up` owns the state dir at a time; a second one would clobber its PIDs.
+	release, err := claimState()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer release()
+	st := loadState()
+	st.OwnerPID = os.Getpid()
+	st.StartedAt = time.Now().Format(time.RFC3339)
+
 	verbosity := 0
 	if *debug {
 		verbosity = 2
@@ -327,6 +752,13 @@ func cmdUp() {
 	}
 
 	streamProcs := *stream || *verbose || *debug
+	logRotate = logRotation{MaxSize: *logMaxSize << 20, MaxAge: *logMaxAge, Keep: *logKeep}
+	accessCfg := accessLogConfig{CaptureBodies: *auditBodies}
+	for _, f := range strings.Split(*auditRedact, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			accessCfg.Redact = append(accessCfg.Redact, f)
+		}
+	}
 
 	// Open log file (optional)
 	lf, err := openLogFile(*logPath)
@@ -353,27 +785,98 @@ func cmdUp() {
 		st.APIKey = shared
 	}
 
+	// Restart policy per stack (aligned like --public-url)
+	policies := make([]restartPolicy, len(configs))
+	for i := range configs {
+		raw := ""
+		if len(restartModes) == 1 {
+			raw = restartModes[0]
+		} else if len(restartModes) > i {
+			raw = restartModes[i]
+		}
+		mode, err := parseRestartMode(raw)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		policies[i] = restartPolicy{
+			Mode:        mode,
+			MaxRestarts: *restartMax,
+			Window:      *restartWindow,
+			Backoff:     *restartBackoff,
+			MaxBackoff:  time.Minute,
+		}
+	}
+
 	// Build instance plans (reserve unique ports per stack)
 	instances := make([]Instance, 0, len(configs))
 	takenFront := map[int]bool{}
 	takenMcpo := map[int]bool{}
+	stackNames := make([]string, len(configs))
+	for i, cfgPath := range configs {
+		stackNames[i] = nameFromPath(cfgPath, i)
+		if i < len(stackSpecs) && stackSpecs[i] != nil {
+			stackNames[i] = stackSpecs[i].Name
+		}
+	}
+	for _, w := range keyScopeWarnings(extraKeys, stackNames) {
+		fmt.Println("Warning:", w)
+	}
 	for i, cfgPath := range configs {
-		name := nameFromPath(cfgPath, i)
-		front := reservePort(*port+i, takenFront)
-		mcpoP := reservePort(*mcpoPort+i, takenMcpo)
+		name := stackNames[i]
+		frontBase, mcpoBase := *port+i, *mcpoPort+i
+		tunnelMode, tunnelNm, backendName := *tunnel, *tunnelName, *backend
+		var spec *manifestStack
+		if i < len(stackSpecs) && stackSpecs[i] != nil {
+			spec = stackSpecs[i]
+			if spec.Port > 0 {
+				frontBase = spec.Port
+			}
+			if spec.McpoPort > 0 {
+				mcpoBase = spec.McpoPort
+			}
+			if spec.Tunnel != "" {
+				tunnelMode = spec.Tunnel
+			}
+			if spec.TunnelName != "" {
+				tunnelNm = spec.TunnelName
+			}
+			if spec.Backend != "" {
+				backendName = spec.Backend
+			}
+		}
+		backendName, err := parseBackend(backendName)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		front := reservePort(frontBase, takenFront)
+		mcpoP := reservePort(mcpoBase, takenMcpo)
 		inst := Instance{
-			Name:       name,
-			ConfigPath: cfgPath,
-			FrontPort:  front,
-			McpoPort:   mcpoP,
-			TunnelMode: *tunnel,
-			TunnelName: *tunnelName,
-		}
-		if *sharedKey {
+			Name:          name,
+			ConfigPath:    cfgPath,
+			FrontPort:     front,
+			McpoPort:      mcpoP,
+			TunnelMode:    tunnelMode,
+			TunnelName:    tunnelNm,
+			RestartPolicy: policies[i].Mode,
+			Reload:        *reload,
+			Backend:       backendName,
+			MCPEndpoint:   *mcpEndpoint,
+		}
+		switch {
+		case *sharedKey:
 			inst.APIKey = shared
-		} else {
+		case spec != nil && spec.APIKey != "":
+			inst.APIKey = spec.APIKey
+		default:
 			inst.APIKey = randomKey(40)
 		}
+		if spec != nil {
+			inst.Tools = spec.Tools
+		}
+		inst.AcceptedKeys = append([]string{inst.APIKey}, scopedKeys(extraKeys, name, stackNames)...)
+		inst.ProtectSpec = *protectSpec
 		// Pre-set public URL if provided
 		if len(publicURLs) == 1 {
 			inst.PublicURL = strings.TrimRight(publicURLs[0], "/")
@@ -383,43 +886,32 @@ func cmdUp() {
 		instances = append(instances, inst)
 	}
 
+	// Remote servers are not started by us; say now if one cannot be reached.
+	for _, inst := range instances {
+		reportRemoteServers(inst.Name, inst.ConfigPath, verbosity > 0)
+	}
+
 	// Start stacks one by one
-	type running struct {
-		inst   *Instance
-		proxy  *frontProxy
-		mcpo   *exec.Cmd
-		tunnel *exec.Cmd
+	runs := make([]*stackRun, 0, len(instances))
+	startStackMcpo := func(inst *Instance) (*exec.Cmd, error) {
+		return startMcpo(inst, streamProcs, lf)
 	}
-	runs := make([]*running, 0, len(instances))
 
+	// Phase 1: start every mcpo and wait for it to come up.
+	mcpoCmds := make([]*exec.Cmd, len(instances))
 	for i := range instances {
 		inst := &instances[i]
 
 		// Start mcpo
-		mcpoCmd := exec.Command(findBinary("mcpo"),
-			"--port", fmt.Sprint(inst.McpoPort),
-			"--api-key", inst.APIKey,
-			"--config", inst.ConfigPath,
-			"--hot-reload",
-		)
-		if runtime.GOOS != "windows" {
-			mcpoCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-		}
-		stdout, _ := mcpoCmd.StdoutPipe()
-		stderr, _ := mcpoCmd.StderrPipe()
-		if err := mcpoCmd.Start(); err != nil {
+		mcpoCmd, err := startStackMcpo(inst)
+		if err != nil {
 			fmt.Printf("Failed to start mcpo for %s: %v\n", inst.Name, err)
 			continue
 		}
-		inst.McpoPID = mcpoCmd.Process.Pid
+		mcpoCmds[i] = mcpoCmd
+		inst.setMcpoPID(mcpoCmd.Process.Pid)
 		saveStateMulti(&st, instances)
 
-		tag := "mcpo#" + inst.Name
-		go scanAndMaybeStream(tag, stdout, streamProcs, lf, nil)
-		go scanAndMaybeStream(tag, stderr, streamProcs, lf, nil)
-
-		waitURL(fmt.Sprintf("http://127.0.0.1:%d/docs", inst.McpoPort), 60*time.Second)
-
 		// Record MCP server names from config
 		cfg := readConfig(inst.ConfigPath)
 		var toolNames []string
@@ -430,11 +922,122 @@ func cmdUp() {
 		inst.ToolNames = toolNames
 		saveStateMulti(&st, instances)
 
+		waitErr := waitURL(fmt.Sprintf("http://127.0.0.1:%d/docs", inst.McpoPort), 60*time.Second)
+		h := probeStack(inst.McpoPort, inst.APIKey, inst.ToolNames)
+		if waitErr != nil || !h.ready() {
+			if waitErr != nil {
+				h.Error = waitErr.Error()
+			}
+			reportStackHealth(inst.Name, h)
+		}
+		if !h.McpoUp {
+			fmt.Printf("Stack %s failed to start; skipping it.\n", inst.Name)
+			_ = killProcessGroup(inst.McpoPID)
+			mcpoCmds[i] = nil
+			inst.setMcpoPID(0)
+			saveStateMulti(&st, instances)
+		}
+	}
+
+	// Auto-split: carve over-limit stacks into GPT-sized virtual stacks that share the same mcpo
+	// but each get their own front port, /openapi.json, API key and tunnel.
+	if autoSplit > 0 {
+		for i, n := 0, len(instances); i < n; i++ {
+			if mcpoCmds[i] == nil {
+				continue
+			}
+			inst := &instances[i]
+			spec, _, err := mergeOpenAPI(*inst, fmt.Sprintf("http://127.0.0.1:%d", inst.FrontPort))
+			if err != nil {
+				fmt.Printf("[split#%s] could not inspect spec: %v\n", inst.Name, err)
+				continue
+			}
+			total := countOperations(spec)
+			if total <= int(autoSplit) {
+				continue
+			}
+			parts, err := planSplit(spec, inst.ToolNames, int(autoSplit))
+			if err != nil || len(parts) < 2 {
+				continue
+			}
+			if inst.TunnelMode == "named" && inst.PublicURL == "" {
+				fmt.Printf("[split#%s] not splitting: --tunnel named needs --public-url to derive a hostname for each part\n", inst.Name)
+				continue
+			}
+			fmt.Printf("[split#%s] %d operations > %d → %d GPTs:\n", inst.Name, total, int(autoSplit), len(parts))
+			base := *inst
+			for k, part := range parts {
+				var p *Instance
+				if k == 0 {
+					p = inst
+				} else {
+					next := base
+					next.Name = fmt.Sprintf("%s-%d", base.Name, k+1)
+					next.SplitOf = base.Name
+					// Next to the parent's port, like the other stacks' base+i; takenFront already
+					// holds every stack's port (manifest-pinned ones included), so none is reused.
+					next.FrontPort = reservePort(base.FrontPort+k, takenFront)
+					next.setMcpoPID(0)
+					next.RestartPolicy = ""
+					next.PublicURL = ""
+					if base.TunnelMode == "named" {
+						// One tunnel routes by hostname, so each part needs a hostname of its own.
+						next.PublicURL = splitPublicURL(base.PublicURL, k+1)
+					}
+					// APIKey stays the shared mcpo's key; clients get their own accepted key.
+					clientKey := shared
+					if !*sharedKey {
+						clientKey = randomKey(40)
+					}
+					next.AcceptedKeys = append([]string{clientKey}, scopedKeys(extraKeys, next.Name, stackNames)...)
+					instances = append(instances, next)
+					mcpoCmds = append(mcpoCmds, nil)
+					policies = append(policies, restartPolicy{Mode: restartNever})
+					p = &instances[len(instances)-1]
+				}
+				p.Routes = part.Routes
+				p.ToolNames = part.serverNames()
+				fmt.Printf("  GPT %d (%s): %s\n", k+1, p.Name, part.describe())
+				if u, err := url.Parse(p.PublicURL); k > 0 && p.PublicURL != "" && err == nil {
+					tunnel := p.TunnelName
+					if tunnel == "" {
+						tunnel = "<tunnel>"
+					}
+					fmt.Printf("    %s needs a DNS route: cloudflared tunnel route dns %s %s\n", p.PublicURL, tunnel, u.Hostname())
+				}
+			}
+			saveStateMulti(&st, instances)
+		}
+	}
+
+	// Phase 2: front proxy, tunnel and merged OpenAPI per (possibly virtual) stack.
+	for i := range instances {
+		inst := &instances[i]
+		mcpoCmd := mcpoCmds[i]
+		if mcpoCmd == nil && inst.SplitOf == "" {
+			continue
+		}
+
 		// Front proxy
-		proxy := newFrontProxy(inst.FrontPort, inst.McpoPort)
+		proxy := newFrontProxy(inst.Name, inst.FrontPort, inst.McpoPort, inst.APIKey)
+		logProcLine("proxy#"+inst.Name, fmt.Sprintf("front proxy on :%d → mcpo :%d", inst.FrontPort, inst.McpoPort))
+		if *accessLog {
+			proxy.EnableAccessLog(accessCfg)
+		}
+		if *frontMetrics {
+			proxy.EnableMetrics()
+		}
+		if inst.MCPEndpoint {
+			proxy.EnableMCP()
+		}
+		proxy.SetKeys(acceptedKeys(*inst), inst.ProtectSpec)
+		proxy.SetServers(inst.ToolNames)
 		go func(name string, fp *frontProxy) {
-			if err := fp.Serve(); err != nil && !errors.Is(err, http.ErrServerClosed) && streamProcs {
-				fmt.Printf("[front#%s] error: %v\n", name, err)
+			if err := fp.Serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logProcLine("proxy#"+name, "error: "+err.Error())
+				if streamProcs {
+					fmt.Printf("[front#%s] error: %v\n", name, err)
+				}
 			}
 		}(inst.Name, proxy)
 		if verbosity > 0 {
@@ -444,7 +1047,9 @@ func cmdUp() {
 		// Cloudflare tunnel
 		switch inst.TunnelMode {
 		case "quick":
-			u := startQuickTunnel("cloudflared#"+inst.Name, inst.FrontPort, streamProcs, lf)
+			u, pid := startQuickTunnel("cloudflared#"+inst.Name, inst.FrontPort, streamProcs, lf)
+			inst.setCloudflaredPID(pid)
+			saveStateMulti(&st, instances)
 			if u == "" {
 				if verbosity > 0 {
 					fmt.Printf("[tunnel#%s] Quick Tunnel failed; continuing without a public URL.\n", inst.Name)
@@ -453,13 +1058,8 @@ func cmdUp() {
 				inst.PublicURL = u
 				saveStateMulti(&st, instances)
 			}
-		case "named":
-			if inst.PublicURL == "" && verbosity > 0 {
-				fmt.Printf("[tunnel#%s] Named tunnel selected but --public-url not provided. Please pass --public-url https://your.host\n", inst.Name)
-			}
-			startNamedTunnel("cloudflared#"+inst.Name, inst.TunnelName, streamProcs, lf)
-		case "none":
-			// no-op
+		case "named", "none":
+			// named: one cloudflared per tunnel, started below once every front port is known
 		default:
 			if verbosity > 0 {
 				fmt.Printf("[tunnel#%s] Unknown tunnel mode: %s\n", inst.Name, inst.TunnelMode)
@@ -471,10 +1071,14 @@ func cmdUp() {
 		if baseURL == "" {
 			baseURL = fmt.Sprintf("http://127.0.0.1:%d", inst.FrontPort)
 		}
-		spec, err := mergeOpenAPI(*inst, baseURL)
+		spec, filtered, err := mergeOpenAPI(*inst, baseURL)
 		if err != nil {
 			fmt.Printf("[openapi#%s] merge failed: %v\n", inst.Name, err)
 		} else {
+			if len(filtered) > 0 && verbosity > 0 {
+				fmt.Printf("[openapi#%s] tool filters hid %d operation(s)\n", inst.Name, len(filtered))
+			}
+			proxy.SetFiltered(filtered)
 			inst.OperationCount = countOperations(spec)
 			saveStateMulti(&st, instances)
 			// quick sanity check: any dangling component refs?
@@ -493,107 +1097,208 @@ func cmdUp() {
 			}
 			proxy.SetOpenAPI(spec)
 		}
-		runs = append(runs, &running{inst: inst, proxy: proxy, mcpo: mcpoCmd})
+		runs = append(runs, &stackRun{inst: inst, proxy: proxy, mcpo: mcpoCmd, policy: policies[i]})
+	}
+	for _, r := range runs {
+		for _, p := range runs {
+			if p.inst.SplitOf == r.inst.Name {
+				r.parts = append(r.parts, p)
+			}
+		}
+	}
+
+	// Named tunnels: one cloudflared per tunnel name with ingress for all of its stacks.
+	named := make([]*Instance, 0, len(runs))
+	for _, r := range runs {
+		named = append(named, r.inst)
 	}
+	startNamedTunnels(named, streamProcs, lf, verbosity > 0)
+	saveStateMulti(&st, instances)
 
 	// Minimal “important” output
 	fmt.Println()
 	if len(runs) == 0 {
 		fmt.Println("No stacks started.")
+		st.OwnerPID = 0
+		saveStateMulti(&st, instances)
 		return
 	}
-	fmt.Println("=== SHARE THESE WITH CHATGPT (Actions → Import from URL) ===")
-	for idx, r := range runs {
-		inst := r.inst
-		url := inst.PublicURL
-		if url == "" {
-			url = fmt.Sprintf("http://127.0.0.1:%d", inst.FrontPort)
-		}
-		fmt.Printf("%d) %s/openapi.json  (config: %s)\n", idx+1, url, filepath.Base(inst.ConfigPath))
-		fmt.Printf("   X-API-Key: %s\n", inst.APIKey)
-		warn := ""
-		switch {
-		case inst.OperationCount > 30:
-			warn = "  ⚠ OVER 30-limit"
-		case inst.OperationCount >= 28:
-			warn = "  ⚠ near 30"
-		}
-		fmt.Printf("   MCP servers: %d\n", len(inst.ToolNames))
-		fmt.Printf("   Endpoints (OpenAPI operations): %d%s\n", inst.OperationCount, warn)
+	started := make([]Instance, 0, len(runs))
+	for _, r := range runs {
+		started = append(started, *r.inst)
 	}
-	fmt.Println()
+	printShareBlock(started)
 
 	// Handle signals and mcpo exits
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 
-	doneCh := make(chan string, len(runs))
+	// From here on, supervisors mutate instances concurrently; stMu guards them and st.
+	var stMu sync.Mutex
+	update := func(fn func()) {
+		stMu.Lock()
+		defer stMu.Unlock()
+		fn()
+		saveStateMulti(&st, instances)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	metrics.SetStacks(func() []Instance {
+		stMu.Lock()
+		defer stMu.Unlock()
+		return slices.Clone(instances)
+	})
+	var adminSrv *http.Server
+	if *metricsAddr != "" {
+		if adminSrv, err = startMetricsServer(*metricsAddr); err != nil {
+			fmt.Println("Could not start metrics server:", err)
+		} else if verbosity > 0 {
+			fmt.Println("Metrics: http://" + *metricsAddr + "/metrics")
+		}
+	}
+
+	doneCh := make(chan *stackRun, len(runs))
+	alive := 0
 	for _, r := range runs {
-		go func(name string, cmd *exec.Cmd) {
-			_ = cmd.Wait()
-			doneCh <- name
-		}(r.inst.Name, r.mcpo)
+		if r.mcpo == nil {
+			continue // auto-split part; its mcpo is supervised with the parent stack
+		}
+		alive++
+		go func(r *stackRun) {
+			superviseMcpo(ctx, r, startStackMcpo, update)
+			doneCh <- r
+		}(r)
 	}
 
-	if verbosity > 0 {
-		fmt.Println("Press Ctrl+C to stop (or run `mcp-launch down` from another shell).")
+	if *reload {
+		opts := reloadOptions{Interval: 2 * time.Second, Settle: 3 * time.Second, SpecPoll: *specPoll, Verbose: verbosity > 0}
+		for _, r := range runs {
+			go watchStack(ctx, r, &stMu, update, opts)
+		}
 	}
 
 	cleanup := func() {
-		// stop proxies first
+		// stop supervisors first so nothing restarts underneath us
+		cancel()
+		// stop proxies
+		if adminSrv != nil {
+			_ = adminSrv.Close()
+		}
 		for _, r := range runs {
 			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 			_ = r.proxy.Close(ctx)
 			cancel()
 		}
 		// stop cloudflared and mcpo trees
+		stMu.Lock()
+		defer stMu.Unlock()
+		stopped := map[int]bool{} // a named-tunnel cloudflared may serve several stacks
 		for i := range instances {
 			inst := &instances[i]
-			if inst.CloudflaredPID > 0 {
-				_ = killPID(inst.CloudflaredPID)
-				inst.CloudflaredPID = 0
+			if pid := inst.CloudflaredPID; pid > 0 {
+				if !stopped[pid] {
+					_ = killPID(pid)
+					stopped[pid] = true
+				}
+				inst.setCloudflaredPID(0)
 			}
 			if inst.McpoPID > 0 {
 				_ = killProcessGroup(inst.McpoPID)
-				inst.McpoPID = 0
+				inst.setMcpoPID(0)
 			}
 		}
+		removeRuntimeConfigs()
+		st.OwnerPID = 0
 		saveStateMulti(&st, instances)
 	}
 
-	select {
-	case <-sigCh:
-		fmt.Println("\nReceived signal, shutting down…")
-		cleanup()
-	case name := <-doneCh:
-		fmt.Println("\nmcpo exited for stack:", name)
-		cleanup()
+	// In daemon mode, clients drive us over the control socket instead of a terminal.
+	// A daemon nobody can reach or stop is useless, so failing to open it is fatal.
+	var downCh <-chan struct{}
+	if isDaemonChild() {
+		ctl, err := startControlServer(&stMu, &st, instances, runs)
+		if err != nil {
+			fmt.Println("Could not open control socket:", err)
+			cleanup()
+			os.Exit(1)
+		}
+		defer ctl.Close()
+		downCh = ctl.Down()
+	} else if verbosity > 0 {
+		fmt.Println("Press Ctrl+C to stop (or run `mcp-launch down` from another shell).")
+	}
+
+	for {
+		select {
+		case <-sigCh:
+			fmt.Println("\nReceived signal, shutting down…")
+			cleanup()
+			return
+		case <-downCh:
+			fmt.Println("\nStop requested over control socket, shutting down…")
+			cleanup()
+			return
+		case r := <-doneCh:
+			alive--
+			if r.policy.Mode == restartNever {
+				fmt.Println("\nmcpo exited for stack:", r.inst.Name)
+				cleanup()
+				return
+			}
+			if alive == 0 {
+				fmt.Println("\nAll stacks have stopped; shutting down…")
+				cleanup()
+				return
+			}
+			fmt.Printf("Stack %s is down; %d stack(s) still running.\n", r.inst.Name, alive)
+		}
 	}
 }
 
-func cmdStatus() {
-	st := loadState()
-	if len(st.Instances) == 0 {
-		// Legacy single-instance print (if present)
-		fmt.Println("mcp-launch status (legacy):")
-		fmt.Printf("- Front: http://127.0.0.1:%d\n", st.FrontPort)
-		fmt.Printf("- mcpo:  http://127.0.0.1:%d\n", st.McpoPort)
-		if st.PublicURL != "" {
-			fmt.Println("- Public URL:", st.PublicURL)
-		} else {
-			fmt.Println("- Public URL: (none)")
+// printShareBlock prints the per-stack schema URL, key and operation count after `up`.
+func printShareBlock(insts []Instance) {
+	fmt.Println("=== SHARE THESE WITH CHATGPT (Actions → Import from URL) ===")
+	for idx, inst := range insts {
+		url := inst.PublicURL
+		if url == "" {
+			url = fmt.Sprintf("http://127.0.0.1:%d", inst.FrontPort)
+		}
+		label := filepath.Base(inst.ConfigPath)
+		if len(inst.Routes) > 0 {
+			label += ", split: " + strings.Join(inst.ToolNames, ", ")
+		}
+		fmt.Printf("%d) %s/openapi.json  (config: %s)\n", idx+1, url, label)
+		if keys := acceptedKeys(inst); len(keys) > 0 {
+			fmt.Printf("   X-API-Key: %s\n", keys[0])
 		}
-		fmt.Println("- Tunnel:", st.TunnelMode)
-		if len(st.ToolNames) > 0 {
-			fmt.Println("- Tools:", strings.Join(st.ToolNames, ", "))
+		if inst.MCPEndpoint {
+			fmt.Printf("   MCP clients: %s/mcp  (same key, as X-API-Key or Authorization: Bearer)\n", url)
 		}
-		if st.APIKey != "" {
-			fmt.Println("- API key (X-API-Key):", st.APIKey)
+		warn := ""
+		switch {
+		case inst.OperationCount > 30:
+			warn = "  ⚠ OVER 30-limit (try --auto-split)"
+		case inst.OperationCount >= 28:
+			warn = "  ⚠ near 30"
 		}
+		fmt.Printf("   MCP servers: %d\n", len(inst.ToolNames))
+		fmt.Printf("   Endpoints (OpenAPI operations): %d%s\n", inst.OperationCount, warn)
+	}
+	fmt.Println()
+}
+
+func cmdStatus() {
+	st, daemonPID := currentState()
+	if len(st.Instances) == 0 {
+		fmt.Println("No stacks in", statePath(), "(start some with `mcp-launch up`).")
 		return
 	}
 
-	fmt.Println("mcp-launch status (multi):")
+	if daemonPID > 0 {
+		fmt.Printf("mcp-launch status (live, daemon pid %d):\n", daemonPID)
+	} else {
+		fmt.Println("mcp-launch status (multi):")
+	}
 	for i, inst := range st.Instances {
 		base := inst.PublicURL
 		if base == "" {
@@ -601,11 +1306,44 @@ func cmdStatus() {
 		}
 		fmt.Printf("[%d] %s\n", i+1, inst.Name)
 		fmt.Printf("    Front: %s/openapi.json\n", base)
-		fmt.Printf("    mcpo:  http://127.0.0.1:%d\n", inst.McpoPort)
-		fmt.Printf("    Tunnel: %s\n", inst.TunnelMode)
+		if inst.MCPEndpoint {
+			fmt.Printf("    MCP:   %s/mcp\n", base)
+		}
+		if inst.Backend == backendNative {
+			fmt.Println("    Backend: native (mcp-launch bridge)")
+		}
+		switch {
+		case inst.McpoPID > 0:
+			fmt.Printf("    mcpo:  http://127.0.0.1:%d (%s)\n", inst.McpoPort, describeProc(inst.McpoPID, inst.McpoProc))
+		case inst.SplitOf != "" && inst.LastExit != "":
+			fmt.Printf("    mcpo:  http://127.0.0.1:%d (shared with %s, %s)\n", inst.McpoPort, inst.SplitOf, inst.LastExit)
+		case inst.SplitOf != "":
+			fmt.Printf("    mcpo:  http://127.0.0.1:%d (shared with %s)\n", inst.McpoPort, inst.SplitOf)
+		default:
+			fmt.Printf("    mcpo:  http://127.0.0.1:%d (not running)\n", inst.McpoPort)
+		}
+		if inst.CloudflaredPID > 0 {
+			shared := ""
+			if peers := tunnelPeers(inst, st.Instances); len(peers) > 0 {
+				shared = ", shared with " + strings.Join(peers, ", ")
+			}
+			fmt.Printf("    Tunnel: %s (cloudflared %s%s)\n", inst.TunnelMode, describeProc(inst.CloudflaredPID, inst.CloudflaredProc), shared)
+		} else {
+			fmt.Printf("    Tunnel: %s\n", inst.TunnelMode)
+		}
+		if inst.RestartPolicy != "" && inst.RestartPolicy != restartNever {
+			line := fmt.Sprintf("    Restart: %s (%d restart(s)", inst.RestartPolicy, inst.Restarts)
+			if inst.LastExit != "" {
+				line += ", last exit: " + inst.LastExit
+			}
+			fmt.Println(line + ")")
+		}
 		if len(inst.ToolNames) > 0 {
 			fmt.Printf("    MCP servers: %d\n", len(inst.ToolNames))
 		}
+		for _, line := range remoteStatus(inst) {
+			fmt.Printf("    Remote: %s\n", line)
+		}
 		warn := ""
 		switch {
 		case inst.OperationCount > 30:
@@ -616,18 +1354,23 @@ func cmdStatus() {
 		if inst.OperationCount > 0 {
 			fmt.Printf("    Endpoints (OpenAPI operations): %d%s\n", inst.OperationCount, warn)
 		}
-		fmt.Printf("    X-API-Key: %s\n", inst.APIKey)
+		keys := acceptedKeys(inst)
+		if len(keys) > 0 {
+			fmt.Printf("    X-API-Key: %s\n", keys[0])
+		}
+		if len(keys) > 1 {
+			fmt.Printf("    Also accepted: %s\n", strings.Join(keys[1:], ", "))
+		}
+		if inst.ProtectSpec {
+			fmt.Println("    /openapi.json requires X-API-Key")
+		}
 	}
 }
 
 func cmdShare() {
-	st := loadState()
+	st, _ := currentState()
 	if len(st.Instances) == 0 {
-		if st.PublicURL == "" {
-			fmt.Printf("Local: http://127.0.0.1:%d/openapi.json\n", st.FrontPort)
-			return
-		}
-		fmt.Println(st.PublicURL + "/openapi.json")
+		fmt.Println("No stacks in", statePath(), "(start some with `mcp-launch up`).")
 		return
 	}
 	for _, inst := range st.Instances {
@@ -640,46 +1383,155 @@ func cmdShare() {
 }
 
 func cmdDown() {
-	st := loadState()
-	// Prefer multi-instance
-	if len(st.Instances) > 0 {
+	// A detached daemon owns its processes (and front proxies); let it shut them down.
+	if ds, ok := stopDaemon(); ok {
+		for _, inst := range ds.State.Instances {
+			fmt.Println("Stopped stack", inst.Name)
+		}
+		fmt.Println("Stopped detached mcp-launch (pid", ds.PID, ")")
+		return
+	}
+	fs := flag.NewFlagSet("down", flag.ExitOnError)
+	fs.Usage = func() { helpTopic("down") }
+	force := fs.Bool("force", false, "Also signal PIDs whose identity was not recorded (state from an older mcp-launch) and forget reused PIDs")
+	_ = fs.Parse(os.Args[2:])
+
+	// A foreground `up` owns its stacks too; ask it to clean up rather than racing its state writes.
+	release, err := stopOwner()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer release()
+	// Whatever the owner left behind (or a crashed run recorded) is stopped from state.json,
+	// but only PIDs that are still the processes we started.
+	err = updateState(func(st *State) {
+		stopped := map[int]bool{} // a named-tunnel cloudflared may serve several stacks
 		for i := range st.Instances {
 			inst := &st.Instances[i]
-			if inst.CloudflaredPID > 0 {
-				_ = killPID(inst.CloudflaredPID)
-				fmt.Println("Stopped cloudflared (pid", inst.CloudflaredPID, ") for", inst.Name)
-				inst.CloudflaredPID = 0
+			pid := inst.CloudflaredPID
+			if stopped[pid] || stopRecorded("cloudflared", tunnelStacks(*inst, st.Instances), pid, inst.CloudflaredProc, *force, killPID) {
+				stopped[pid] = true
+				inst.setCloudflaredPID(0)
 			}
-			if inst.McpoPID > 0 {
-				_ = killProcessGroup(inst.McpoPID)
-				fmt.Println("Stopped mcpo (pid", inst.McpoPID, ") and its child MCP servers for", inst.Name)
-				inst.McpoPID = 0
+			if stopRecorded("mcpo", inst.Name, inst.McpoPID, inst.McpoProc, *force, killProcessGroup) {
+				inst.setMcpoPID(0)
 			}
 		}
-		saveState(&st)
+	})
+	if err != nil {
+		fmt.Println("Could not update state:", err)
+	}
+	removeRuntimeConfigs()
+}
+
+// stopRecorded stops a process recorded in state if it is still ours. It reports whether the
+// PID should be dropped from state: stopped or gone, or (with force) now someone else's. A PID
+// that seems reused is never signalled but stays recorded, so a misjudged live mcpo is not
+// orphaned.
+func stopRecorded(what, stack string, pid int, rec *procStamp, force bool, kill func(int) error) bool {
+	if pid <= 0 {
+		return false
+	}
+	state, cur := checkProc(pid, rec)
+	switch {
+	case state == procDead:
+		fmt.Printf("%s for %s (pid %d) was not running\n", what, stack, pid)
+		return true
+	case state == procMismatch && force:
+		fmt.Printf("Forgetting pid %d: it is no longer %s for %s but %q (started %s)\n", pid, what, stack, shortCmd(cur.Cmd), cur.Start)
+		return true
+	case state == procMismatch:
+		fmt.Printf("Not stopping pid %d: it no longer looks like %s for %s but %q (started %s). Check it, then re-run with --force to forget it.\n", pid, what, stack, shortCmd(cur.Cmd), cur.Start)
+		return false
+	case state == procUnverified && !force:
+		fmt.Printf("Not stopping pid %d (%s for %s): its identity was not recorded; it is running %q. Check it, then re-run with --force.\n", pid, what, stack, shortCmd(cur.Cmd))
+		return false
+	}
+	_ = kill(pid)
+	if what == "mcpo" {
+		fmt.Println("Stopped mcpo (pid", pid, ") and its child MCP servers for", stack)
+	} else {
+		fmt.Println("Stopped", what, "(pid", pid, ") for", stack)
+	}
+	return true
+}
+
+func cmdKeys() {
+	args := os.Args[2:]
+	if len(args) == 0 || args[0] == "list" {
+		st, _ := currentState()
+		for _, inst := range st.Instances {
+			fmt.Printf("%s: %s\n", inst.Name, strings.Join(acceptedKeys(inst), ", "))
+		}
 		return
 	}
-	// Fallback legacy
-	if st.CloudflaredPID > 0 {
-		_ = killPID(st.CloudflaredPID)
-		fmt.Println("Stopped cloudflared (pid", st.CloudflaredPID, ")")
-		st.CloudflaredPID = 0
+	if len(args) < 2 || (args[0] != "add" && args[0] != "rm") {
+		helpTopic("keys")
+		os.Exit(2)
+	}
+	req := keysRequest{Stack: args[1]}
+	switch {
+	case args[0] == "add" && len(args) > 2:
+		req.Add = []string{args[2]}
+	case args[0] == "add":
+		req.Add = []string{randomKey(40)}
+	case len(args) > 2:
+		req.Remove = []string{args[2]}
+	default:
+		helpTopic("keys")
+		os.Exit(2)
+	}
+	var inst Instance
+	if err := daemonCall(http.MethodPost, "/v1/keys", req, &inst); err != nil {
+		fmt.Println("Could not update keys (is a detached stack running?):", err)
+		os.Exit(1)
 	}
-	if st.McpoPID > 0 {
-		_ = killProcessGroup(st.McpoPID)
-		fmt.Println("Stopped mcpo (pid", st.McpoPID, ") and its child MCP servers")
-		st.McpoPID = 0
+	if len(req.Add) > 0 {
+		fmt.Printf("Added key for %s: %s\n", inst.Name, req.Add[0])
+	} else {
+		fmt.Printf("Removed key from %s\n", inst.Name)
 	}
-	saveState(&st)
+	fmt.Printf("%s now accepts: %s\n", inst.Name, strings.Join(acceptedKeys(inst), ", "))
 }
 
 func cmdOpenAPI() {
+	if len(os.Args) > 2 {
+		switch os.Args[2] {
+		case "diff":
+			cmdOpenAPIDiff(os.Args[3:])
+			return
+		case "history":
+			cmdOpenAPIHistory(os.Args[3:])
+			return
+		}
+	}
 	fs := flag.NewFlagSet("openapi", flag.ExitOnError)
 	fs.Usage = func() { helpTopic("openapi") }
 	var publicURLs stringSlice
 	fs.Var(&publicURLs, "public-url", "Public base URL (repeatable; align with running stacks or one for all)")
 	_ = fs.Parse(os.Args[2:])
 
+	// A detached daemon regenerates and serves the new spec immediately.
+	var results []openAPIResult
+	if err := daemonCall(http.MethodPost, "/v1/openapi", openAPIRequest{PublicURLs: publicURLs}, &results); err == nil {
+		for _, r := range results {
+			if r.Error != "" {
+				fmt.Printf("[openapi#%s] merge failed: %s\n", r.Name, r.Error)
+				continue
+			}
+			if len(r.Warnings) > 0 {
+				fmt.Printf("[openapi#%s] WARNING: unresolved $ref targets detected:\n", r.Name)
+				for _, w := range r.Warnings {
+					fmt.Println("  -", w)
+				}
+			}
+			fmt.Printf("Wrote merged OpenAPI for %s to %s\n", r.Name, r.File)
+			fmt.Printf("Now serving at http://127.0.0.1:%d/openapi.json (%d operations)\n", r.FrontPort, r.OperationCount)
+		}
+		return
+	}
+
 	st := loadState()
 	if len(st.Instances) == 0 {
 		fmt.Println("No running stacks found in state.")
@@ -688,16 +1540,8 @@ func cmdOpenAPI() {
 
 	for i := range st.Instances {
 		inst := &st.Instances[i]
-		baseURL := inst.PublicURL
-		if len(publicURLs) == 1 {
-			baseURL = strings.TrimRight(publicURLs[0], "/")
-		} else if len(publicURLs) > i {
-			baseURL = strings.TrimRight(publicURLs[i], "/")
-		}
-		if baseURL == "" {
-			baseURL = fmt.Sprintf("http://127.0.0.1:%d", inst.FrontPort)
-		}
-		spec, err := mergeOpenAPI(*inst, baseURL)
+		baseURL := alignedPublicURL(publicURLs, i, *inst)
+		spec, _, out, err := writeMergedOpenAPI(*inst, baseURL)
 		if err != nil {
 			fmt.Printf("[openapi#%s] merge failed: %v\n", inst.Name, err)
 			continue
@@ -709,60 +1553,82 @@ func cmdOpenAPI() {
 				fmt.Println("  -", w)
 			}
 		}
-		out := filepath.Join(getStateDir(), fmt.Sprintf("openapi_%s.json", inst.Name))
-		_ = os.WriteFile(out, spec, 0644)
 		fmt.Printf("Wrote merged OpenAPI for %s to %s\n", inst.Name, out)
 		fmt.Printf("Serve URL (if front proxy running): http://127.0.0.1:%d/openapi.json\n", inst.FrontPort)
 	}
 }
 
-// ---------- helpers ----------
-
-func readConfig(path string) MCPConfig {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return MCPConfig{}
+// alignedPublicURL picks the i-th (or the single) --public-url, else the instance's own URL, else local.
+func alignedPublicURL(publicURLs []string, i int, inst Instance) string {
+	baseURL := inst.PublicURL
+	if len(publicURLs) == 1 {
+		baseURL = strings.TrimRight(publicURLs[0], "/")
+	} else if len(publicURLs) > i {
+		baseURL = strings.TrimRight(publicURLs[i], "/")
 	}
-	var cfg MCPConfig
-	_ = json.Unmarshal(data, &cfg)
-	return cfg
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("http://127.0.0.1:%d", inst.FrontPort)
+	}
+	return baseURL
 }
 
-func ensureStateDir() string {
-	dir := getStateDir()
-	_ = os.MkdirAll(dir, 0o755)
-	return dir
+// writeMergedOpenAPI merges the instance's spec and writes it to .mcp-launch/openapi_<name>.json.
+// It also returns the routes hidden by tool filters.
+func writeMergedOpenAPI(inst Instance, baseURL string) ([]byte, []string, string, error) {
+	spec, filtered, err := mergeOpenAPI(inst, baseURL)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	out := filepath.Join(getStateDir(), fmt.Sprintf("openapi_%s.json", inst.Name))
+	_ = os.WriteFile(out, spec, 0644)
+	return spec, filtered, out, nil
 }
 
-func getStateDir() string { return filepath.Join(".", stateDirName) }
-func statePath() string   { return filepath.Join(getStateDir(), stateFileName) }
-
-func saveState(st *State) {
-	_ = os.MkdirAll(getStateDir(), 0o755)
-	data, _ := json.MarshalIndent(st, "", "  ")
-	_ = os.WriteFile(statePath(), data, 0644)
-}
+// ---------- helpers ----------
 
-func saveStateMulti(st *State, instances []Instance) {
-	st.Instances = instances
-	saveState(st)
+// readConfig is the lenient loader used once `up` has validated its configs; see loadConfig.
+// Disabled servers are left out, as they are from the config mcpo runs.
+func readConfig(path string) MCPConfig {
+	cfg, _ := loadConfig(path)
+	return cfg.enabled()
 }
 
-func loadState() State {
-	path := statePath()
-	var st State
-	data, err := os.ReadFile(path)
+// startMcpo launches mcpo (or the native bridge) for one stack in its own process group and
+// wires its output.
+func startMcpo(inst *Instance, stream bool, logFile *os.File) (*exec.Cmd, error) {
+	// mcpo reads a private copy with ${...} and envFile resolved; ConfigPath keeps the placeholders.
+	cfgPath, err := materializeConfig(inst)
 	if err != nil {
-		// default empty state
-		st = State{
-			StartedAt: time.Now().Format(time.RFC3339),
-			Instances: nil,
+		return nil, err
+	}
+	var cmd *exec.Cmd
+	if inst.Backend == backendNative {
+		self, err := os.Executable()
+		if err != nil {
+			return nil, err
 		}
-		saveState(&st)
-		return st
+		cmd = exec.Command(self, "bridge", "--port", fmt.Sprint(inst.McpoPort), "--config", cfgPath, "--hot-reload")
+		cmd.Env = append(os.Environ(), bridgeKeyEnv+"="+inst.APIKey)
+	} else {
+		cmd = exec.Command(findBinary("mcpo"),
+			"--port", fmt.Sprint(inst.McpoPort),
+			"--api-key", inst.APIKey,
+			"--config", cfgPath,
+			"--hot-reload",
+		)
+	}
+	if runtime.GOOS != "windows" {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 	}
-	_ = json.Unmarshal(data, &st)
-	return st
+	stdout, _ := cmd.StdoutPipe()
+	stderr, _ := cmd.StderrPipe()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	tag := "mcpo#" + inst.Name
+	go scanAndMaybeStream(tag, stdout, stream, logFile, nil)
+	go scanAndMaybeStream(tag, stderr, stream, logFile, nil)
+	return cmd, nil
 }
 
 func findBinary(name string) string {
@@ -787,7 +1653,7 @@ func openLogFile(path string) (*os.File, error) {
 	if dir := filepath.Dir(path); dir != "." && dir != "" {
 		_ = os.MkdirAll(dir, 0o755)
 	}
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
 	if err != nil {
 		return nil, err
 	}
@@ -796,9 +1662,13 @@ func openLogFile(path string) (*os.File, error) {
 }
 
 func scanAndMaybeStream(tag string, r io.Reader, stream bool, logFile *os.File, onLine func(string)) {
+	procLog := procLogFor(tag) // .mcp-launch/logs/<stack>/<proc>.log
 	sc := bufio.NewScanner(r)
 	for sc.Scan() {
-		line := sc.Text()
+		line := redactSecrets(sc.Text())
+		if procLog != nil {
+			procLog.WriteLine(line)
+		}
 		if stream {
 			fmt.Printf("[%s] %s\n", tag, line)
 		}
@@ -813,6 +1683,55 @@ func scanAndMaybeStream(tag string, r io.Reader, stream bool, logFile *os.File,
 	}
 }
 
+// scopedKeys returns the --accept-key values that apply to stack: unscoped KEY, or STACK=KEY
+// where STACK is a stack of this run. A key that merely contains "=" (base64 padding, a=b) is
+// unscoped unless the text before it names one of stacks.
+func scopedKeys(raw []string, stack string, stacks []string) []string {
+	var out []string
+	for _, v := range raw {
+		scope, k, ok := keyScope(v, stacks)
+		switch {
+		case !ok:
+			out = append(out, v)
+		case scope == stack:
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// keyScope splits STACK=KEY when STACK is one of stacks or an auto-split part of one (code-2).
+func keyScope(v string, stacks []string) (scope, key string, ok bool) {
+	scope, key, ok = strings.Cut(v, "=")
+	if !ok || scope == "" {
+		return "", "", false
+	}
+	for _, s := range stacks {
+		if scope == s {
+			return scope, key, true
+		}
+		if part, ok := strings.CutPrefix(scope, s+"-"); ok {
+			if n, err := strconv.Atoi(part); err == nil && n >= 2 {
+				return scope, key, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// keyScopeWarnings flags --accept-key values that look like STACK=KEY for a stack that is not
+// part of this run; they are accepted whole by every stack, which is rarely what was meant.
+func keyScopeWarnings(raw []string, stacks []string) []string {
+	var out []string
+	for _, v := range raw {
+		prefix, _, found := strings.Cut(v, "=")
+		if _, _, ok := keyScope(v, stacks); found && !ok && prefix != "" && prefix == nameFromPath(prefix, 0) {
+			out = append(out, fmt.Sprintf("--accept-key %s=…: there is no stack %q in this run, so the whole value is accepted as a key by every stack", prefix, prefix))
+		}
+	}
+	return out
+}
+
 func randomKey(n int) string {
 	const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	buf := make([]byte, n)
@@ -868,15 +1787,46 @@ type frontProxy struct {
 	proxy *httputil.ReverseProxy
 	mu    sync.RWMutex
 	spec  []byte // merged openapi
+
+	upstreamKey string          // key mcpo was started with; injected on every proxied request
+	keys        []string        // X-API-Key values accepted from clients
+	protectSpec bool            // also require a key for /openapi.json
+	filtered    map[string]bool // "METHOD /path" routes hidden by tool filters
+
+	name     string            // stack name, for logs and metrics
+	ops      map[string]string // "METHOD /path" → operationId from the merged spec
+	tools    []frontTool       // tools offered at /mcp, from the merged spec
+	mux      *http.ServeMux
+	mcpoPort int
+	servers  []string // MCP servers probed by /healthz and /readyz
+	down     string   // why the stack's mcpo was given up on; health answers 503 without probing
+
+	healthMu sync.Mutex
+	health   stackHealth // last probe result, reused for anonymous callers
+	healthAt time.Time
 }
 
-func newFrontProxy(frontPort, mcpoPort int) *frontProxy {
+func newFrontProxy(name string, frontPort, mcpoPort int, upstreamKey string) *frontProxy {
 	target, _ := url.Parse(fmt.Sprintf("http://127.0.0.1:%d", mcpoPort))
 	p := httputil.NewSingleHostReverseProxy(target)
-	fp := &frontProxy{proxy: p}
+	errLog := log.New(procLogFor("proxy#"+name), "", 0)
+	p.ErrorLog = errLog
+	fp := &frontProxy{proxy: p, upstreamKey: upstreamKey, name: name, mcpoPort: mcpoPort}
+	director := p.Director
+	p.Director = func(r *http.Request) {
+		director(r)
+		// Clients may use any accepted key; mcpo only knows its own.
+		if fp.upstreamKey != "" {
+			r.Header.Set("X-API-Key", fp.upstreamKey)
+		}
+	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		if fp.protected() && !fp.authorized(r) {
+			writeUnauthorized(w)
+			return
+		}
 		fp.mu.RLock()
 		defer fp.mu.RUnlock()
 		if len(fp.spec) == 0 {
@@ -886,49 +1836,132 @@ func newFrontProxy(frontPort, mcpoPort int) *frontProxy {
 		w.Header().Set("Content-Type", "application/json")
 		_, _ = w.Write(fp.spec)
 	})
-	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
-	})
+	mux.HandleFunc("/healthz", fp.handleHealth(false))
+	mux.HandleFunc("/readyz", fp.handleHealth(true))
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if !fp.authorized(r) {
+			writeUnauthorized(w)
+			return
+		}
+		if fp.isFiltered(r) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"detail":"Not Found"}`))
+			return
+		}
 		p.ServeHTTP(w, r)
 	})
 
+	fp.mux = mux
 	fp.srv = &http.Server{
-		Addr:    fmt.Sprintf(":%d", frontPort),
-		Handler: mux,
+		Addr:     fmt.Sprintf(":%d", frontPort),
+		Handler:  fp.withMetrics(mux),
+		ErrorLog: errLog,
 	}
 	return fp
 }
 
 func (f *frontProxy) Serve() error { return f.srv.ListenAndServe() }
 
+// SetOpenAPI serves a new merged spec and records it in the stack's spec history.
 func (f *frontProxy) SetOpenAPI(spec []byte) {
+	ops, tools := operationIndex(spec), mcpToolIndex(spec)
+	if err := saveSpecRevision(f.name, spec); err != nil {
+		logProcLine("proxy#"+f.name, "could not save spec history: "+err.Error())
+	}
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	f.spec = spec
+	f.ops = ops
+	f.tools = tools
+}
+
+// EnableAccessLog records every request as a JSON line in the stack's access log. Call before Serve.
+func (f *frontProxy) EnableAccessLog(cfg accessLogConfig) {
+	f.srv.Handler = f.withAccessLog(f.srv.Handler, newAccessLog(f.name), cfg)
+}
+
+// SetKeys replaces the accepted client keys; an empty set disables the check.
+func (f *frontProxy) SetKeys(keys []string, protectSpec bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys = slices.Clone(keys)
+	f.protectSpec = protectSpec
+}
+
+// SetFiltered replaces the set of routes (see routeKey) the proxy answers with 404.
+func (f *frontProxy) SetFiltered(routes []string) {
+	m := make(map[string]bool, len(routes))
+	for _, r := range routes {
+		m[r] = true
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.filtered = m
+}
+
+func (f *frontProxy) isFiltered(r *http.Request) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.filtered[routeKey(r.Method, path.Clean("/"+r.URL.Path))]
+}
+
+func (f *frontProxy) protected() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.protectSpec
+}
+
+// authorized compares X-API-Key against every accepted key in constant time.
+func (f *frontProxy) authorized(r *http.Request) bool {
+	return f.keyAccepted(r.Header.Get("X-API-Key"))
+}
+
+func (f *frontProxy) keyAccepted(key string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if len(f.keys) == 0 {
+		return true
+	}
+	got := []byte(key)
+	ok := 0
+	for _, k := range f.keys {
+		ok |= subtle.ConstantTimeCompare(got, []byte(k))
+	}
+	return ok == 1
+}
+
+func writeUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", `APIKey header="X-API-Key"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	_, _ = w.Write([]byte(`{"detail":"Missing or invalid X-API-Key"}`))
+}
+
+// acceptedKeys lists the client keys a stack accepts (the mcpo key unless rotated).
+func acceptedKeys(inst Instance) []string {
+	if len(inst.AcceptedKeys) > 0 {
+		return inst.AcceptedKeys
+	}
+	if inst.APIKey == "" {
+		return nil
+	}
+	return []string{inst.APIKey}
 }
 
 func (f *frontProxy) Close(ctx context.Context) error { return f.srv.Shutdown(ctx) }
 
-func startQuickTunnel(tag string, frontPort int, stream bool, logFile *os.File) string {
+// startQuickTunnel starts cloudflared for frontPort and returns its public URL ("" if none
+// appeared) and PID; the caller records the PID in state.
+func startQuickTunnel(tag string, frontPort int, stream bool, logFile *os.File) (string, int) {
 	bin := findBinary("cloudflared")
 	cmd := exec.Command(bin, "tunnel", "--url", fmt.Sprintf("http://127.0.0.1:%d", frontPort))
 	stdout, _ := cmd.StdoutPipe()
 	stderr, _ := cmd.StderrPipe()
 	if err := cmd.Start(); err != nil {
 		fmt.Println("Failed to start cloudflared:", err)
-		return ""
-	}
-	// save PID
-	st := loadState()
-	for i := range st.Instances {
-		if st.Instances[i].FrontPort == frontPort {
-			st.Instances[i].CloudflaredPID = cmd.Process.Pid
-			break
-		}
+		return "", 0
 	}
-	saveState(&st)
 
 	// Parse URL from output
 	urlCh := make(chan string, 1)
@@ -945,35 +1978,12 @@ func startQuickTunnel(tag string, frontPort int, stream bool, logFile *os.File)
 
 	select {
 	case u := <-urlCh:
-		return strings.TrimSuffix(u, "/")
+		return strings.TrimSuffix(u, "/"), cmd.Process.Pid
 	case <-time.After(25 * time.Second):
-		return ""
+		return "", cmd.Process.Pid
 	}
 }
 
-func startNamedTunnel(tag, name string, stream bool, logFile *os.File) {
-	args := []string{"tunnel", "run"}
-	if name != "" {
-		args = append(args, name)
-	}
-	cmd := exec.Command(findBinary("cloudflared"), args...)
-	stdout, _ := cmd.StdoutPipe()
-	stderr, _ := cmd.StderrPipe()
-	_ = cmd.Start()
-	go scanAndMaybeStream(tag, stdout, stream, logFile, nil)
-	go scanAndMaybeStream(tag, stderr, stream, logFile, nil)
-
-	// save PID
-	st := loadState()
-	for i := range st.Instances {
-		if st.Instances[i].CloudflaredPID == 0 {
-			st.Instances[i].CloudflaredPID = cmd.Process.Pid
-			break
-		}
-	}
-	saveState(&st)
-}
-
 func killPID(pid int) error {
 	if pid <= 0 {
 		return nil
@@ -1005,21 +2015,25 @@ func killProcessGroup(pid int) error {
 	return nil
 }
 
-func waitURL(u string, timeout time.Duration) {
+// waitURL polls u until it answers; the error carries the last failure after the timeout.
+func waitURL(u string, timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 	t := time.NewTicker(500 * time.Millisecond)
 	defer t.Stop()
+	lastErr := errors.New("no response")
 	for {
 		select {
 		case <-ctx.Done():
-			return
+			return fmt.Errorf("%s did not answer within %s: %w", u, timeout, lastErr)
 		case <-t.C:
-			req, _ := http.NewRequest("GET", u, nil)
-			_, err := http.DefaultClient.Do(req)
+			req, _ := http.NewRequestWithContext(ctx, "GET", u, nil)
+			resp, err := http.DefaultClient.Do(req)
 			if err == nil {
-				return
+				resp.Body.Close()
+				return nil
 			}
+			lastErr = err
 		}
 	}
 }
@@ -1055,10 +2069,20 @@ func nameFromPath(p string, i int) string {
 
 // -------- OpenAPI merge --------
 
-func mergeOpenAPI(inst Instance, baseURL string) ([]byte, error) {
+// mergeOpenAPI builds the stack's merged spec. It also returns the routes removed by tool
+// filters ("METHOD /path"), which the front proxy refuses with 404.
+func mergeOpenAPI(inst Instance, baseURL string) ([]byte, []string, error) {
 	cfg := readConfig(inst.ConfigPath)
 	if len(cfg.MCPServers) == 0 {
-		return nil, fmt.Errorf("no mcpServers in %s", inst.ConfigPath)
+		return nil, nil, fmt.Errorf("no mcpServers in %s", inst.ConfigPath)
+	}
+	var filtered []string
+	var only map[string]bool // --auto-split part: restrict to assigned routes
+	if len(inst.Routes) > 0 {
+		only = make(map[string]bool, len(inst.Routes))
+		for _, r := range inst.Routes {
+			only[r] = true
+		}
 	}
 
 	merged := map[string]any{
@@ -1101,6 +2125,7 @@ func mergeOpenAPI(inst Instance, baseURL string) ([]byte, error) {
 
 	client := &http.Client{Timeout: 30 * time.Second}
 	for _, name := range names {
+		server := cfg.MCPServers[name]
 		toolURL := fmt.Sprintf("http://127.0.0.1:%d/%s/openapi.json", inst.McpoPort, name)
 		req, _ := http.NewRequest("GET", toolURL, nil)
 		if inst.APIKey != "" {
@@ -1108,17 +2133,17 @@ func mergeOpenAPI(inst Instance, baseURL string) ([]byte, error) {
 		}
 		resp, err := client.Do(req)
 		if err != nil {
-			return nil, fmt.Errorf("fetch %s: %w", toolURL, err)
+			return nil, nil, fmt.Errorf("fetch %s: %w", toolURL, err)
 		}
 		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
 		if resp.StatusCode != 200 {
-			return nil, fmt.Errorf("fetch %s: status %s\n%s", toolURL, resp.Status, string(body))
+			return nil, nil, fmt.Errorf("fetch %s: status %s\n%s", toolURL, resp.Status, string(body))
 		}
 
 		var spec map[string]any
 		if err := json.Unmarshal(body, &spec); err != nil {
-			return nil, fmt.Errorf("parse %s: %w", toolURL, err)
+			return nil, nil, fmt.Errorf("parse %s: %w", toolURL, err)
 		}
 
 		// Collect local component keys from the ORIGINAL to know which refs are local.
@@ -1163,14 +2188,26 @@ func mergeOpenAPI(inst Instance, baseURL string) ([]byte, error) {
 						if !ok {
 							continue
 						}
-						if oid, ok := om["operationId"].(string); ok && oid != "" {
-							om["operationId"] = name + "__" + oid
-						} else {
-							om["operationId"] = name + "__" + strings.ToLower(method) + "_" + sanitizeForID(rawPath)
+						localID, _ := om["operationId"].(string)
+						if localID == "" {
+							localID = strings.ToLower(method) + "_" + sanitizeForID(rawPath)
+						}
+						om["operationId"] = name + "__" + localID
+						// Per-server then per-stack allow/deny filters.
+						ids := []string{localID, name + "__" + localID}
+						routes := []string{ensureLeadingSlash(rawPath), newPath}
+						if !server.Tools.allows(ids, routes) || !cfg.Tools.allows(ids, routes) || !inst.Tools.allows(ids, routes) ||
+							(only != nil && !only[routeKey(method, newPath)]) {
+							delete(m, method)
+							filtered = append(filtered, routeKey(method, newPath))
+							continue
 						}
 						// Cleanup: remove any per-operation security (duplicate of top-level).
 						delete(om, "security")
 					}
+					if !hasOperations(m) {
+						continue
+					}
 				}
 				pathsOut[newPath] = v
 			}
@@ -1186,7 +2223,7 @@ func mergeOpenAPI(inst Instance, baseURL string) ([]byte, error) {
 	coerceIntegerTypes(merged)
 
 	out, _ := json.MarshalIndent(merged, "", "  ")
-	return out, nil
+	return redactSecretsJSON(out), filtered, nil
 }
 
 // rewriteRefs recursively rewrites local component $refs to namespaced form "<tool>__Name".
@@ -1238,15 +2275,11 @@ func countOperations(spec []byte) int {
 	if paths == nil {
 		return 0
 	}
-	methods := map[string]struct{}{
-		"get": {}, "post": {}, "put": {}, "delete": {}, "patch": {},
-		"options": {}, "head": {}, "trace": {}, "connect": {},
-	}
 	count := 0
 	for _, v := range paths {
 		if mm, ok := v.(map[string]any); ok {
 			for mk := range mm {
-				if _, ok := methods[strings.ToLower(mk)]; ok {
+				if _, ok := httpMethods[strings.ToLower(mk)]; ok {
 					count++
 				}
 			}
@@ -1255,6 +2288,21 @@ func countOperations(spec []byte) int {
 	return count
 }
 
+var httpMethods = map[string]struct{}{
+	"get": {}, "post": {}, "put": {}, "delete": {}, "patch": {},
+	"options": {}, "head": {}, "trace": {}, "connect": {},
+}
+
+// hasOperations reports whether a path item still has at least one HTTP method.
+func hasOperations(item map[string]any) bool {
+	for k := range item {
+		if _, ok := httpMethods[strings.ToLower(k)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 // Find unresolved component $ref targets for quick diagnostics.
 func findDanglingComponentRefs(spec []byte) []string {
 	var m map[string]any
@@ -0,0 +1,128 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// chdirTemp runs the rest of a test in a fresh directory, so the state dir (.mcp-launch) and
+// logs it writes stay out of the repository.
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	old, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(old) })
+	return dir
+}
+
+func TestScopedKeys(t *testing.T) {
+	stacks := []string{"code", "docs"}
+	raw := []string{"plain", "code=k1", "docs=k2", "code-2=k3", "code-x=k4", "abc==", "other=k5", "=lead"}
+	tests := []struct {
+		stack string
+		want  []string
+	}{
+		{"code", []string{"plain", "k1", "code-x=k4", "abc==", "other=k5", "=lead"}},
+		{"docs", []string{"plain", "k2", "code-x=k4", "abc==", "other=k5", "=lead"}},
+		{"code-2", []string{"plain", "k3", "code-x=k4", "abc==", "other=k5", "=lead"}},
+	}
+	for _, tt := range tests {
+		if got := scopedKeys(raw, tt.stack, stacks); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("scopedKeys(%s) = %q, want %q", tt.stack, got, tt.want)
+		}
+	}
+
+	warnings := keyScopeWarnings(raw, stacks)
+	if len(warnings) != 3 || !strings.Contains(warnings[0], `"code-x"`) || !strings.Contains(warnings[1], `"abc"`) || !strings.Contains(warnings[2], `"other"`) {
+		t.Errorf("warnings = %q, want code-x, abc and other", warnings)
+	}
+	for _, w := range warnings {
+		if strings.Contains(w, "k4") || strings.Contains(w, "k5") {
+			t.Errorf("warning leaks the key: %s", w)
+		}
+	}
+}
+
+func TestFrontProxyKeys(t *testing.T) {
+	f := newTestMCPProxy(t, "frontkeys")
+	tests := []struct {
+		name        string
+		keys        []string
+		protectSpec bool
+		path, key   string
+		want        int
+	}{
+		{"no key", []string{"client-key"}, false, "/srv/echo", "", http.StatusUnauthorized},
+		{"wrong key", []string{"client-key"}, false, "/srv/echo", "nope", http.StatusUnauthorized},
+		{"prefix of a key", []string{"client-key"}, false, "/srv/echo", "client", http.StatusUnauthorized},
+		{"accepted key", []string{"client-key"}, false, "/srv/echo", "client-key", http.StatusOK},
+		{"rotated key", []string{"client-key", "next-key"}, false, "/srv/echo", "next-key", http.StatusOK},
+		{"no keys accepts anything", nil, false, "/srv/echo", "", http.StatusOK},
+		{"spec is open", []string{"client-key"}, false, "/openapi.json", "", http.StatusOK},
+		{"spec is protected", []string{"client-key"}, true, "/openapi.json", "", http.StatusUnauthorized},
+		{"protected spec with key", []string{"client-key"}, true, "/openapi.json", "client-key", http.StatusOK},
+		{"healthz is open", []string{"client-key"}, true, "/healthz", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f.SetKeys(tt.keys, tt.protectSpec)
+			method := http.MethodGet
+			if tt.path == "/srv/echo" {
+				method = http.MethodPost
+			}
+			req := httptest.NewRequest(method, tt.path, strings.NewReader(`{}`))
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			f.srv.Handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.want, rec.Body)
+			}
+			if rec.Code != http.StatusUnauthorized {
+				return
+			}
+			if got := rec.Body.String(); got != `{"detail":"Missing or invalid X-API-Key"}` {
+				t.Errorf("body = %s", got)
+			}
+			if rec.Header().Get("WWW-Authenticate") == "" || rec.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("headers = %v", rec.Header())
+			}
+		})
+	}
+}
+
+func TestReservePort(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	busy := ln.Addr().(*net.TCPAddr).Port
+	// A part placed after a stack at busy-1 skips that stack's port, a port another stack pinned
+	// (busy+1) and the port something else is listening on (busy).
+	taken := map[int]bool{busy - 1: true, busy + 1: true}
+	first := reservePort(busy-1, taken)
+	if first <= busy+1 {
+		t.Errorf("reservePort = %d, want a port after %d", first, busy+1)
+	}
+	if !taken[first] {
+		t.Error("the reserved port is not marked taken")
+	}
+	if second := reservePort(busy-1, taken); second <= first {
+		t.Errorf("second reservePort = %d, want a port after %d", second, first)
+	}
+}
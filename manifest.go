@@ -0,0 +1,262 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ---------- mcp-launch.yaml stack manifest ----------
+//
+// A manifest describes a whole multi-stack setup so `mcp-launch up` needs no flags:
+//
+//	tunnel: named
+//	stacks:
+//	  - name: code
+//	    config: code.json
+//	    hostname: code.example.com
+//	    api_key: ${CODE_GPT_KEY}
+//	  - name: ops
+//	    servers: { time: { command: uvx, args: [mcp-server-time] } }
+//	    tools: { exclude: ["*delete*"] }
+//
+// Top-level values are defaults for every stack; flags given on the command line win.
+
+const manifestStacksDirName = "stacks" // generated configs for stacks with inline servers
+
+var defaultManifestNames = []string{"mcp-launch.yaml", "mcp-launch.yml", "mcp-launch.json", "mcp-launch.toml"}
+
+type manifest struct {
+	Port           int             `json:"port,omitempty"`      // base front port
+	McpoPort       int             `json:"mcpo_port,omitempty"` // base mcpo port
+	Tunnel         string          `json:"tunnel,omitempty"`    // quick | named | none
+	TunnelName     string          `json:"tunnel_name,omitempty"`
+	Backend        string          `json:"backend,omitempty"` // mcpo | native
+	Restart        string          `json:"restart,omitempty"`
+	SharedKey      bool            `json:"shared_key,omitempty"`
+	APIKey         string          `json:"api_key,omitempty"` // with shared_key; ${VAR} / ${file:...}
+	ProtectOpenAPI bool            `json:"protect_openapi,omitempty"`
+	Stacks         []manifestStack `json:"stacks"`
+}
+
+type manifestStack struct {
+	Name       string               `json:"name,omitempty"`
+	Config     string               `json:"config,omitempty"`  // path to a JSON/YAML/TOML config…
+	Servers    map[string]MCPServer `json:"servers,omitempty"` // …or inline servers
+	Tools      *ToolFilter          `json:"tools,omitempty"`   // stack-wide filter on top of the config's
+	Port       int                  `json:"port,omitempty"`
+	McpoPort   int                  `json:"mcpo_port,omitempty"`
+	Tunnel     string               `json:"tunnel,omitempty"`
+	TunnelName string               `json:"tunnel_name,omitempty"`
+	Backend    string               `json:"backend,omitempty"`
+	Hostname   string               `json:"hostname,omitempty"` // named tunnel hostname → https://hostname
+	PublicURL  string               `json:"public_url,omitempty"`
+	APIKey     string               `json:"api_key,omitempty"` // fixed key, usually ${VAR} or ${file:...}
+	AcceptKeys []string             `json:"accept_keys,omitempty"`
+	Restart    string               `json:"restart,omitempty"`
+}
+
+// findManifest returns the manifest to use: --manifest, else the first default name present.
+func findManifest(explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	for _, name := range defaultManifestNames {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// loadManifest reads and checks a manifest; unknown fields are errors so typos don't go unnoticed.
+func loadManifest(path string) (*manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	js, err := configJSON(path, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var m manifest
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(m.Stacks) == 0 {
+		return nil, fmt.Errorf("%s: no stacks", path)
+	}
+	relDir := filepath.Dir(path)
+	seen := map[string]bool{}
+	for i := range m.Stacks {
+		s := &m.Stacks[i]
+		switch {
+		case s.Config != "" && len(s.Servers) > 0:
+			return nil, fmt.Errorf("%s: stack %d: use either config or servers, not both", path, i+1)
+		case s.Config == "" && len(s.Servers) == 0:
+			return nil, fmt.Errorf("%s: stack %d: needs config or servers", path, i+1)
+		case len(s.Servers) > 0 && s.Name == "":
+			return nil, fmt.Errorf("%s: stack %d: stacks with inline servers need a name", path, i+1)
+		}
+		if s.Config != "" && !filepath.IsAbs(s.Config) {
+			s.Config = filepath.Join(relDir, s.Config)
+		}
+		if s.Name == "" {
+			s.Name = nameFromPath(s.Config, i)
+		}
+		if seen[s.Name] {
+			return nil, fmt.Errorf("%s: duplicate stack name %q", path, s.Name)
+		}
+		seen[s.Name] = true
+		if s.Hostname != "" && s.PublicURL == "" {
+			s.PublicURL = "https://" + strings.TrimPrefix(s.Hostname, "https://")
+		}
+	}
+	return &m, nil
+}
+
+// configPath returns the stack's config, writing inline servers to .mcp-launch/stacks/<name>.json.
+// ${...} placeholders are kept; they are resolved like any config when mcpo starts.
+func (s manifestStack) configPath() (string, error) {
+	if s.Config != "" {
+		return s.Config, nil
+	}
+	p := filepath.Join(getStateDir(), manifestStacksDirName, s.Name+".json")
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return "", err
+	}
+	b, _ := json.MarshalIndent(MCPConfig{MCPServers: s.Servers}, "", "  ")
+	return p, os.WriteFile(p, b, 0o644)
+}
+
+// resolveKeySource resolves a key source (literal, ${VAR} or ${file:...}).
+func resolveKeySource(raw, relDir string) (string, error) {
+	key, err := interpolate(raw, os.LookupEnv, relDir)
+	if err != nil {
+		return "", err
+	}
+	if key != raw {
+		registerSecret(key)
+	}
+	if key == "" {
+		return "", errors.New("empty api_key")
+	}
+	return key, nil
+}
+
+// applyManifest fills flags the user did not set from the manifest and returns the per-stack
+// settings aligned with the resulting --config list (nil entries when --config replaced the stacks).
+func applyManifest(fs *flag.FlagSet, m *manifest, manifestPath string, configs, publicURLs, restartModes, extraKeys *stringSlice) ([]*manifestStack, error) {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	relDir := filepath.Dir(manifestPath)
+
+	defaults := map[string]string{
+		"tunnel":      m.Tunnel,
+		"tunnel-name": m.TunnelName,
+		"backend":     m.Backend,
+	}
+	if m.Port > 0 {
+		defaults["port"] = strconv.Itoa(m.Port)
+	}
+	if m.McpoPort > 0 {
+		defaults["mcpo-port"] = strconv.Itoa(m.McpoPort)
+	}
+	if m.SharedKey {
+		defaults["shared-key"] = "true"
+	}
+	if m.ProtectOpenAPI {
+		defaults["protect-openapi"] = "true"
+	}
+	if m.APIKey != "" && !set["api-key"] {
+		key, err := resolveKeySource(m.APIKey, relDir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: api_key: %w", manifestPath, err)
+		}
+		defaults["api-key"] = key
+	}
+	for name, v := range defaults {
+		if v != "" && !set[name] {
+			if err := fs.Set(name, v); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", manifestPath, name, err)
+			}
+		}
+	}
+
+	// Explicit flags also beat per-stack values.
+	for i := range m.Stacks {
+		s := &m.Stacks[i]
+		if set["port"] {
+			s.Port = 0
+		}
+		if set["mcpo-port"] {
+			s.McpoPort = 0
+		}
+		if set["tunnel"] {
+			s.Tunnel = ""
+		}
+		if set["tunnel-name"] {
+			s.TunnelName = ""
+		}
+		if set["backend"] {
+			s.Backend = ""
+		}
+		if set["api-key"] || set["shared-key"] {
+			s.APIKey = ""
+		}
+	}
+
+	// --config on the command line replaces the manifest's stack list.
+	if set["config"] {
+		return make([]*manifestStack, len(*configs)), nil
+	}
+	stacks := make([]*manifestStack, len(m.Stacks))
+	var urls, modes []string
+	anyURL, anyMode := false, false
+	for i := range m.Stacks {
+		s := &m.Stacks[i]
+		p, err := s.configPath()
+		if err != nil {
+			return nil, fmt.Errorf("stack %s: %w", s.Name, err)
+		}
+		*configs = append(*configs, p)
+		stacks[i] = s
+		urls = append(urls, s.PublicURL)
+		anyURL = anyURL || s.PublicURL != ""
+		mode := s.Restart
+		if mode == "" {
+			mode = m.Restart
+		}
+		modes = append(modes, mode)
+		anyMode = anyMode || mode != ""
+		for _, k := range s.AcceptKeys {
+			key, err := resolveKeySource(k, relDir)
+			if err != nil {
+				return nil, fmt.Errorf("stack %s: accept_keys: %w", s.Name, err)
+			}
+			*extraKeys = append(*extraKeys, s.Name+"="+key)
+		}
+		if s.APIKey != "" {
+			if s.APIKey, err = resolveKeySource(s.APIKey, relDir); err != nil {
+				return nil, fmt.Errorf("stack %s: api_key: %w", s.Name, err)
+			}
+		}
+	}
+	if anyURL && !set["public-url"] {
+		*publicURLs = urls
+	}
+	if anyMode && !set["restart"] {
+		*restartModes = modes
+	}
+	return stacks, nil
+}
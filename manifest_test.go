@@ -0,0 +1,123 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"flag"
+	"os"
+	"reflect"
+	"testing"
+)
+
+const testManifest = `
+port: 9000
+mcpo_port: 9100
+tunnel: named
+tunnel_name: from-manifest
+backend: native
+restart: always
+shared_key: true
+api_key: ${TEST_MANIFEST_KEY}
+protect_openapi: true
+stacks:
+  - name: a
+    config: a.json
+    port: 9500
+    tunnel: quick
+    public_url: https://a.example.com
+    api_key: stack-key
+    accept_keys: [next-key]
+  - config: b.json
+`
+
+// upFlags is the part of up's flag set that a manifest can fill in.
+type upFlags struct {
+	fs                                   *flag.FlagSet
+	port, mcpoPort                       *int
+	tunnel, tunnelName, backend, apiKey  *string
+	sharedKey, protectSpec               *bool
+	configs, publicURLs, restarts, extra stringSlice
+}
+
+func newUpFlags(args ...string) *upFlags {
+	u := &upFlags{fs: flag.NewFlagSet("up", flag.ContinueOnError)}
+	u.fs.Var(&u.configs, "config", "")
+	u.fs.Var(&u.publicURLs, "public-url", "")
+	u.fs.Var(&u.restarts, "restart", "")
+	u.fs.Var(&u.extra, "accept-key", "")
+	u.port = u.fs.Int("port", defaultFrontPort, "")
+	u.mcpoPort = u.fs.Int("mcpo-port", defaultMcpoPort, "")
+	u.tunnel = u.fs.String("tunnel", "quick", "")
+	u.tunnelName = u.fs.String("tunnel-name", "", "")
+	u.backend = u.fs.String("backend", backendMcpo, "")
+	u.apiKey = u.fs.String("api-key", "", "")
+	u.sharedKey = u.fs.Bool("shared-key", false, "")
+	u.protectSpec = u.fs.Bool("protect-openapi", false, "")
+	_ = u.fs.Parse(args)
+	return u
+}
+
+func TestApplyManifestPrecedence(t *testing.T) {
+	chdirTemp(t)
+	t.Setenv("TEST_MANIFEST_KEY", "manifest-key")
+	if err := os.WriteFile("mcp-launch.yaml", []byte(testManifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	type want struct {
+		port, mcpoPort                      int
+		tunnel, tunnelName, backend, apiKey string
+		sharedKey, protectSpec              bool
+		configs, publicURLs, restarts       []string
+		stackPort                           int
+		stackTunnel, stackKey               string
+	}
+	fromManifest := want{9000, 9100, "named", "from-manifest", "native", "manifest-key", true, true,
+		[]string{"a.json", "b.json"}, []string{"https://a.example.com", ""}, []string{"always", "always"}, 9500, "quick", "stack-key"}
+	tests := []struct {
+		name string
+		args []string
+		want want
+	}{
+		{"unset flags take the manifest", nil, fromManifest},
+		{"explicit flags win", []string{"--port", "7000", "--mcpo-port=7100", "--tunnel", "none", "--tunnel-name", "cli", "--backend", "mcpo",
+			"--api-key", "cli-key", "--restart", "never", "--public-url", "https://cli.example.com"},
+			want{7000, 7100, "none", "cli", "mcpo", "cli-key", true, true,
+				[]string{"a.json", "b.json"}, []string{"https://cli.example.com"}, []string{"never"}, 0, "", ""}},
+		{"explicit false beats manifest true", []string{"--shared-key=false", "--protect-openapi=false"},
+			want{9000, 9100, "named", "from-manifest", "native", "manifest-key", false, false,
+				[]string{"a.json", "b.json"}, []string{"https://a.example.com", ""}, []string{"always", "always"}, 9500, "quick", ""}},
+		{"a flag equal to its default still wins", []string{"--port", "8000", "--tunnel", "quick"},
+			want{8000, 9100, "quick", "from-manifest", "native", "manifest-key", true, true,
+				[]string{"a.json", "b.json"}, []string{"https://a.example.com", ""}, []string{"always", "always"}, 0, "", "stack-key"}},
+		{"--config replaces the stacks", []string{"--config", "c.json"},
+			want{9000, 9100, "named", "from-manifest", "native", "manifest-key", true, true,
+				[]string{"c.json"}, nil, nil, 0, "", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := loadManifest("mcp-launch.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			u := newUpFlags(tt.args...)
+			stacks, err := applyManifest(u.fs, m, "mcp-launch.yaml", &u.configs, &u.publicURLs, &u.restarts, &u.extra)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := want{*u.port, *u.mcpoPort, *u.tunnel, *u.tunnelName, *u.backend, *u.apiKey, *u.sharedKey, *u.protectSpec,
+				u.configs, u.publicURLs, u.restarts, 0, "", ""}
+			if len(stacks) != len(u.configs) {
+				t.Fatalf("%d stack spec(s) for %d config(s)", len(stacks), len(u.configs))
+			}
+			if stacks[0] != nil {
+				got.stackPort, got.stackTunnel, got.stackKey = stacks[0].Port, stacks[0].Tunnel, stacks[0].APIKey
+				if !reflect.DeepEqual(u.extra, stringSlice{"a=next-key"}) {
+					t.Errorf("accept keys = %q", u.extra)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("after applyManifest:\n got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,407 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ---------- MCP client ----------
+//
+// Just enough of the Model Context Protocol to list and call tools: JSON-RPC 2.0 over a stdio
+// server's stdin/stdout (one message per line) or, for remote servers, over HTTP (remote.go).
+// Requests from the server (ping, roots, sampling) are answered so servers that send them do
+// not hang.
+
+const mcpProtocolVersion = "2025-03-26"
+
+type rpcError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string { return fmt.Sprintf("%s (code %d)", e.Message, e.Code) }
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type mcpTool struct {
+	Name         string          `json:"name"`
+	Title        string          `json:"title,omitempty"`
+	Description  string          `json:"description,omitempty"`
+	InputSchema  json.RawMessage `json:"inputSchema,omitempty"`
+	OutputSchema json.RawMessage `json:"outputSchema,omitempty"`
+}
+
+type mcpContent struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	Data     string          `json:"data,omitempty"`
+	MimeType string          `json:"mimeType,omitempty"`
+	Resource json.RawMessage `json:"resource,omitempty"`
+}
+
+type mcpCallResult struct {
+	Content           []mcpContent    `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+}
+
+// mcpTransport carries JSON-RPC messages to a server; messages from the server are handed to
+// the client's dispatch, possibly before send returns.
+type mcpTransport interface {
+	send(ctx context.Context, data []byte) error
+	close()
+}
+
+type mcpClient struct {
+	name string
+	logw io.Writer // server stderr and stray stdout lines
+	t    mcpTransport
+
+	mu      sync.Mutex // guards nextID and pending
+	nextID  int64
+	pending map[int64]chan rpcMessage
+
+	done     chan struct{} // closed when the session ends (process exit, stream closed)
+	doneOnce sync.Once
+	err      error // why, once done is closed
+
+	// onToolsChanged handles notifications/tools/list_changed (may be nil). It is set before the
+	// transport starts reading, since it is called from the reading goroutine.
+	onToolsChanged func()
+
+	ServerName, ServerVersion string
+}
+
+func newMCPClient(name string, logw io.Writer, onToolsChanged func()) *mcpClient {
+	return &mcpClient{name: name, logw: logw, onToolsChanged: onToolsChanged, pending: map[int64]chan rpcMessage{}, done: make(chan struct{})}
+}
+
+// dialMCP connects to a server of any type and performs the initialize handshake, retrying
+// per the server's connect policy. onToolsChanged (may be nil) runs when the server reports
+// that its tools changed.
+func dialMCP(name string, s MCPServer, logw io.Writer, onToolsChanged func()) (*mcpClient, error) {
+	timeout, retries := s.connectPolicy()
+	var err error
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		var c *mcpClient
+		c, err = connectMCP(ctx, name, s, logw, onToolsChanged)
+		cancel()
+		if err == nil {
+			return c, nil
+		}
+		if attempt >= retries {
+			break
+		}
+		delay := time.Second << attempt
+		fmt.Fprintf(logw, "[%s] connect failed (%v); retrying in %s\n", name, err, delay)
+		time.Sleep(delay)
+	}
+	if retries > 0 {
+		return nil, fmt.Errorf("%w (after %d attempts)", err, retries+1)
+	}
+	return nil, err
+}
+
+func connectMCP(ctx context.Context, name string, s MCPServer, logw io.Writer, onToolsChanged func()) (*mcpClient, error) {
+	c := newMCPClient(name, logw, onToolsChanged)
+	var err error
+	switch s.Type {
+	case "sse":
+		c.t, err = openSSE(ctx, c, s)
+	case "streamable-http":
+		c.t = &httpTransport{c: c, url: s.URL, headers: s.Headers, client: &http.Client{}}
+	default:
+		c.t, err = startStdio(c, s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.initialize(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// stdioTransport talks to a server process over its stdin/stdout.
+type stdioTransport struct {
+	cmd    *exec.Cmd
+	mu     sync.Mutex
+	w      io.WriteCloser
+	exited chan struct{}
+}
+
+func startStdio(c *mcpClient, s MCPServer) (*stdioTransport, error) {
+	name, logw := c.name, c.logw
+	cmd := exec.Command(s.Command, s.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range s.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = &prefixWriter{prefix: "[" + name + "] ", w: logw}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	t := &stdioTransport{cmd: cmd, w: stdin, exited: make(chan struct{})}
+	go c.readLoop(stdout)
+	go func() {
+		err := cmd.Wait()
+		if err == nil {
+			err = errors.New("server exited")
+		} else {
+			err = fmt.Errorf("server exited: %w", err)
+		}
+		close(t.exited)
+		c.finish(err)
+	}()
+	return t, nil
+}
+
+func (t *stdioTransport) send(_ context.Context, data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := t.w.Write(append(data, '\n'))
+	return err
+}
+
+// close closes stdin, which asks the server to exit; it is killed if it does not.
+func (t *stdioTransport) close() {
+	t.mu.Lock()
+	_ = t.w.Close()
+	t.mu.Unlock()
+	select {
+	case <-t.exited:
+	case <-time.After(2 * time.Second):
+		_ = t.cmd.Process.Kill()
+		<-t.exited
+	}
+}
+
+func (c *mcpClient) initialize(ctx context.Context) error {
+	var res struct {
+		ServerInfo struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"serverInfo"`
+	}
+	err := c.call(ctx, "initialize", map[string]any{
+		"protocolVersion": mcpProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "mcp-launch", "version": Version},
+	}, &res)
+	if err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	c.ServerName, c.ServerVersion = res.ServerInfo.Name, res.ServerInfo.Version
+	return c.notify(ctx, "notifications/initialized", nil)
+}
+
+// ListTools returns every tool, following pagination.
+func (c *mcpClient) ListTools(ctx context.Context) ([]mcpTool, error) {
+	var tools []mcpTool
+	cursor := ""
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var page struct {
+			Tools      []mcpTool `json:"tools"`
+			NextCursor string    `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, fmt.Errorf("tools/list: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+func (c *mcpClient) CallTool(ctx context.Context, name string, args json.RawMessage) (*mcpCallResult, error) {
+	var res mcpCallResult
+	if err := c.call(ctx, "tools/call", map[string]any{"name": name, "arguments": args}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Alive reports whether the session is still open (for stdio: the process is running).
+func (c *mcpClient) Alive() bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Close ends the session.
+func (c *mcpClient) Close() {
+	c.t.close()
+	c.finish(errors.New("session closed"))
+}
+
+func (c *mcpClient) call(ctx context.Context, method string, params, out any) error {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	ch := make(chan rpcMessage, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(ctx, rpcMessage{ID: json.RawMessage(strconv.FormatInt(id, 10)), Method: method, Params: mustJSON(params)}); err != nil {
+		return err
+	}
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if out == nil {
+			return nil
+		}
+		return json.Unmarshal(msg.Result, out)
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		_ = c.notify(context.Background(), "notifications/cancelled", map[string]any{"requestId": id, "reason": ctx.Err().Error()})
+		return ctx.Err()
+	}
+}
+
+func (c *mcpClient) notify(ctx context.Context, method string, params any) error {
+	msg := rpcMessage{Method: method}
+	if params != nil {
+		msg.Params = mustJSON(params)
+	}
+	return c.send(ctx, msg)
+}
+
+func (c *mcpClient) send(ctx context.Context, msg rpcMessage) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if !c.Alive() {
+		return c.err
+	}
+	return c.t.send(ctx, data)
+}
+
+func (c *mcpClient) readLoop(r io.Reader) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			c.dispatch(line)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *mcpClient) dispatch(line []byte) {
+	var msg rpcMessage
+	if err := json.Unmarshal(line, &msg); err != nil || msg.JSONRPC == "" {
+		// Servers that print to stdout break the protocol; keep the line for the log.
+		fmt.Fprintf(c.logw, "[%s] stdout: %s\n", c.name, line)
+		return
+	}
+	switch {
+	case msg.Method != "" && msg.ID != nil: // request from the server
+		reply := rpcMessage{ID: msg.ID}
+		switch msg.Method {
+		case "ping":
+			reply.Result = json.RawMessage("{}")
+		case "roots/list":
+			reply.Result = json.RawMessage(`{"roots":[]}`)
+		default:
+			reply.Error = &rpcError{Code: -32601, Message: "method not supported by mcp-launch: " + msg.Method}
+		}
+		_ = c.send(context.Background(), reply)
+	case msg.Method != "": // notification
+		if msg.Method == "notifications/tools/list_changed" && c.onToolsChanged != nil {
+			go c.onToolsChanged()
+		}
+	default: // response
+		id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+		if err != nil {
+			return
+		}
+		c.mu.Lock()
+		ch := c.pending[id]
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- msg
+		}
+	}
+}
+
+func (c *mcpClient) finish(err error) {
+	c.doneOnce.Do(func() {
+		c.err = err
+		close(c.done)
+	})
+}
+
+// prefixWriter prefixes every line written to w (for server stderr in the backend's log).
+type prefixWriter struct {
+	prefix string
+	w      io.Writer
+	mu     sync.Mutex
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.buf[:i])
+		p.buf = p.buf[i+1:]
+	}
+}
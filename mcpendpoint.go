@@ -0,0 +1,394 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ---------- aggregated MCP endpoint (/mcp) ----------
+//
+// Each front proxy also speaks MCP at /mcp (streamable HTTP, stateless: every POST carries
+// complete JSON-RPC messages and gets a JSON reply), so MCP clients can use the same stack, key
+// and tunnel as ChatGPT. It is built on the merged spec rather than on MCP sessions of its own:
+// each POST /<server>/<tool> operation is offered as the tool <server>__<tool>, with the
+// operation's request body as its input schema, and tools/call is sent to the backend like any
+// REST call. Tool filters and --auto-split parts therefore apply to /mcp unchanged.
+
+const (
+	mcpToolSep = "__"
+	// mcpCallTimeout bounds one tools/call (the default of `mcp-launch call --timeout`).
+	mcpCallTimeout = 2 * time.Minute
+)
+
+// Protocol versions /mcp accepts from clients; anything else is answered with ours.
+var mcpProtocolVersions = []string{"2025-06-18", mcpProtocolVersion, "2024-11-05"}
+
+// frontTool is one tool offered at /mcp and the backend route that serves it.
+type frontTool struct {
+	mcpTool
+	route string // "/<server>/<tool>"
+}
+
+// mcpToolIndex derives the /mcp tool list from a merged spec, sorted by name.
+func mcpToolIndex(spec []byte) []frontTool {
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil
+	}
+	var out []frontTool
+	for p, item := range doc.Paths {
+		raw, ok := item["post"]
+		server, tool, cut := strings.Cut(strings.TrimPrefix(p, "/"), "/")
+		if !ok || !cut || server == "" || tool == "" || strings.Contains(tool, "/") {
+			continue
+		}
+		var op struct {
+			Summary     string `json:"summary"`
+			Description string `json:"description"`
+			RequestBody struct {
+				Content map[string]struct {
+					Schema json.RawMessage `json:"schema"`
+				} `json:"content"`
+			} `json:"requestBody"`
+		}
+		if json.Unmarshal(raw, &op) != nil {
+			continue
+		}
+		desc := op.Description
+		if desc == "" {
+			desc = op.Summary
+		}
+		out = append(out, frontTool{
+			mcpTool: mcpTool{
+				Name:        server + mcpToolSep + tool,
+				Description: desc,
+				InputSchema: inputSchema(op.RequestBody.Content["application/json"].Schema, doc.Components.Schemas),
+			},
+			route: p,
+		})
+	}
+	slices.SortFunc(out, func(a, b frontTool) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+// inputSchema turns an OpenAPI request body schema into a self-contained JSON Schema: component
+// references become $defs, and a top-level $ref is inlined (MCP wants an object schema there).
+func inputSchema(schema json.RawMessage, components map[string]json.RawMessage) json.RawMessage {
+	var root any
+	if len(schema) == 0 || json.Unmarshal(schema, &root) != nil {
+		return json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	defs := map[string]any{}
+	var walk func(v any) any
+	walk = func(v any) any {
+		switch t := v.(type) {
+		case map[string]any:
+			for k, c := range t {
+				ref, isRef := c.(string)
+				name, isComponent := strings.CutPrefix(ref, "#/components/schemas/")
+				if k != "$ref" || !isRef || !isComponent {
+					t[k] = walk(c)
+					continue
+				}
+				t[k] = "#/$defs/" + name
+				if _, seen := defs[name]; !seen {
+					defs[name] = map[string]any{} // placeholder while walking recursive schemas
+					var d any
+					if json.Unmarshal(components[name], &d) == nil {
+						defs[name] = walk(d)
+					}
+				}
+			}
+		case []any:
+			for i, c := range t {
+				t[i] = walk(c)
+			}
+		}
+		return v
+	}
+	root = walk(root)
+
+	m, ok := root.(map[string]any)
+	if !ok {
+		return json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	if ref, ok := m["$ref"].(string); ok && len(m) == 1 {
+		name := strings.TrimPrefix(ref, "#/$defs/")
+		if d, ok := defs[name].(map[string]any); ok {
+			m = make(map[string]any, len(d)+1)
+			for k, v := range d {
+				m[k] = v
+			}
+			delete(defs, name)
+			m["$defs"] = defs
+			if bytes.Contains(mustJSON(m), []byte(`"`+ref+`"`)) { // still referenced (recursive model)
+				defs[name] = d
+			}
+		}
+	}
+	if _, ok := m["type"]; !ok {
+		m["type"] = "object"
+	}
+	delete(m, "$defs")
+	if len(defs) > 0 {
+		m["$defs"] = defs
+	}
+	return mustJSON(m)
+}
+
+// mcpTools lists the tools /mcp offers right now (routes hidden by filters are left out).
+func (f *frontProxy) mcpTools() []frontTool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make([]frontTool, 0, len(f.tools))
+	for _, t := range f.tools {
+		if !f.filtered[routeKey(http.MethodPost, t.route)] {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// EnableMCP serves the aggregated MCP endpoint at /mcp. Call before Serve.
+func (f *frontProxy) EnableMCP() {
+	f.mux.HandleFunc("/mcp", f.handleMCP)
+}
+
+func (f *frontProxy) handleMCP(w http.ResponseWriter, r *http.Request) {
+	if !f.keyAccepted(requestKey(r)) {
+		writeUnauthorized(w)
+		return
+	}
+	if r.Method != http.MethodPost {
+		// Stateless: no server-initiated stream (GET) and no session to end (DELETE).
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"detail": "Method Not Allowed"})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 4<<20))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, rpcMessage{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: -32700, Message: err.Error()}})
+		return
+	}
+	body = bytes.TrimSpace(body)
+	batch := len(body) > 0 && body[0] == '['
+	var msgs []rpcMessage
+	if batch {
+		err = json.Unmarshal(body, &msgs)
+	} else {
+		var msg rpcMessage
+		err = json.Unmarshal(body, &msg)
+		msgs = []rpcMessage{msg}
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, rpcMessage{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: -32700, Message: "parse error: " + err.Error()}})
+		return
+	}
+
+	var replies []rpcMessage
+	for _, msg := range msgs {
+		if reply := f.mcpDispatch(r, msg); reply != nil {
+			replies = append(replies, *reply)
+		}
+	}
+	switch {
+	case len(replies) == 0: // only notifications or responses
+		w.WriteHeader(http.StatusAccepted)
+	case batch:
+		writeJSON(w, http.StatusOK, replies)
+	default:
+		writeJSON(w, http.StatusOK, replies[0])
+	}
+}
+
+// requestKey is the client's key: X-API-Key, or for MCP clients (usually configured with a
+// bearer token) Authorization: Bearer.
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(bearer)
+	}
+	return ""
+}
+
+// mcpDispatch answers one JSON-RPC message of the POST r; notifications (no "id" member) and
+// responses get no reply. MCP request ids are strings or integers, so a request with "id": null
+// (or any other id) is answered as an invalid request rather than dropped as a notification.
+func (f *frontProxy) mcpDispatch(r *http.Request, msg rpcMessage) *rpcMessage {
+	if msg.Method == "" || msg.ID == nil {
+		return nil
+	}
+	reply := &rpcMessage{JSONRPC: "2.0", ID: msg.ID}
+	if !validRequestID(msg.ID) {
+		reply.ID = json.RawMessage("null")
+		reply.Error = &rpcError{Code: -32600, Message: "invalid request: id must be a string or an integer"}
+		return reply
+	}
+	switch msg.Method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(msg.Params, &p)
+		version := mcpProtocolVersion
+		if slices.Contains(mcpProtocolVersions, p.ProtocolVersion) {
+			version = p.ProtocolVersion
+		}
+		reply.Result = mustJSON(map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "mcp-launch/" + f.name, "version": Version},
+			"instructions":    "Tools are named <server>" + mcpToolSep + "<tool> after the MCP servers of stack " + f.name + ".",
+		})
+	case "ping":
+		reply.Result = json.RawMessage("{}")
+	case "tools/list":
+		tools := f.mcpTools()
+		list := make([]mcpTool, len(tools))
+		for i, t := range tools {
+			list[i] = t.mcpTool
+		}
+		reply.Result = mustJSON(map[string]any{"tools": list})
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			reply.Error = &rpcError{Code: -32602, Message: "invalid params: " + err.Error()}
+			break
+		}
+		// One snapshot: a reload between lookup and use must not shift the index.
+		tools := f.mcpTools()
+		i := slices.IndexFunc(tools, func(t frontTool) bool { return t.Name == p.Name })
+		if i < 0 {
+			reply.Error = &rpcError{Code: -32602, Message: "unknown tool: " + p.Name}
+			break
+		}
+		reply.Result = mustJSON(f.callRoute(r, tools[i].route, p.Arguments))
+	default:
+		reply.Error = &rpcError{Code: -32601, Message: "method not found: " + msg.Method}
+	}
+	return reply
+}
+
+// validRequestID reports whether a JSON-RPC id is a string or an integer.
+func validRequestID(id json.RawMessage) bool {
+	var v any
+	if json.Unmarshal(id, &v) != nil {
+		return false
+	}
+	switch n := v.(type) {
+	case string:
+		return true
+	case float64:
+		return n == float64(int64(n))
+	}
+	return false
+}
+
+// callRoute runs one tool as a POST to its REST route through the proxy's own handler, so the
+// call is authorized, filtered, access-logged and counted in metrics like any other, and wraps
+// the reply as a tool result. Backend failures become tool errors (isError) so the model sees
+// them, as with a real server. The client's key and forwarding headers are carried over from the
+// /mcp request r.
+func (f *frontProxy) callRoute(r *http.Request, route string, args json.RawMessage) mcpCallResult {
+	if len(bytes.TrimSpace(args)) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+	toolError := func(text string) mcpCallResult {
+		return mcpCallResult{Content: []mcpContent{{Type: "text", Text: text}}, IsError: true}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), mcpCallTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, route, bytes.NewReader(args))
+	if err != nil {
+		return toolError(err.Error())
+	}
+	req.RemoteAddr, req.Host = r.RemoteAddr, r.Host
+	for _, h := range []string{"CF-Connecting-IP", "CF-IPCountry", "CF-Ray", "X-Forwarded-For", "User-Agent"} {
+		if v := r.Header.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+	if key := requestKey(r); key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp := &bufferedResponse{header: http.Header{}}
+	f.srv.Handler.ServeHTTP(resp, req)
+
+	body := resp.body.Bytes()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return toolError(fmt.Sprintf("timed out after %s", mcpCallTimeout))
+	case resp.status == http.StatusBadGateway && len(bytes.TrimSpace(body)) == 0:
+		return toolError("backend unreachable")
+	case resp.status >= 300:
+		var e struct {
+			Detail json.RawMessage `json:"detail"`
+		}
+		detail := string(body)
+		if json.Unmarshal(body, &e) == nil && len(e.Detail) > 0 {
+			detail = jsonText(e.Detail)
+		}
+		return toolError(fmt.Sprintf("%d %s: %s", resp.status, http.StatusText(resp.status), detail))
+	}
+	res := mcpCallResult{Content: []mcpContent{{Type: "text", Text: jsonText(body)}}}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed) {
+		res.StructuredContent = trimmed
+	}
+	return res
+}
+
+// bufferedResponse collects a response served in-process (see callRoute).
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	if b.status == 0 {
+		b.status = code
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+// jsonText renders a JSON value as text: strings unquoted, anything else as compact JSON.
+func jsonText(b []byte) string {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		return s
+	}
+	var buf bytes.Buffer
+	if json.Compact(&buf, b) == nil {
+		return buf.String()
+	}
+	return string(b)
+}
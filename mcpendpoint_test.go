@@ -0,0 +1,240 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestInputSchema(t *testing.T) {
+	components := map[string]json.RawMessage{
+		"Form":  json.RawMessage(`{"type":"object","properties":{"opt":{"$ref":"#/components/schemas/Opt"}},"required":["opt"]}`),
+		"Opt":   json.RawMessage(`{"type":"string","enum":["a","b"]}`),
+		"Tree":  json.RawMessage(`{"type":"object","properties":{"kids":{"type":"array","items":{"$ref":"#/components/schemas/Tree"}}}}`),
+		"Empty": json.RawMessage(`{"title":"Empty"}`),
+	}
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{"no body", ``, `{"type":"object","properties":{}}`},
+		{"not an object", `[1]`, `{"type":"object","properties":{}}`},
+		{"inline object", `{"type":"object","properties":{"x":{"type":"integer"}}}`, `{"type":"object","properties":{"x":{"type":"integer"}}}`},
+		{"top-level ref is inlined", `{"$ref":"#/components/schemas/Form"}`,
+			`{"type":"object","properties":{"opt":{"$ref":"#/$defs/Opt"}},"required":["opt"],"$defs":{"Opt":{"type":"string","enum":["a","b"]}}}`},
+		{"recursive ref keeps its def", `{"$ref":"#/components/schemas/Tree"}`,
+			`{"type":"object","properties":{"kids":{"type":"array","items":{"$ref":"#/$defs/Tree"}}},"$defs":{"Tree":{"type":"object","properties":{"kids":{"type":"array","items":{"$ref":"#/$defs/Tree"}}}}}}`},
+		{"type defaults to object", `{"$ref":"#/components/schemas/Empty"}`, `{"title":"Empty","type":"object"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got, want any
+			if err := json.Unmarshal(inputSchema(json.RawMessage(tt.schema), components), &got); err != nil {
+				t.Fatal(err)
+			}
+			_ = json.Unmarshal([]byte(tt.want), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("inputSchema = %s\nwant          %s", mustJSON(got), tt.want)
+			}
+		})
+	}
+}
+
+const testMCPSpec = `{
+  "openapi": "3.1.0",
+  "paths": {
+    "/srv/echo":   {"post": {"operationId": "srv__echo", "summary": "Echo",
+                    "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Echo"}}}}}},
+    "/srv/fail":   {"post": {"operationId": "srv__fail", "summary": "Fail"}},
+    "/srv/hidden": {"post": {"operationId": "srv__hidden", "summary": "Hidden"}}
+  },
+  "components": {"schemas": {"Echo": {"type": "object", "properties": {"text": {"type": "string"}}, "required": ["text"]}}}
+}`
+
+// newTestMCPProxy starts a stand-in backend (which insists on the upstream key) and a front
+// proxy for it with the access log on, key "client-key" and /srv/hidden filtered out. Metrics
+// are global, so each test uses its own stack name.
+func newTestMCPProxy(t *testing.T, stack string) *frontProxy {
+	t.Helper()
+	chdirTemp(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "upstream-key" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"detail": "bad key"})
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/srv/echo":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(body)
+		default:
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"detail": "field required"})
+		}
+	}))
+	t.Cleanup(backend.Close)
+	u, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	f := newFrontProxy(stack, 0, port, "upstream-key")
+	f.EnableAccessLog(accessLogConfig{})
+	f.EnableMCP()
+	f.SetOpenAPI([]byte(testMCPSpec))
+	f.SetKeys([]string{"client-key"}, false)
+	f.SetFiltered([]string{routeKey("post", "/srv/hidden")})
+	return f
+}
+
+func TestMCPDispatch(t *testing.T) {
+	f := newTestMCPProxy(t, "mcpdispatch")
+	tests := []struct {
+		name      string
+		msg       string
+		wantReply bool
+		wantID    string
+		wantCode  int    // JSON-RPC error code, 0 for a result
+		wantIn    string // substring of the result
+	}{
+		{"notification", `{"jsonrpc":"2.0","method":"notifications/initialized"}`, false, "", 0, ""},
+		{"response", `{"jsonrpc":"2.0","id":7,"result":{}}`, false, "", 0, ""},
+		{"null id", `{"jsonrpc":"2.0","id":null,"method":"ping"}`, true, "null", -32600, ""},
+		{"object id", `{"jsonrpc":"2.0","id":{"a":1},"method":"ping"}`, true, "null", -32600, ""},
+		{"fractional id", `{"jsonrpc":"2.0","id":1.5,"method":"ping"}`, true, "null", -32600, ""},
+		{"ping", `{"jsonrpc":"2.0","id":1,"method":"ping"}`, true, "1", 0, "{}"},
+		{"string id", `{"jsonrpc":"2.0","id":"a","method":"ping"}`, true, `"a"`, 0, "{}"},
+		{"initialize echoes a known version", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`, true, "1", 0, `"protocolVersion":"2024-11-05"`},
+		{"initialize answers ours otherwise", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`, true, "1", 0, `"protocolVersion":"` + mcpProtocolVersion + `"`},
+		{"tools/list hides filtered routes", `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`, true, "2", 0, `"name":"srv__echo"`},
+		{"call", `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"srv__echo","arguments":{"text":"hi"}}}`, true, "3", 0, `"structuredContent":{"text":"hi"}`},
+		{"call error", `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"srv__fail"}}`, true, "4", 0, `"isError":true`},
+		{"call filtered", `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"srv__hidden"}}`, true, "5", -32602, ""},
+		{"unknown method", `{"jsonrpc":"2.0","id":6,"method":"resources/list"}`, true, "6", -32601, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msg rpcMessage
+			if err := json.Unmarshal([]byte(tt.msg), &msg); err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			r.Header.Set("Authorization", "Bearer client-key")
+			reply := f.mcpDispatch(r, msg)
+			if (reply != nil) != tt.wantReply {
+				t.Fatalf("reply = %v, want reply: %v", reply, tt.wantReply)
+			}
+			if reply == nil {
+				return
+			}
+			if string(reply.ID) != tt.wantID {
+				t.Errorf("id = %s, want %s", reply.ID, tt.wantID)
+			}
+			switch {
+			case tt.wantCode != 0 && (reply.Error == nil || reply.Error.Code != tt.wantCode):
+				t.Errorf("error = %+v, want code %d", reply.Error, tt.wantCode)
+			case tt.wantCode == 0 && reply.Error != nil:
+				t.Errorf("unexpected error %+v", reply.Error)
+			case !strings.Contains(string(reply.Result), tt.wantIn):
+				t.Errorf("result = %s, want it to contain %s", reply.Result, tt.wantIn)
+			}
+			if tt.name == "tools/list hides filtered routes" && strings.Contains(string(reply.Result), "srv__hidden") {
+				t.Errorf("filtered tool listed: %s", reply.Result)
+			}
+		})
+	}
+}
+
+// Tool calls over /mcp go through the proxy's handler, so they show up per tool in the access
+// log and metrics rather than only as POST /mcp.
+func TestMCPToolCallIsAudited(t *testing.T) {
+	f := newTestMCPProxy(t, "mcpaudit")
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"srv__echo","arguments":{"text":"hi"}}}`
+	r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+	r.Header.Set("X-API-Key", "client-key")
+	r.Header.Set("CF-Connecting-IP", "203.0.113.9")
+	w := httptest.NewRecorder()
+	f.srv.Handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"text":"hi"`) {
+		t.Fatalf("POST /mcp = %d %s", w.Code, w.Body)
+	}
+
+	data, err := os.ReadFile(accessLogPath("mcpaudit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tool, mcp *accessRecord
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var rec accessRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatal(err)
+		}
+		switch rec.Path {
+		case "/srv/echo":
+			tool = &rec
+		case "/mcp":
+			mcp = &rec
+		}
+	}
+	if tool == nil || mcp == nil {
+		t.Fatalf("access log lacks the tool call or the /mcp request:\n%s", data)
+	}
+	if tool.OperationID != "srv__echo" || tool.Server != "srv" || tool.Status != 200 || tool.ClientIP != "203.0.113.9" || tool.KeyID != keyID("client-key") {
+		t.Errorf("tool record = %+v", *tool)
+	}
+
+	var sb strings.Builder
+	metrics.writeTo(&sb, "mcpaudit")
+	if want := `mcp_launch_http_requests_total{stack="mcpaudit",server="srv",operation="srv__echo",code="200"} 1`; !strings.Contains(sb.String(), want) {
+		t.Errorf("metrics lack %s:\n%s", want, sb.String())
+	}
+}
+
+// Tools are looked up by name in the list current at call time, so a reload that adds, drops
+// or reorders tools never runs a different tool than the one named.
+func TestMCPCallAfterToolsChange(t *testing.T) {
+	f := newTestMCPProxy(t, "mcpreload")
+	call := func(name string) *rpcMessage {
+		msg := rpcMessage{JSONRPC: "2.0", ID: json.RawMessage("1"), Method: "tools/call",
+			Params: mustJSON(map[string]any{"name": name, "arguments": map[string]string{"text": "hi"}})}
+		r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		r.Header.Set("X-API-Key", "client-key")
+		return f.mcpDispatch(r, msg)
+	}
+	if reply := call("srv__fail"); reply.Error != nil {
+		t.Fatalf("srv__fail before reload: %+v", reply.Error)
+	}
+
+	f.SetOpenAPI([]byte(`{
+  "openapi": "3.1.0",
+  "paths": {
+    "/srv/aaa":  {"post": {"operationId": "srv__aaa", "summary": "Sorts first"}},
+    "/srv/echo": {"post": {"operationId": "srv__echo", "summary": "Echo"}}
+  }
+}`))
+	tests := []struct {
+		tool     string
+		wantCode int
+		wantIn   string
+	}{
+		{"srv__echo", 0, `"structuredContent":{"text":"hi"}`},
+		{"srv__aaa", 0, `"isError":true`},
+		{"srv__fail", -32602, ""},
+	}
+	for _, tt := range tests {
+		reply := call(tt.tool)
+		switch {
+		case tt.wantCode != 0 && (reply.Error == nil || reply.Error.Code != tt.wantCode):
+			t.Errorf("%s: error = %+v, want code %d", tt.tool, reply.Error, tt.wantCode)
+		case tt.wantCode == 0 && (reply.Error != nil || !strings.Contains(string(reply.Result), tt.wantIn)):
+			t.Errorf("%s: reply = %s %+v, want %s", tt.tool, reply.Result, reply.Error, tt.wantIn)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ---------- Prometheus metrics ----------
+
+// Tool calls can be slow (LLM-driven servers, git clones), so buckets reach a minute.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+type requestLabels struct{ stack, server, operation, code string }
+type latencyLabels struct{ stack, server, operation string }
+
+type histogram struct {
+	counts []uint64 // per bucket, non-cumulative
+	sum    float64
+	count  uint64
+}
+
+// metricsRegistry holds request metrics for every stack in this process; process gauges
+// are read from the live instances at scrape time.
+type metricsRegistry struct {
+	mu        sync.Mutex
+	requests  map[requestLabels]uint64
+	latencies map[latencyLabels]*histogram
+	stacks    func() []Instance
+}
+
+var metrics = &metricsRegistry{
+	requests:  map[requestLabels]uint64{},
+	latencies: map[latencyLabels]*histogram{},
+}
+
+// SetStacks registers the snapshot function used for process gauges.
+func (m *metricsRegistry) SetStacks(fn func() []Instance) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stacks = fn
+}
+
+func (m *metricsRegistry) observe(stack, server, operation string, code int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestLabels{stack, server, operation, strconv.Itoa(code)}]++
+	k := latencyLabels{stack, server, operation}
+	h := m.latencies[k]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		m.latencies[k] = h
+	}
+	secs := d.Seconds()
+	for i, b := range latencyBuckets {
+		if secs <= b {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += secs
+	h.count++
+}
+
+// writeTo renders the Prometheus text format; stack != "" limits output to one stack.
+func (m *metricsRegistry) writeTo(w io.Writer, stack string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP mcp_launch_http_requests_total Requests handled by the front proxy.")
+	fmt.Fprintln(w, "# TYPE mcp_launch_http_requests_total counter")
+	reqKeys := make([]requestLabels, 0, len(m.requests))
+	for k := range m.requests {
+		if stack == "" || k.stack == stack {
+			reqKeys = append(reqKeys, k)
+		}
+	}
+	slices.SortFunc(reqKeys, func(a, b requestLabels) int {
+		return strings.Compare(a.stack+a.server+a.operation+a.code, b.stack+b.server+b.operation+b.code)
+	})
+	for _, k := range reqKeys {
+		fmt.Fprintf(w, "mcp_launch_http_requests_total{%s,code=%q} %d\n", opLabels(k.stack, k.server, k.operation), k.code, m.requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP mcp_launch_http_request_errors_total Requests answered with a 5xx status.")
+	fmt.Fprintln(w, "# TYPE mcp_launch_http_request_errors_total counter")
+	errs := map[latencyLabels]uint64{}
+	for _, k := range reqKeys {
+		if strings.HasPrefix(k.code, "5") {
+			errs[latencyLabels{k.stack, k.server, k.operation}] += m.requests[k]
+		}
+	}
+	for _, k := range sortedLatencyKeys(errs) {
+		fmt.Fprintf(w, "mcp_launch_http_request_errors_total{%s} %d\n", opLabels(k.stack, k.server, k.operation), errs[k])
+	}
+
+	fmt.Fprintln(w, "# HELP mcp_launch_http_request_duration_seconds Front proxy request latency.")
+	fmt.Fprintln(w, "# TYPE mcp_launch_http_request_duration_seconds histogram")
+	lat := map[latencyLabels]uint64{}
+	for k, h := range m.latencies {
+		if stack == "" || k.stack == stack {
+			lat[k] = h.count
+		}
+	}
+	for _, k := range sortedLatencyKeys(lat) {
+		h := m.latencies[k]
+		labels := opLabels(k.stack, k.server, k.operation)
+		var cum uint64
+		for i, b := range latencyBuckets {
+			cum += h.counts[i]
+			fmt.Fprintf(w, "mcp_launch_http_request_duration_seconds_bucket{%s,le=%q} %d\n", labels, strconv.FormatFloat(b, 'g', -1, 64), cum)
+		}
+		fmt.Fprintf(w, "mcp_launch_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(w, "mcp_launch_http_request_duration_seconds_sum{%s} %g\n", labels, h.sum)
+		fmt.Fprintf(w, "mcp_launch_http_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+
+	if m.stacks == nil {
+		return
+	}
+	insts := m.stacks()
+	gauge := func(name, help, typ string, value func(Instance) (string, float64, bool)) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		for _, inst := range insts {
+			if stack != "" && inst.Name != stack {
+				continue
+			}
+			if extra, v, ok := value(inst); ok {
+				fmt.Fprintf(w, "%s{stack=%s%s} %g\n", name, labelValue(inst.Name), extra, v)
+			}
+		}
+	}
+	gauge("mcp_launch_mcpo_up", "Whether the stack's mcpo process is running.", "gauge", func(inst Instance) (string, float64, bool) {
+		return "", boolGauge(inst.McpoPID > 0), inst.SplitOf == ""
+	})
+	gauge("mcp_launch_mcpo_restarts_total", "mcpo restarts during this run.", "counter", func(inst Instance) (string, float64, bool) {
+		return "", float64(inst.Restarts), inst.SplitOf == ""
+	})
+	gauge("mcp_launch_tunnel_up", "Whether the stack's cloudflared tunnel process is running.", "gauge", func(inst Instance) (string, float64, bool) {
+		return ",mode=" + labelValue(inst.TunnelMode), boolGauge(inst.CloudflaredPID > 0), inst.TunnelMode == "quick" || inst.TunnelMode == "named"
+	})
+	gauge("mcp_launch_openapi_operations", "Operations in the stack's merged OpenAPI spec.", "gauge", func(inst Instance) (string, float64, bool) {
+		return "", float64(inst.OperationCount), true
+	})
+}
+
+func sortedLatencyKeys(m map[latencyLabels]uint64) []latencyLabels {
+	keys := make([]latencyLabels, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b latencyLabels) int {
+		return strings.Compare(a.stack+a.server+a.operation, b.stack+b.server+b.operation)
+	})
+	return keys
+}
+
+func opLabels(stack, server, operation string) string {
+	return "stack=" + labelValue(stack) + ",server=" + labelValue(server) + ",operation=" + labelValue(operation)
+}
+
+// labelEscaper covers the only escapes the text format knows: \\, \" and \n.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelValue quotes a label value. Go's %q would also emit \t, \x.. and \u.. escapes, which
+// Prometheus rejects.
+func labelValue(s string) string {
+	return `"` + labelEscaper.Replace(s) + `"`
+}
+
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// metricLabels bounds label cardinality: unknown paths collapse to operation="other".
+func metricLabels(f *frontProxy, r *http.Request) (server, operation string) {
+	p := path.Clean("/" + r.URL.Path)
+	if op := f.operationFor(r.Method, p); op != "" {
+		return serverFromPath(p), op
+	}
+	switch p {
+	case "/openapi.json", "/healthz", "/readyz", "/metrics", "/mcp":
+		return "", strings.TrimPrefix(p, "/")
+	}
+	return "", "other"
+}
+
+// withMetrics records request count and latency for every request through the proxy.
+func (f *frontProxy) withMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &accessRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		server, op := metricLabels(f, r)
+		metrics.observe(f.name, server, op, rec.status, time.Since(start))
+	})
+}
+
+// isLocalRequest is true for loopback callers that did not come through Cloudflare.
+func isLocalRequest(r *http.Request) bool {
+	if r.Header.Get("CF-Connecting-IP") != "" || r.Header.Get("CF-Ray") != "" {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// EnableMetrics serves this stack's /metrics: open to local callers, otherwise X-API-Key is required.
+func (f *frontProxy) EnableMetrics() {
+	f.mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if !isLocalRequest(r) && !f.authorized(r) {
+			writeUnauthorized(w)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		metrics.writeTo(w, f.name)
+	})
+}
+
+// startMetricsServer serves /metrics for all stacks on a separate admin address.
+// A bare port (":9464" or "9464") binds to 127.0.0.1 only.
+func startMetricsServer(addr string) (*http.Server, error) {
+	if !strings.Contains(addr, ":") {
+		addr = ":" + addr
+	}
+	if strings.HasPrefix(addr, ":") {
+		addr = "127.0.0.1" + addr
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		metrics.writeTo(w, "")
+	})
+	srv := &http.Server{Handler: mux}
+	go func() { _ = srv.Serve(ln) }()
+	return srv, nil
+}
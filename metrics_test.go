@@ -0,0 +1,141 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	m := &metricsRegistry{requests: map[requestLabels]uint64{}, latencies: map[latencyLabels]*histogram{}}
+	for _, d := range []time.Duration{3 * time.Millisecond, 20 * time.Millisecond, 20 * time.Millisecond, 2 * time.Second, 90 * time.Second} {
+		m.observe("s1", "srv", "srv__get", 200, d)
+	}
+	m.observe("s1", "srv", "srv__get", 502, time.Millisecond)
+	m.observe("s1", "srv", "srv__get", 503, time.Millisecond)
+	m.observe("s1", "we\"ird", "a\\b\nc\td", 200, time.Millisecond)
+	m.observe("s2", "srv", "srv__get", 200, time.Millisecond)
+	m.SetStacks(func() []Instance {
+		return []Instance{
+			{Name: "s1", McpoPID: 42, Restarts: 2, TunnelMode: "quick", OperationCount: 7},
+			{Name: "s1-2", SplitOf: "s1", TunnelMode: "none", OperationCount: 3},
+			{Name: "s2", TunnelMode: "named", CloudflaredPID: 9},
+		}
+	})
+
+	var b strings.Builder
+	m.writeTo(&b, "s1")
+	out := b.String()
+	get := `stack="s1",server="srv",operation="srv__get"`
+	for _, want := range []string{
+		"# HELP mcp_launch_http_requests_total Requests handled by the front proxy.\n# TYPE mcp_launch_http_requests_total counter\n",
+		"# TYPE mcp_launch_http_request_errors_total counter\n",
+		"# TYPE mcp_launch_http_request_duration_seconds histogram\n",
+		"# TYPE mcp_launch_mcpo_up gauge\n",
+		"# TYPE mcp_launch_mcpo_restarts_total counter\n",
+		"mcp_launch_http_requests_total{" + get + `,code="200"} 5` + "\n",
+		"mcp_launch_http_requests_total{" + get + `,code="502"} 1` + "\n",
+		"mcp_launch_http_request_errors_total{" + get + "} 2\n",
+		// Buckets are cumulative; 90s only shows in +Inf.
+		"mcp_launch_http_request_duration_seconds_bucket{" + get + `,le="0.005"} 3` + "\n",
+		"mcp_launch_http_request_duration_seconds_bucket{" + get + `,le="0.01"} 3` + "\n",
+		"mcp_launch_http_request_duration_seconds_bucket{" + get + `,le="0.025"} 5` + "\n",
+		"mcp_launch_http_request_duration_seconds_bucket{" + get + `,le="2.5"} 6` + "\n",
+		"mcp_launch_http_request_duration_seconds_bucket{" + get + `,le="60"} 6` + "\n",
+		"mcp_launch_http_request_duration_seconds_bucket{" + get + `,le="+Inf"} 7` + "\n",
+		"mcp_launch_http_request_duration_seconds_count{" + get + "} 7\n",
+		// Only \\, \" and \n are escaped; a tab stays as it is.
+		`mcp_launch_http_requests_total{stack="s1",server="we\"ird",operation="a\\b\nc` + "\t" + `d",code="200"} 1` + "\n",
+		`mcp_launch_mcpo_up{stack="s1"} 1` + "\n",
+		`mcp_launch_mcpo_restarts_total{stack="s1"} 2` + "\n",
+		`mcp_launch_tunnel_up{stack="s1",mode="quick"} 0` + "\n",
+		`mcp_launch_openapi_operations{stack="s1"} 7` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+	sumLine := regexp.MustCompile(`(?m)^mcp_launch_http_request_duration_seconds_sum\{` + regexp.QuoteMeta(get) + `\} (\S+)$`).FindStringSubmatch(out)
+	if sumLine == nil {
+		t.Error("missing _sum for srv__get")
+	} else if sum, _ := strconv.ParseFloat(sumLine[1], 64); math.Abs(sum-92.045) > 1e-9 {
+		t.Errorf("_sum = %s, want 92.045", sumLine[1])
+	}
+	for _, unwanted := range []string{`stack="s2"`, `stack="s1-2"`} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("output for s1 contains %s", unwanted)
+		}
+	}
+
+	// Every line is a comment or a sample the text format accepts, and every sample's family
+	// was declared before it.
+	sample := regexp.MustCompile(`^([a-z_]+)(\{([a-z_]+="([^"\\\n]|\\[\\"n])*",?)*\})? [-+0-9.e]+$`)
+	declared := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if name, ok := strings.CutPrefix(line, "# TYPE "); ok {
+			declared[strings.Fields(name)[0]] = true
+			continue
+		}
+		if strings.HasPrefix(line, "# HELP ") {
+			continue
+		}
+		sm := sample.FindStringSubmatch(line)
+		if sm == nil {
+			t.Errorf("malformed sample line %q", line)
+			continue
+		}
+		family := sm[1]
+		for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+			if base, ok := strings.CutSuffix(family, suffix); ok && declared[base] {
+				family = base
+			}
+		}
+		if !declared[family] {
+			t.Errorf("sample before its # TYPE: %q", line)
+		}
+	}
+
+	b.Reset()
+	m.writeTo(&b, "")
+	if all := b.String(); !strings.Contains(all, `stack="s2"`) || !strings.Contains(all, `mcp_launch_tunnel_up{stack="s2",mode="named"} 1`) {
+		t.Errorf("unfiltered output misses s2:\n%s", all)
+	}
+}
+
+func TestFrontProxyMetricsEndpoint(t *testing.T) {
+	f := newTestMCPProxy(t, "metricsendpoint")
+	f.EnableMetrics()
+	call := func(method, path, key, remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"x":1}`))
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		if remote != "" {
+			req.RemoteAddr = remote
+		}
+		rec := httptest.NewRecorder()
+		f.srv.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := call(http.MethodPost, "/srv/echo", "client-key", ""); rec.Code != http.StatusOK {
+		t.Fatalf("echo: %d %s", rec.Code, rec.Body)
+	}
+	if rec := call(http.MethodGet, "/metrics", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("remote /metrics without a key: %d, want 401", rec.Code)
+	}
+	for _, rec := range []*httptest.ResponseRecorder{call(http.MethodGet, "/metrics", "client-key", ""), call(http.MethodGet, "/metrics", "", "127.0.0.1:5000")} {
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+			t.Fatalf("/metrics: %d %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		if want := `mcp_launch_http_requests_total{stack="metricsendpoint",server="srv",operation="srv__echo",code="200"} 1`; !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("/metrics misses %s:\n%s", want, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ---------- process identity ----------
+//
+// A PID alone does not identify a process: after a reboot (or enough churn) the PID in
+// state.json can belong to something unrelated. Next to each PID we record the process's start
+// time and command line, and only signal a PID whose start time still matches.
+
+// procStamp identifies one process incarnation. On Linux the kernel's raw start time (clock
+// ticks since boot) and boot ID decide, since a wall-clock start derived from btime shifts with
+// clock adjustments; elsewhere the start time printed by ps does.
+type procStamp struct {
+	Start  string `json:"start"`             // start time as reported by the OS (for display)
+	Ticks  int64  `json:"ticks,omitempty"`   // Linux: /proc/<pid>/stat starttime
+	BootID string `json:"boot_id,omitempty"` // Linux: /proc/sys/kernel/random/boot_id
+	Cmd    string `json:"cmd,omitempty"`     // command line when recorded (for display)
+}
+
+// sameProcess reports whether two stamps describe the same process incarnation.
+func (a procStamp) sameProcess(b procStamp) bool {
+	if a.Ticks != 0 && b.Ticks != 0 && a.BootID != "" && b.BootID != "" {
+		return a.Ticks == b.Ticks && a.BootID == b.BootID
+	}
+	return a.Start == b.Start
+}
+
+// Process states reported by checkProc.
+const (
+	procRunning    = "running"
+	procDead       = "not running"
+	procMismatch   = "reused" // the PID now belongs to another process
+	procUnverified = "unverified"
+)
+
+// readProcStamp returns the identity of a live process, or ok=false if there is none.
+func readProcStamp(pid int) (procStamp, bool) {
+	if pid <= 0 {
+		return procStamp{}, false
+	}
+	if st, ok, handled := readLinuxProcStamp(pid); handled {
+		return st, ok
+	}
+	out, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil || strings.TrimSpace(string(out)) == "" {
+		return procStamp{}, false
+	}
+	st := procStamp{Start: strings.Join(strings.Fields(string(out)), " ")}
+	if cmd, err := exec.Command("ps", "-o", "args=", "-p", strconv.Itoa(pid)).Output(); err == nil {
+		st.Cmd = scrubCmd(string(cmd))
+	}
+	return st, true
+}
+
+// readLinuxProcStamp reads /proc; handled is false where there is no /proc.
+func readLinuxProcStamp(pid int) (st procStamp, ok, handled bool) {
+	btime, err := bootTime()
+	if err != nil {
+		return procStamp{}, false, false
+	}
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return procStamp{}, false, true
+	}
+	// Fields after the parenthesized comm: state is field 3, starttime field 22.
+	i := strings.LastIndexByte(string(data), ')')
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) < 20 || fields[0] == "Z" || fields[0] == "X" {
+		return procStamp{}, false, true
+	}
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return procStamp{}, false, true
+	}
+	const clockTicks = 100 // USER_HZ on every Linux platform Go supports
+	start := time.Unix(btime+ticks/clockTicks, (ticks%clockTicks)*int64(time.Second/clockTicks))
+	st.Start = start.UTC().Format("2006-01-02T15:04:05.00Z07:00")
+	st.Ticks = ticks
+	if id, err := os.ReadFile("/proc/sys/kernel/random/boot_id"); err == nil {
+		st.BootID = strings.TrimSpace(string(id))
+	}
+	if cmd, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid)); err == nil {
+		st.Cmd = scrubCmd(strings.ReplaceAll(string(cmd), "\x00", " "))
+	}
+	return st, true, true
+}
+
+func bootTime() (int64, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "btime "); ok {
+			return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("no btime in /proc/stat")
+}
+
+// stampProcess records the identity of a process we just started (nil if it is already gone).
+func stampProcess(pid int) *procStamp {
+	st, ok := readProcStamp(pid)
+	if !ok {
+		return nil
+	}
+	return &st
+}
+
+// checkProc compares a recorded PID with what is running now. The start time decides (see
+// sameProcess); the command line may legitimately change when a wrapper script execs the real
+// binary.
+func checkProc(pid int, rec *procStamp) (string, procStamp) {
+	cur, ok := readProcStamp(pid)
+	switch {
+	case !ok:
+		return procDead, cur
+	case rec == nil:
+		return procUnverified, cur
+	case !cur.sameProcess(*rec):
+		return procMismatch, cur
+	}
+	return procRunning, cur
+}
+
+// describeProc renders a PID and its state for status/down output.
+func describeProc(pid int, rec *procStamp) string {
+	state, cur := checkProc(pid, rec)
+	switch state {
+	case procMismatch:
+		return fmt.Sprintf("pid %d reused by another process: %s", pid, shortCmd(cur.Cmd))
+	case procUnverified:
+		return fmt.Sprintf("pid %d running, unverified (no start time recorded): %s", pid, shortCmd(cur.Cmd))
+	}
+	return fmt.Sprintf("pid %d %s", pid, state)
+}
+
+// scrubCmd hides registered secrets and --api-key values so recorded command lines carry no keys.
+func scrubCmd(cmd string) string {
+	f := strings.Fields(redactSecrets(cmd))
+	for i := range f {
+		switch {
+		case f[i] == "--api-key" && i+1 < len(f):
+			f[i+1] = redactedValue
+		case strings.HasPrefix(f[i], "--api-key="):
+			f[i] = "--api-key=" + redactedValue
+		}
+	}
+	return strings.Join(f, " ")
+}
+
+func shortCmd(cmd string) string {
+	if len(cmd) > 60 {
+		return cmd[:57] + "..."
+	}
+	return cmd
+}
+
+func (inst *Instance) setMcpoPID(pid int) {
+	inst.McpoPID, inst.McpoProc = pid, nil
+	if pid > 0 {
+		inst.McpoProc = stampProcess(pid)
+	}
+}
+
+func (inst *Instance) setCloudflaredPID(pid int) {
+	inst.CloudflaredPID, inst.CloudflaredProc = pid, nil
+	if pid > 0 {
+		inst.CloudflaredProc = stampProcess(pid)
+	}
+}
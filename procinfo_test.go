@@ -0,0 +1,69 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCheckProc(t *testing.T) {
+	self, ok := readProcStamp(os.Getpid())
+	if !ok {
+		t.Skip("cannot read process identity on this platform")
+	}
+	shifted := self
+	shifted.Start = "2001-01-01T00:00:00.00Z" // as if btime moved after a clock adjustment
+	otherTicks := self
+	otherTicks.Ticks++
+	otherBoot := self
+	otherBoot.BootID = "another-boot"
+	legacy := procStamp{Start: self.Start} // recorded before ticks and boot IDs
+
+	type procCase struct {
+		name string
+		pid  int
+		rec  *procStamp
+		want string
+	}
+	tests := []procCase{
+		{"same process", os.Getpid(), &self, procRunning},
+		{"no stamp recorded", os.Getpid(), nil, procUnverified},
+		{"start only", os.Getpid(), &legacy, procRunning},
+		{"dead pid", 1 << 22, &self, procDead},
+		{"no pid", 0, &self, procDead},
+	}
+	if self.Ticks != 0 && self.BootID != "" {
+		tests = append(tests,
+			procCase{"wall clock shifted", os.Getpid(), &shifted, procRunning},
+			procCase{"other start ticks", os.Getpid(), &otherTicks, procMismatch},
+			procCase{"other boot", os.Getpid(), &otherBoot, procMismatch},
+		)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := checkProc(tt.pid, tt.rec); got != tt.want {
+				t.Errorf("checkProc(%d) = %q, want %q", tt.pid, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStopRecordedKeepsReusedPID(t *testing.T) {
+	self, ok := readProcStamp(os.Getpid())
+	if !ok {
+		t.Skip("cannot read process identity on this platform")
+	}
+	other := self
+	other.Start, other.Ticks, other.BootID = "2001-01-01T00:00:00.00Z", 1, "another-boot"
+	kill := func(pid int) error {
+		t.Fatalf("signalled pid %d", pid)
+		return nil
+	}
+	if stopRecorded("mcpo", "s", os.Getpid(), &other, false, kill) {
+		t.Error("a reused pid was dropped from state without --force")
+	}
+	if !stopRecorded("mcpo", "s", os.Getpid(), &other, true, kill) {
+		t.Error("--force did not forget a reused pid")
+	}
+}
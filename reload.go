@@ -0,0 +1,187 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ---------- hot reload of the merged OpenAPI ----------
+
+// reloadOptions controls how `up` notices config edits (mcpo itself runs with --hot-reload)
+// and server-side spec changes.
+type reloadOptions struct {
+	Interval time.Duration // how often ConfigPath is stat'ed
+	Settle   time.Duration // wait after a config edit so mcpo can respawn servers
+	SpecPoll time.Duration // re-merge this often even without edits; 0 = only on edits
+	Verbose  bool          // also report reloads that changed no operations
+}
+
+// configStamp identifies a version of a config file without reading it every tick.
+type configStamp struct {
+	mod  time.Time
+	size int64
+}
+
+func statConfig(path string) (configStamp, bool) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return configStamp{}, false
+	}
+	return configStamp{mod: fi.ModTime(), size: fi.Size()}, true
+}
+
+// watchStack re-merges a stack's OpenAPI when its config changes (or on the spec poll) and
+// swaps it into the live proxy; the front port and tunnel URL stay the same.
+func watchStack(ctx context.Context, r *stackRun, mu *sync.Mutex, update func(func()), opts reloadOptions) {
+	mu.Lock()
+	cfgPath := r.inst.ConfigPath
+	mu.Unlock()
+
+	last, _ := statConfig(cfgPath)
+	pending := time.Time{} // when a config edit was first seen; zero = none
+	lastMerge := time.Now()
+	tick := time.NewTicker(opts.Interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+		if cur, ok := statConfig(cfgPath); ok && cur != last {
+			last = cur
+			pending = time.Now()
+			if err := rematerialize(r, mu); err != nil {
+				logProcLine("mcpo#"+r.proxy.name, "config not reloaded: "+err.Error())
+				fmt.Printf("[reload#%s] config not reloaded: %v\n", r.proxy.name, err)
+			}
+		}
+		switch {
+		case !pending.IsZero() && time.Since(pending) >= opts.Settle:
+		case pending.IsZero() && opts.SpecPoll > 0 && time.Since(lastMerge) >= opts.SpecPoll:
+		default:
+			continue
+		}
+		lastMerge = time.Now()
+		if err := reloadStack(r, mu, update, !pending.IsZero(), opts.Verbose); err != nil {
+			if !pending.IsZero() {
+				logProcLine("proxy#"+r.proxy.name, "reload failed: "+err.Error())
+				fmt.Printf("[reload#%s] %v (will retry)\n", r.proxy.name, err)
+			}
+			continue // keep serving the previous spec; a pending edit is retried next tick
+		}
+		pending = time.Time{}
+	}
+}
+
+// rematerialize refreshes the resolved config mcpo hot-reloads from (not for auto-split parts,
+// which share their parent's mcpo).
+func rematerialize(r *stackRun, mu *sync.Mutex) error {
+	mu.Lock()
+	inst := *r.inst
+	mu.Unlock()
+	if inst.SplitOf != "" {
+		return nil
+	}
+	_, err := materializeConfig(&inst)
+	return err
+}
+
+// reloadStack re-merges one stack and applies the result if anything changed.
+func reloadStack(r *stackRun, mu *sync.Mutex, update func(func()), configChanged, verbose bool) error {
+	mu.Lock()
+	inst := *r.inst
+	mu.Unlock()
+
+	baseURL := inst.PublicURL
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("http://127.0.0.1:%d", inst.FrontPort)
+	}
+	prevTools, toolNames := inst.ToolNames, inst.ToolNames
+	if configChanged {
+		toolNames = reloadedServerNames(inst)
+		inst.ToolNames = toolNames
+	}
+	spec, filtered, err := mergeOpenAPI(inst, baseURL)
+	if err != nil {
+		return err
+	}
+
+	r.proxy.mu.RLock()
+	old := r.proxy.spec
+	r.proxy.mu.RUnlock()
+	if bytes.Equal(old, spec) && slices.Equal(toolNames, prevTools) {
+		return nil
+	}
+
+	added, removed := diffOperations(operationIndex(old), operationIndex(spec))
+	r.proxy.SetFiltered(filtered)
+	r.proxy.SetServers(toolNames)
+	r.proxy.SetOpenAPI(spec)
+	count := countOperations(spec)
+	update(func() {
+		r.inst.ToolNames = toolNames
+		r.inst.OperationCount = count
+	})
+
+	if len(added) == 0 && len(removed) == 0 {
+		logProcLine("proxy#"+inst.Name, "reloaded OpenAPI (no operation changes)")
+		if verbose {
+			fmt.Printf("[reload#%s] OpenAPI updated (no operations added or removed)\n", inst.Name)
+		}
+		return nil
+	}
+	logProcLine("proxy#"+inst.Name, fmt.Sprintf("reloaded OpenAPI: +%d -%d operations (now %d)", len(added), len(removed), count))
+	fmt.Printf("[reload#%s] OpenAPI reloaded: %d operation(s) (+%d, -%d)\n", inst.Name, count, len(added), len(removed))
+	for _, op := range added {
+		fmt.Println("  +", op)
+	}
+	for _, op := range removed {
+		fmt.Println("  -", op)
+	}
+	if count > defaultSplitLimit {
+		fmt.Printf("[reload#%s] ⚠ OVER 30-limit; restart with --auto-split to re-plan\n", inst.Name)
+	}
+	if inst.SplitOf != "" || len(inst.Routes) > 0 {
+		fmt.Printf("[reload#%s] note: new tools are not assigned to auto-split parts until restart\n", inst.Name)
+	}
+	return nil
+}
+
+// reloadedServerNames re-reads the stack's servers; split parts keep only their own servers.
+func reloadedServerNames(inst Instance) []string {
+	cfg := readConfig(inst.ConfigPath)
+	var names []string
+	for name := range cfg.MCPServers {
+		if len(inst.Routes) > 0 && !slices.Contains(inst.ToolNames, name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// diffOperations lists "operationId (METHOD /path)" entries added and removed between two specs.
+func diffOperations(before, after map[string]string) (added, removed []string) {
+	for route, op := range after {
+		if before[route] != op {
+			added = append(added, fmt.Sprintf("%s (%s)", op, route))
+		}
+	}
+	for route, op := range before {
+		if after[route] != op {
+			removed = append(removed, fmt.Sprintf("%s (%s)", op, route))
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+	return added, removed
+}
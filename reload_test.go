@@ -0,0 +1,160 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDiffOperations(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after map[string]string
+		added         []string
+		removed       []string
+	}{
+		{"unchanged", map[string]string{"POST /a/x": "a__x"}, map[string]string{"POST /a/x": "a__x"}, nil, nil},
+		{"from nothing", nil, map[string]string{"POST /a/y": "a__y", "POST /a/x": "a__x"},
+			[]string{"a__x (POST /a/x)", "a__y (POST /a/y)"}, nil},
+		{"removed", map[string]string{"POST /a/x": "a__x", "GET /b/z": "b__z"}, map[string]string{"POST /a/x": "a__x"},
+			nil, []string{"b__z (GET /b/z)"}},
+		{"renamed on the same route", map[string]string{"POST /a/x": "a__x"}, map[string]string{"POST /a/x": "a__x2"},
+			[]string{"a__x2 (POST /a/x)"}, []string{"a__x (POST /a/x)"}},
+		{"moved to another method", map[string]string{"POST /a/x": "a__x"}, map[string]string{"PUT /a/x": "a__x"},
+			[]string{"a__x (PUT /a/x)"}, []string{"a__x (POST /a/x)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := diffOperations(tt.before, tt.after)
+			if !reflect.DeepEqual(added, tt.added) || !reflect.DeepEqual(removed, tt.removed) {
+				t.Errorf("diffOperations = +%q -%q, want +%q -%q", added, removed, tt.added, tt.removed)
+			}
+		})
+	}
+}
+
+func TestReloadedServerNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp.config.json")
+	cfg := `{"mcpServers":{"a":{"command":"x"},"b":{"command":"x"},"c":{"command":"x","disabled":true}}}`
+	if err := os.WriteFile(path, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		inst Instance
+		want []string
+	}{
+		{"whole stack", Instance{ConfigPath: path, ToolNames: []string{"a"}}, []string{"a", "b"}},
+		{"split part keeps its own servers", Instance{ConfigPath: path, Routes: []string{"POST /b/x"}, ToolNames: []string{"b"}}, []string{"b"}},
+		{"split part loses a disabled server", Instance{ConfigPath: path, Routes: []string{"POST /c/x"}, ToolNames: []string{"a", "c"}}, []string{"a"}},
+		{"split part loses a removed server", Instance{ConfigPath: path, Routes: []string{"POST /gone/x"}, ToolNames: []string{"gone"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reloadedServerNames(tt.inst); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reloadedServerNames = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloadStack(t *testing.T) {
+	dir := chdirTemp(t)
+	var specMu sync.Mutex
+	ops := map[string]string{"a": `"/x":{"post":{"operationId":"x"}}`, "b": `"/y":{"get":{"operationId":"y"}}`}
+	mcpo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		specMu.Lock()
+		paths, ok := ops[server]
+		specMu.Unlock()
+		if !ok || r.Header.Get("X-API-Key") != "mcpo-key" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"openapi":"3.1.0","paths":{` + paths + `}}`))
+	}))
+	t.Cleanup(mcpo.Close)
+	u, _ := url.Parse(mcpo.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	cfgPath := filepath.Join(dir, "mcp.config.json")
+	writeCfg := func(cfg string) {
+		if err := os.WriteFile(cfgPath, []byte(cfg), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeCfg(`{"mcpServers":{"a":{"command":"x"},"b":{"command":"x"}}}`)
+	inst := Instance{Name: "reloadtest", ConfigPath: cfgPath, McpoPort: port, FrontPort: 1, APIKey: "mcpo-key", ToolNames: []string{"a", "b"}}
+	r := &stackRun{inst: &inst, proxy: newFrontProxy(inst.Name, 0, port, inst.APIKey)}
+	var mu sync.Mutex
+	updates := 0
+	update := func(fn func()) {
+		mu.Lock()
+		defer mu.Unlock()
+		fn()
+		updates++
+	}
+
+	steps := []struct {
+		name          string
+		edit          func()
+		configChanged bool
+		wantUpdate    bool
+		wantOps       []string
+		wantTools     []string
+	}{
+		{"first merge", func() {}, false, true, []string{"a__x", "b__y"}, []string{"a", "b"}},
+		{"unchanged", func() {}, false, false, []string{"a__x", "b__y"}, []string{"a", "b"}},
+		{"config touched without changes", func() {}, true, false, []string{"a__x", "b__y"}, []string{"a", "b"}},
+		{"operation added", func() {
+			specMu.Lock()
+			ops["a"] += `,"/z":{"post":{"operationId":"z"}}`
+			specMu.Unlock()
+		}, false, true, []string{"a__x", "a__z", "b__y"}, []string{"a", "b"}},
+		{"server removed", func() { writeCfg(`{"mcpServers":{"a":{"command":"x"}}}`) }, true, true, []string{"a__x", "a__z"}, []string{"a"}},
+	}
+	for _, st := range steps {
+		st.edit()
+		before := updates
+		if err := reloadStack(r, &mu, update, st.configChanged, false); err != nil {
+			t.Fatalf("%s: %v", st.name, err)
+		}
+		if got := updates > before; got != st.wantUpdate {
+			t.Errorf("%s: updated = %v, want %v", st.name, got, st.wantUpdate)
+		}
+		r.proxy.mu.RLock()
+		spec := r.proxy.spec
+		r.proxy.mu.RUnlock()
+		var got []string
+		for _, op := range operationIndex(spec) {
+			got = append(got, op)
+		}
+		slices.Sort(got)
+		if !reflect.DeepEqual(got, st.wantOps) {
+			t.Errorf("%s: operations = %q, want %q", st.name, got, st.wantOps)
+		}
+		mu.Lock()
+		if !reflect.DeepEqual(inst.ToolNames, st.wantTools) || inst.OperationCount != len(st.wantOps) {
+			t.Errorf("%s: tools %q, %d operation(s); want %q, %d", st.name, inst.ToolNames, inst.OperationCount, st.wantTools, len(st.wantOps))
+		}
+		mu.Unlock()
+	}
+
+	writeCfg(`{"mcpServers":{"a":{"command":"x"},"down":{"command":"x"}}}`)
+	if err := reloadStack(r, &mu, update, true, false); err == nil {
+		t.Error("reload succeeded with a server whose spec cannot be fetched")
+	}
+	if inst.OperationCount != 2 {
+		t.Errorf("a failed reload changed the stack: %d operation(s)", inst.OperationCount)
+	}
+}
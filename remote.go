@@ -0,0 +1,381 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ---------- remote MCP servers ----------
+//
+// Servers with "type": "sse" or "streamable-http" are reached over HTTP instead of being spawned.
+// `up` and `doctor` check that each one answers the MCP initialize handshake (with the server's
+// connectTimeout and connectRetries), the native backend talks to them directly, and `status`
+// shows whether the stack's backend is connected to them. Header values may use ${VAR} and
+// ${file:...} like env; the resolved values are scrubbed from logs.
+
+const (
+	defaultRemoteTimeout = 10 * time.Second
+	defaultRemoteRetries = 2
+)
+
+func (s MCPServer) isRemote() bool { return s.Type == "sse" || s.Type == "streamable-http" }
+
+// connectPolicy returns how long one connect attempt may take and how often it is retried.
+func (s MCPServer) connectPolicy() (time.Duration, int) {
+	timeout, retries := bridgeInitTimeout, 0 // stdio: one patient attempt (uvx/npx may download first)
+	if s.isRemote() {
+		timeout, retries = defaultRemoteTimeout, defaultRemoteRetries
+	}
+	if d, err := time.ParseDuration(s.ConnectTimeout); err == nil && d > 0 {
+		timeout = d
+	}
+	if s.ConnectRetries != nil {
+		retries = *s.ConnectRetries
+	}
+	return timeout, retries
+}
+
+// remoteCheck is the outcome of connecting to one remote server.
+type remoteCheck struct {
+	Name, Type, URL string
+	Server          string // serverInfo name and version
+	Err             error
+}
+
+// checkRemoteServers connects to every enabled remote server in a config, in parallel, and
+// closes the sessions again. Results are sorted by server name.
+func checkRemoteServers(cfgPath string) []remoteCheck {
+	cfg := readConfig(cfgPath)
+	relDir := filepath.Dir(cfgPath)
+	var out []remoteCheck
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for name, s := range cfg.MCPServers {
+		if !s.isRemote() {
+			continue
+		}
+		wg.Add(1)
+		go func(name string, s MCPServer) {
+			defer wg.Done()
+			rc := remoteCheck{Name: name, Type: s.Type, URL: s.URL}
+			rs, err := resolveServer(name, s, relDir)
+			if err == nil {
+				var c *mcpClient
+				if c, err = dialMCP(name, rs, io.Discard, nil); err == nil {
+					rc.Server = strings.TrimSpace(c.ServerName + " " + c.ServerVersion)
+					c.Close()
+				}
+			}
+			rc.Err = err
+			mu.Lock()
+			out = append(out, rc)
+			mu.Unlock()
+		}(name, s)
+	}
+	wg.Wait()
+	slices.SortFunc(out, func(a, b remoteCheck) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+// reportRemoteServers prints unreachable remote servers of a stack (and, verbosely, the rest).
+func reportRemoteServers(stack, cfgPath string, verbose bool) {
+	for _, rc := range checkRemoteServers(cfgPath) {
+		switch {
+		case rc.Err != nil:
+			fmt.Printf("[remote#%s] %s (%s %s) is unreachable: %v\n", stack, rc.Name, rc.Type, redactSecrets(rc.URL), redactSecrets(rc.Err.Error()))
+		case verbose:
+			fmt.Printf("[remote#%s] %s (%s %s) answered: %s\n", stack, rc.Name, rc.Type, redactSecrets(rc.URL), rc.Server)
+		}
+	}
+}
+
+// remoteStatus describes, per remote server of a stack, whether its backend is connected to it.
+func remoteStatus(inst Instance) []string {
+	cfg := readConfig(inst.ConfigPath)
+	var names []string
+	for name, s := range cfg.MCPServers {
+		if s.isRemote() && (len(inst.ToolNames) == 0 || slices.Contains(inst.ToolNames, name)) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	backendUp := len(names) > 0 && probeMcpo(inst.McpoPort) == nil
+	out := make([]string, len(names))
+	for i, name := range names {
+		s := cfg.MCPServers[name]
+		state := "stack not running"
+		if backendUp {
+			if h := probeServer(inst.McpoPort, inst.APIKey, name); h.OK {
+				state = "connected"
+			} else {
+				state = "not connected: " + h.Error
+			}
+		}
+		out[i] = fmt.Sprintf("%s (%s %s): %s", name, s.Type, s.URL, state)
+	}
+	return out
+}
+
+// ---------- streamable HTTP transport ----------
+
+// httpTransport POSTs each message to the server's endpoint; replies come back in the response,
+// as JSON or as a short SSE stream (MCP streamable HTTP, 2025-03-26).
+type httpTransport struct {
+	c       *mcpClient
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu      sync.Mutex
+	session string // Mcp-Session-Id assigned at initialize
+}
+
+func (t *httpTransport) send(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	t.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if sid := resp.Header.Get("Mcp-Session-Id"); sid != "" {
+		t.mu.Lock()
+		t.session = sid
+		t.mu.Unlock()
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound && req.Header.Get("Mcp-Session-Id") != "":
+		err := errors.New("session expired")
+		t.c.finish(err)
+		return err
+	case resp.StatusCode >= 300:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("POST %s: %s", t.url, strings.TrimSpace(resp.Status+" "+oneLine(body)))
+	case resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusNoContent:
+		return nil
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		// Read until our reply; the server should close the stream after it anyway.
+		readSSE(resp.Body, func(event, data string) bool {
+			if event != "" && event != "message" {
+				return true
+			}
+			t.c.dispatch([]byte(data))
+			return !isResponse([]byte(data))
+		})
+		return nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	t.dispatchBody(body)
+	return nil
+}
+
+// dispatchBody handles a JSON reply, which may be a batch.
+func (t *httpTransport) dispatchBody(body []byte) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if json.Unmarshal(body, &batch) == nil {
+			for _, m := range batch {
+				t.c.dispatch(m)
+			}
+			return
+		}
+	}
+	if len(body) > 0 {
+		t.c.dispatch(body)
+	}
+}
+
+func (t *httpTransport) setHeaders(req *http.Request) {
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	if t.session != "" {
+		req.Header.Set("Mcp-Session-Id", t.session)
+	}
+	t.mu.Unlock()
+}
+
+// close ends the server-side session, if one was assigned.
+func (t *httpTransport) close() {
+	t.mu.Lock()
+	sid := t.session
+	t.mu.Unlock()
+	if sid == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+	if err != nil {
+		return
+	}
+	t.setHeaders(req)
+	if resp, err := t.client.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// ---------- SSE transport ----------
+
+// sseTransport is the older HTTP+SSE transport (2024-11-05): a GET stream carries every
+// message from the server, and its first "endpoint" event names the URL to POST messages to.
+type sseTransport struct {
+	headers  map[string]string
+	client   *http.Client
+	endpoint string
+	cancel   context.CancelFunc
+}
+
+func openSSE(ctx context.Context, c *mcpClient, s MCPServer) (*sseTransport, error) {
+	streamCtx, cancel := context.WithCancel(context.Background())
+	// The stream outlives ctx; ctx only bounds how long we wait for it to open.
+	stop := context.AfterFunc(ctx, cancel)
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	t := &sseTransport{headers: s.Headers, client: &http.Client{}, cancel: cancel}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("GET %s: %s", s.URL, resp.Status)
+	}
+
+	endpoint := make(chan string, 1)
+	go func() {
+		defer resp.Body.Close()
+		readSSE(resp.Body, func(event, data string) bool {
+			switch event {
+			case "endpoint":
+				select {
+				case endpoint <- data:
+				default:
+				}
+			case "", "message":
+				c.dispatch([]byte(data))
+			}
+			return true
+		})
+		c.finish(errors.New("SSE stream closed"))
+	}()
+	select {
+	case ep := <-endpoint:
+		stop()
+		base, _ := url.Parse(s.URL)
+		ref, err := url.Parse(strings.TrimSpace(ep))
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("bad endpoint event %q: %w", ep, err)
+		}
+		t.endpoint = base.ResolveReference(ref).String()
+		return t, nil
+	case <-c.done:
+		cancel()
+		return nil, c.err
+	case <-ctx.Done():
+		cancel()
+		return nil, fmt.Errorf("no endpoint event from %s: %w", s.URL, ctx.Err())
+	}
+}
+
+func (t *sseTransport) send(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("POST %s: %s", t.endpoint, strings.TrimSpace(resp.Status+" "+oneLine(body)))
+	}
+	return nil
+}
+
+func (t *sseTransport) close() { t.cancel() }
+
+// readSSE calls fn for each event in an event stream until fn returns false or the stream ends.
+func readSSE(r io.Reader, fn func(event, data string) bool) {
+	br := bufio.NewReader(r)
+	var event string
+	var data []string
+	for {
+		line, err := br.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "" && err == nil:
+			if len(data) > 0 && !fn(event, strings.Join(data, "\n")) {
+				return
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"): // comment / keep-alive
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				event = value
+			case "data":
+				data = append(data, value)
+			}
+		}
+		if err != nil {
+			if len(data) > 0 {
+				fn(event, strings.Join(data, "\n"))
+			}
+			return
+		}
+	}
+}
+
+// oneLine condenses an error body (often an HTML page) for a single log line.
+func oneLine(body []byte) string {
+	return strings.Join(strings.Fields(string(body)), " ")
+}
+
+func isResponse(data []byte) bool {
+	var msg rpcMessage
+	return json.Unmarshal(data, &msg) == nil && msg.Method == "" && msg.ID != nil
+}
@@ -0,0 +1,337 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeMCPReply answers the requests a client makes while connecting and listing tools.
+func fakeMCPReply(msg rpcMessage) *rpcMessage {
+	if msg.ID == nil {
+		return nil // notification
+	}
+	reply := &rpcMessage{JSONRPC: "2.0", ID: msg.ID}
+	switch msg.Method {
+	case "initialize":
+		reply.Result = mustJSON(map[string]any{"protocolVersion": mcpProtocolVersion, "capabilities": map[string]any{},
+			"serverInfo": map[string]any{"name": "fake", "version": "1.0"}})
+	case "tools/list":
+		reply.Result = mustJSON(map[string]any{"tools": []mcpTool{{Name: "echo", InputSchema: json.RawMessage(`{"type":"object"}`)}}})
+	default:
+		reply.Error = &rpcError{Code: -32601, Message: "method not found"}
+	}
+	return reply
+}
+
+// fakeSSEServer speaks the HTTP+SSE transport at /mcp/sse: the stream's first event names a
+// relative message endpoint, and replies to POSTed requests arrive on the stream.
+type fakeSSEServer struct {
+	token string // bearer token required on every request ("" = none)
+	hang  bool   // open the stream but never send the endpoint event
+
+	streams atomic.Int32
+	done    chan struct{}
+	mu      sync.Mutex
+	out     chan []byte // the open stream, if any
+}
+
+// newFakeSSEServer starts a fake server; its URL is srv.URL + "/mcp/sse".
+func newFakeSSEServer(t *testing.T, token string, hang bool) (*fakeSSEServer, *httptest.Server) {
+	t.Helper()
+	f := &fakeSSEServer{token: token, hang: hang, done: make(chan struct{})}
+	srv := httptest.NewServer(f)
+	t.Cleanup(func() {
+		close(f.done)
+		srv.Close()
+	})
+	return f, srv
+}
+
+func (f *fakeSSEServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.token != "" && r.Header.Get("Authorization") != "Bearer "+f.token {
+		http.Error(w, "bad token", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/mcp/sse":
+		f.streams.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		out := make(chan []byte, 8)
+		if !f.hang {
+			f.mu.Lock()
+			f.out = out
+			f.mu.Unlock()
+			fmt.Fprint(w, ": keep-alive\n\nevent: endpoint\ndata: messages?session=1\n\n")
+			w.(http.Flusher).Flush()
+		}
+		for {
+			select {
+			case m := <-out:
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", m)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			case <-f.done:
+				return
+			}
+		}
+	case r.Method == http.MethodPost && r.URL.Path == "/mcp/messages" && r.URL.Query().Get("session") == "1":
+		var msg rpcMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		if reply := fakeMCPReply(msg); reply != nil {
+			f.mu.Lock()
+			f.out <- mustJSON(reply)
+			f.mu.Unlock()
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestSSETransportHandshake(t *testing.T) {
+	_, srv := newFakeSSEServer(t, "tok", false)
+	zero := 0
+	tests := []struct {
+		name    string
+		headers map[string]string
+		wantErr string
+	}{
+		{"endpoint event and initialize", map[string]string{"Authorization": "Bearer tok"}, ""},
+		{"rejected", map[string]string{"Authorization": "Bearer nope"}, "401 Unauthorized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := MCPServer{Type: "sse", URL: srv.URL + "/mcp/sse", Headers: tt.headers, ConnectRetries: &zero}
+			c, err := dialMCP("fake", s, io.Discard, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("dialMCP error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			if c.ServerName != "fake" || c.ServerVersion != "1.0" {
+				t.Errorf("server = %q %q, want fake 1.0", c.ServerName, c.ServerVersion)
+			}
+			if ep := c.t.(*sseTransport).endpoint; ep != srv.URL+"/mcp/messages?session=1" {
+				t.Errorf("endpoint = %s, want it resolved against the stream URL", ep)
+			}
+			tools, err := c.ListTools(context.Background())
+			if err != nil || len(tools) != 1 || tools[0].Name != "echo" {
+				t.Errorf("ListTools = %v, %v", tools, err)
+			}
+		})
+	}
+}
+
+// A stream that never names its endpoint is given up per connectTimeout and retried
+// connectRetries times.
+func TestSSEConnectTimeoutAndRetry(t *testing.T) {
+	f, srv := newFakeSSEServer(t, "", true)
+	one := 1
+	s := MCPServer{Type: "sse", URL: srv.URL + "/mcp/sse", ConnectTimeout: "100ms", ConnectRetries: &one}
+	_, err := dialMCP("fake", s, io.Discard, nil)
+	if err == nil || !strings.Contains(err.Error(), "no endpoint event") || !strings.Contains(err.Error(), "(after 2 attempts)") {
+		t.Fatalf("dialMCP error = %v", err)
+	}
+	if n := f.streams.Load(); n != 2 {
+		t.Errorf("opened %d streams, want 2", n)
+	}
+}
+
+func TestConnectPolicy(t *testing.T) {
+	three := 3
+	tests := []struct {
+		name    string
+		s       MCPServer
+		timeout string
+		retries int
+	}{
+		{"stdio", MCPServer{Command: "x"}, bridgeInitTimeout.String(), 0},
+		{"remote", MCPServer{Type: "sse"}, defaultRemoteTimeout.String(), defaultRemoteRetries},
+		{"configured", MCPServer{Type: "streamable-http", ConnectTimeout: "3s", ConnectRetries: &three}, "3s", 3},
+		{"bad timeout", MCPServer{Type: "sse", ConnectTimeout: "soon"}, defaultRemoteTimeout.String(), defaultRemoteRetries},
+	}
+	for _, tt := range tests {
+		if timeout, retries := tt.s.connectPolicy(); timeout.String() != tt.timeout || retries != tt.retries {
+			t.Errorf("%s: connectPolicy = %s, %d; want %s, %d", tt.name, timeout, retries, tt.timeout, tt.retries)
+		}
+	}
+}
+
+// newFakeHTTPServer speaks streamable HTTP at /mcp: JSON replies, an SSE reply for tools/list,
+// a session ID from initialize on, and DELETE to end the session.
+func newFakeHTTPServer(t *testing.T, token string) (srv *httptest.Server, deleted *atomic.Bool) {
+	t.Helper()
+	deleted = &atomic.Bool{}
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "bad token", http.StatusUnauthorized)
+			return
+		}
+		var msg rpcMessage
+		if r.Method == http.MethodPost {
+			if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if msg.Method != "initialize" && r.Header.Get("Mcp-Session-Id") != "s1" {
+			http.Error(w, "no session", http.StatusBadRequest)
+			return
+		}
+		reply := fakeMCPReply(msg)
+		switch {
+		case r.Method == http.MethodDelete:
+			deleted.Store(true)
+		case reply == nil:
+			w.WriteHeader(http.StatusAccepted)
+		case msg.Method == "tools/list":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", mustJSON(reply))
+		default:
+			w.Header().Set("Mcp-Session-Id", "s1")
+			writeJSON(w, http.StatusOK, reply)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, deleted
+}
+
+func TestStreamableHTTPTransport(t *testing.T) {
+	srv, deleted := newFakeHTTPServer(t, "tok")
+	c, err := dialMCP("fake", MCPServer{Type: "streamable-http", URL: srv.URL + "/mcp", Headers: map[string]string{"Authorization": "Bearer tok"}}, io.Discard, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ServerName != "fake" {
+		t.Errorf("server = %q, want fake", c.ServerName)
+	}
+	tools, err := c.ListTools(context.Background())
+	if err != nil || len(tools) != 1 || tools[0].Name != "echo" {
+		t.Errorf("ListTools = %v, %v", tools, err)
+	}
+	c.Close()
+	if !deleted.Load() {
+		t.Error("closing did not end the session")
+	}
+}
+
+// Header values in a config are ${VAR}-templated before connecting.
+func TestCheckRemoteServersTemplatesHeaders(t *testing.T) {
+	_, sse := newFakeSSEServer(t, "sse-token-4711", false)
+	streamable, _ := newFakeHTTPServer(t, "http-token-4711")
+	t.Setenv("MCPL_TEST_SSE_TOKEN", "sse-token-4711")
+	t.Setenv("MCPL_TEST_HTTP_TOKEN", "http-token-4711")
+	cfg := map[string]any{"mcpServers": map[string]any{
+		"a-sse":    map[string]any{"type": "sse", "url": sse.URL + "/mcp/sse", "headers": map[string]string{"Authorization": "Bearer ${MCPL_TEST_SSE_TOKEN}"}},
+		"b-http":   map[string]any{"type": "streamable-http", "url": streamable.URL + "/mcp", "headers": map[string]string{"Authorization": "Bearer ${MCPL_TEST_HTTP_TOKEN}"}},
+		"c-unset":  map[string]any{"type": "sse", "url": sse.URL + "/mcp/sse", "headers": map[string]string{"Authorization": "Bearer ${MCPL_TEST_UNSET_TOKEN}"}},
+		"d-wrong":  map[string]any{"type": "sse", "url": sse.URL + "/mcp/sse", "headers": map[string]string{"Authorization": "Bearer ${MCPL_TEST_HTTP_TOKEN}"}, "connectRetries": 0},
+		"e-local":  map[string]any{"command": "true"},
+		"f-remote": map[string]any{"type": "sse", "url": sse.URL + "/mcp/sse", "disabled": true},
+	}}
+	path := filepath.Join(t.TempDir(), "mcp.config.json")
+	if err := os.WriteFile(path, mustJSON(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := checkRemoteServers(path)
+	var got []string
+	for _, rc := range checks {
+		line := rc.Name + ": " + rc.Server
+		if rc.Err != nil {
+			line = rc.Name + ": " + rc.Err.Error()
+		}
+		got = append(got, line)
+	}
+	want := []string{"a-sse: fake 1.0", "b-http: fake 1.0", "c-unset: ", "d-wrong: "}
+	if len(got) != len(want) {
+		t.Fatalf("checks = %q, want %d servers", got, len(want))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("check %d = %q, want prefix %q", i, got[i], want[i])
+		}
+	}
+	if !strings.Contains(got[2], "MCPL_TEST_UNSET_TOKEN") || !strings.Contains(got[3], "401") {
+		t.Errorf("failures = %q", got[2:])
+	}
+}
+
+// status reports, per remote server, whether the stack's backend is connected to it.
+func TestRemoteStatus(t *testing.T) {
+	_, up := newFakeSSEServer(t, "", false)
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+	zero := 0
+	servers := map[string]MCPServer{
+		"up":    {Type: "sse", URL: up.URL + "/mcp/sse"},
+		"down":  {Type: "sse", URL: gone.URL + "/mcp/sse", ConnectTimeout: "200ms", ConnectRetries: &zero},
+		"local": {Command: "true"},
+	}
+	path := filepath.Join(t.TempDir(), "mcp.config.json")
+	if err := os.WriteFile(path, mustJSON(MCPConfig{MCPServers: servers}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b := &bridge{apiKey: "k", logw: io.Discard, servers: map[string]*bridgeServer{}}
+	b.apply(MCPConfig{MCPServers: map[string]MCPServer{"up": servers["up"], "down": servers["down"]}})
+	t.Cleanup(b.closeAll)
+	backend := httptest.NewServer(b)
+	u, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(u.Port())
+	inst := Instance{Name: "s", ConfigPath: path, McpoPort: port, APIKey: "k"}
+
+	tests := []struct {
+		name    string
+		stop    bool
+		want    []string
+		wantSub []string
+	}{
+		{"running", false,
+			[]string{"down (sse " + gone.URL + "/mcp/sse): not connected: openapi.json: 503 Service Unavailable: server down: ", "up (sse " + up.URL + "/mcp/sse): connected"},
+			[]string{"connection refused", ""}},
+		{"stopped", true,
+			[]string{"down (sse " + gone.URL + "/mcp/sse): stack not running", "up (sse " + up.URL + "/mcp/sse): stack not running"},
+			[]string{"", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.stop {
+				backend.Close()
+			}
+			got := remoteStatus(inst)
+			if len(got) != len(tt.want) {
+				t.Fatalf("status = %q, want %d lines", got, len(tt.want))
+			}
+			for i := range got {
+				if !strings.HasPrefix(got[i], tt.want[i]) || !strings.Contains(got[i], tt.wantSub[i]) {
+					t.Errorf("status[%d] = %q, want %q…%q", i, got[i], tt.want[i], tt.wantSub[i])
+				}
+			}
+		})
+	}
+}
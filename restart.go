@@ -0,0 +1,172 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ---------- restart policy ----------
+
+const (
+	restartNever     = "never"
+	restartOnFailure = "on-failure"
+	restartAlways    = "always"
+)
+
+type restartPolicy struct {
+	Mode        string        // never | on-failure | always
+	MaxRestarts int           // max restarts within Window (0 = unlimited)
+	Window      time.Duration // sliding window for MaxRestarts
+	Backoff     time.Duration // first delay; doubled for each consecutive restart
+	MaxBackoff  time.Duration
+}
+
+func parseRestartMode(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", restartNever, "no":
+		return restartNever, nil
+	case restartOnFailure:
+		return restartOnFailure, nil
+	case restartAlways:
+		return restartAlways, nil
+	}
+	return "", fmt.Errorf("unknown restart policy %q (want never|on-failure|always)", s)
+}
+
+// wants reports whether the policy asks for a restart after an exit with exitErr.
+func (p restartPolicy) wants(exitErr error) bool {
+	switch p.Mode {
+	case restartAlways:
+		return true
+	case restartOnFailure:
+		return exitErr != nil
+	}
+	return false
+}
+
+// restartTracker applies backoff and the max-restarts window for one stack.
+type restartTracker struct {
+	policy      restartPolicy
+	recent      []time.Time
+	consecutive int
+}
+
+// next returns the delay before the next restart, or false if the stack should be given up.
+// A process that stayed up for a full window resets the backoff.
+func (t *restartTracker) next(exitErr error, uptime time.Duration, now time.Time) (time.Duration, bool) {
+	if !t.policy.wants(exitErr) {
+		return 0, false
+	}
+	if t.policy.Window > 0 && uptime >= t.policy.Window {
+		t.consecutive = 0
+	}
+	kept := t.recent[:0]
+	for _, at := range t.recent {
+		if t.policy.Window <= 0 || now.Sub(at) < t.policy.Window {
+			kept = append(kept, at)
+		}
+	}
+	t.recent = kept
+	if t.policy.MaxRestarts > 0 && len(t.recent) >= t.policy.MaxRestarts {
+		return 0, false
+	}
+	delay := t.policy.Backoff
+	for i := 0; i < t.consecutive && delay < t.policy.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if t.policy.MaxBackoff > 0 && delay > t.policy.MaxBackoff {
+		delay = t.policy.MaxBackoff
+	}
+	t.consecutive++
+	t.recent = append(t.recent, now)
+	return delay, true
+}
+
+func describeExit(err error) string {
+	if err == nil {
+		return "exit status 0"
+	}
+	return err.Error()
+}
+
+// superviseMcpo waits on a stack's mcpo and restarts only that mcpo according to the
+// stack's policy; the front proxy and tunnel are left untouched. It returns once the
+// stack has been given up on or ctx is cancelled. update runs under the caller's state lock.
+func superviseMcpo(ctx context.Context, r *stackRun, start func(*Instance) (*exec.Cmd, error), update func(func())) {
+	tracker := &restartTracker{policy: r.policy}
+	cmd := r.mcpo
+	started := time.Now()
+	var startErr error
+	for {
+		exitErr := startErr
+		if cmd != nil {
+			exitErr = cmd.Wait()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		now := time.Now()
+		var oldPID, restarts int
+		update(func() {
+			oldPID, restarts = r.inst.McpoPID, r.inst.Restarts
+			r.inst.setMcpoPID(0)
+			r.inst.LastExit = describeExit(exitErr)
+		})
+		// Reap MCP servers the old mcpo left behind in its process group.
+		_ = killProcessGroup(oldPID)
+
+		delay, ok := tracker.next(exitErr, now.Sub(started), now)
+		if !ok {
+			msg := fmt.Sprintf("%s; giving up after %d restart(s)", describeExit(exitErr), restarts)
+			logProcLine("mcpo#"+r.inst.Name, msg)
+			if r.policy.Mode != restartNever {
+				fmt.Printf("[mcpo#%s] %s\n", r.inst.Name, msg)
+			}
+			// Split parts share this mcpo, so they are down for good as well.
+			reason := "mcpo given up: " + describeExit(exitErr)
+			update(func() {
+				for _, p := range r.parts {
+					p.inst.LastExit = reason
+				}
+			})
+			for _, run := range append([]*stackRun{r}, r.parts...) {
+				run.proxy.SetDown(reason)
+			}
+			return
+		}
+		msg := fmt.Sprintf("%s; restarting in %s", describeExit(exitErr), delay)
+		logProcLine("mcpo#"+r.inst.Name, msg)
+		fmt.Printf("[mcpo#%s] %s\n", r.inst.Name, msg)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		next, err := start(r.inst)
+		started = time.Now()
+		if err != nil {
+			logProcLine("mcpo#"+r.inst.Name, "restart failed: "+err.Error())
+			fmt.Printf("[mcpo#%s] restart failed: %v\n", r.inst.Name, err)
+			cmd, startErr = nil, err
+			continue
+		}
+		startErr = nil
+		cmd = next
+		update(func() {
+			r.mcpo = next
+			r.inst.setMcpoPID(next.Process.Pid)
+			r.inst.Restarts++
+			r.inst.LastRestartAt = started.Format(time.RFC3339)
+		})
+		if err := waitURL(fmt.Sprintf("http://127.0.0.1:%d/docs", r.inst.McpoPort), 60*time.Second); err != nil {
+			logProcLine("mcpo#"+r.inst.Name, "restarted mcpo is not answering: "+err.Error())
+			fmt.Printf("[mcpo#%s] restarted mcpo is not answering: %v\n", r.inst.Name, err)
+		}
+	}
+}
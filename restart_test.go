@@ -0,0 +1,148 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRestartTrackerNext(t *testing.T) {
+	failed := errors.New("exit status 1")
+	base := restartPolicy{Mode: restartOnFailure, MaxRestarts: 3, Window: time.Minute, Backoff: time.Second, MaxBackoff: 4 * time.Second}
+	type step struct {
+		exitErr error
+		uptime  time.Duration
+		at      time.Duration // since the start of the test
+		delay   time.Duration
+		ok      bool
+	}
+	tests := []struct {
+		name   string
+		policy func(p restartPolicy) restartPolicy
+		steps  []step
+	}{
+		{"never", func(p restartPolicy) restartPolicy { p.Mode = restartNever; return p }, []step{
+			{failed, 0, 0, 0, false},
+		}},
+		{"on-failure ignores a clean exit", nil, []step{
+			{nil, 0, 0, 0, false},
+		}},
+		{"always restarts a clean exit", func(p restartPolicy) restartPolicy { p.Mode = restartAlways; return p }, []step{
+			{nil, 0, 0, time.Second, true},
+		}},
+		{"backoff doubles up to the cap", func(p restartPolicy) restartPolicy { p.MaxRestarts = 0; return p }, []step{
+			{failed, 0, 0, time.Second, true},
+			{failed, 0, time.Second, 2 * time.Second, true},
+			{failed, 0, 2 * time.Second, 4 * time.Second, true},
+			{failed, 0, 3 * time.Second, 4 * time.Second, true},
+		}},
+		{"gives up after max restarts in the window", nil, []step{
+			{failed, 0, 0, time.Second, true},
+			{failed, 0, time.Second, 2 * time.Second, true},
+			{failed, 0, 2 * time.Second, 4 * time.Second, true},
+			{failed, 0, 3 * time.Second, 0, false},
+		}},
+		{"old restarts leave the window", nil, []step{
+			{failed, 0, 0, time.Second, true},
+			{failed, 0, time.Second, 2 * time.Second, true},
+			{failed, 0, 2 * time.Second, 4 * time.Second, true},
+			{failed, 0, 61 * time.Second, 4 * time.Second, true},
+		}},
+		{"a long uptime resets the backoff", nil, []step{
+			{failed, 0, 0, time.Second, true},
+			{failed, 0, time.Second, 2 * time.Second, true},
+			{failed, 2 * time.Minute, 3 * time.Minute, time.Second, true},
+		}},
+	}
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := base
+			if tt.policy != nil {
+				p = tt.policy(p)
+			}
+			tracker := &restartTracker{policy: p}
+			for i, s := range tt.steps {
+				delay, ok := tracker.next(s.exitErr, s.uptime, start.Add(s.at))
+				if delay != s.delay || ok != s.ok {
+					t.Fatalf("step %d: next = %v, %v; want %v, %v", i, delay, ok, s.delay, s.ok)
+				}
+			}
+		})
+	}
+}
+
+// A stack whose mcpo keeps exiting is given up on, and its auto-split parts go down with it.
+func TestSuperviseMcpoGivesUp(t *testing.T) {
+	chdirTemp(t)
+	mcpo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(mcpo.Close)
+	u, _ := url.Parse(mcpo.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	starts := 0
+	start := func(*Instance) (*exec.Cmd, error) {
+		starts++
+		cmd := exec.Command("sh", "-c", "exit 3")
+		return cmd, cmd.Start()
+	}
+	first, err := start(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := Instance{Name: "giveup", McpoPort: port}
+	parent.setMcpoPID(first.Process.Pid)
+	part := Instance{Name: "giveup-2", SplitOf: "giveup", McpoPort: port}
+	partRun := &stackRun{inst: &part, proxy: newFrontProxy(part.Name, 0, port, "")}
+	r := &stackRun{
+		inst:   &parent,
+		proxy:  newFrontProxy(parent.Name, 0, port, ""),
+		mcpo:   first,
+		policy: restartPolicy{Mode: restartOnFailure, MaxRestarts: 1, Window: time.Minute, Backoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		parts:  []*stackRun{partRun},
+	}
+	var mu sync.Mutex
+	update := func(fn func()) {
+		mu.Lock()
+		defer mu.Unlock()
+		fn()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		superviseMcpo(context.Background(), r, start, update)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("superviseMcpo did not give up")
+	}
+
+	if starts != 2 || parent.Restarts != 1 {
+		t.Errorf("started %d time(s), %d restart(s); want 2 and 1", starts, parent.Restarts)
+	}
+	if parent.McpoPID != 0 || parent.LastExit != "exit status 3" {
+		t.Errorf("parent after giving up: pid %d, last exit %q", parent.McpoPID, parent.LastExit)
+	}
+	if !strings.Contains(part.LastExit, "given up") {
+		t.Errorf("split part last exit = %q, want it marked as given up", part.LastExit)
+	}
+	for _, run := range []*stackRun{r, partRun} {
+		rec := httptest.NewRecorder()
+		run.proxy.srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"status":"down"`) {
+			t.Errorf("%s /readyz = %d %s, want 503 down", run.inst.Name, rec.Code, rec.Body)
+		}
+	}
+}
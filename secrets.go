@@ -0,0 +1,292 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ---------- ${VAR} interpolation and per-server env ----------
+//
+// Configs may reference ${VAR}, ${VAR:-default} and ${file:/path} in command, args, url,
+// headers and env values ($${ escapes a literal ${). mcp-launch resolves them into a private
+// runtime copy of the config (.mcp-launch/run/<stack>.json, mode 0600) that only mcpo reads;
+// the committed config, state.json, logs and the merged spec only ever see the placeholders.
+
+const runDirName = "run"
+
+func runtimeConfigPath(stack string) string {
+	return filepath.Join(getStateDir(), runDirName, stack+".json")
+}
+
+// interpolate expands ${...} references in s. lookup returns a variable's value and whether it is set;
+// relDir anchors relative ${file:...} paths. Contents of ${file:...} are registered as secrets;
+// variable values are registered by the caller's lookup, and :- defaults are not registered.
+func interpolate(s string, lookup func(string) (string, bool), relDir string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' { // $${ → literal ${
+			b.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+		b.WriteString(s[:i])
+		j := strings.IndexByte(s[i:], '}')
+		if j < 0 {
+			return "", fmt.Errorf("unterminated ${ in %q", s)
+		}
+		expr := s[i+2 : i+j]
+		s = s[i+j+1:]
+
+		val, err := resolveRef(expr, lookup, relDir)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(expr, "file:") {
+			registerSecret(val)
+		}
+		b.WriteString(val)
+	}
+}
+
+func resolveRef(expr string, lookup func(string) (string, bool), relDir string) (string, error) {
+	if p, ok := strings.CutPrefix(expr, "file:"); ok {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(relDir, p)
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return "", fmt.Errorf("${file:%s}: %w", p, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	name, def, hasDef := strings.Cut(expr, ":-")
+	if name == "" {
+		return "", fmt.Errorf("empty variable name in ${%s}", expr)
+	}
+	v, ok := lookup(name)
+	switch {
+	case hasDef && (!ok || v == ""): // like the shell, :- also replaces an empty value
+		return def, nil
+	case !ok:
+		return "", fmt.Errorf("${%s} is not set", name)
+	}
+	return v, nil
+}
+
+// readEnvFile parses KEY=VALUE lines (optional `export `, # comments, single/double quotes).
+func readEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	out := map[string]string{}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, n)
+		}
+		v = strings.TrimSpace(v)
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		out[strings.TrimSpace(k)] = v
+	}
+	return out, sc.Err()
+}
+
+// resolveServer returns a copy of s with env materialized and every ${...} expanded.
+// Lookups see the server's env, then its envFile, then the process environment.
+func resolveServer(name string, s MCPServer, relDir string) (MCPServer, error) {
+	fileEnv := map[string]string{}
+	if s.EnvFile != "" {
+		p := s.EnvFile
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(relDir, p)
+		}
+		vars, err := readEnvFile(p)
+		if err != nil {
+			return s, fmt.Errorf("server %s: envFile: %w", name, err)
+		}
+		fileEnv = vars
+	}
+	baseLookup := func(k string) (string, bool) {
+		if v, ok := fileEnv[k]; ok {
+			return v, true
+		}
+		return os.LookupEnv(k)
+	}
+
+	out := s
+	out.EnvFile = ""
+	out.Tools = nil
+	env := make(map[string]string, len(fileEnv)+len(s.Env))
+	for k, v := range fileEnv {
+		registerSecret(v)
+		env[k] = v
+	}
+	for k, v := range s.Env {
+		ev, err := interpolate(v, baseLookup, relDir)
+		if err != nil {
+			return s, fmt.Errorf("server %s: env %s: %w", name, k, err)
+		}
+		registerSecret(ev)
+		env[k] = ev
+	}
+	if len(env) > 0 {
+		out.Env = env
+	}
+	lookup := func(k string) (string, bool) {
+		if v, ok := env[k]; ok {
+			return v, true
+		}
+		return baseLookup(k)
+	}
+
+	// Any variable may carry a credential (Bearer ${TOKEN}, ?token=${TOKEN}, --api-key=${KEY}),
+	// so every value looked up is scrubbed from logs and state; registerSecret skips short ones.
+	secretLookup := func(k string) (string, bool) {
+		v, ok := lookup(k)
+		if ok {
+			registerSecret(v)
+		}
+		return v, ok
+	}
+
+	var err error
+	expand := func(field, v string) string {
+		if err != nil {
+			return v
+		}
+		var r string
+		if r, err = interpolate(v, secretLookup, relDir); err != nil {
+			err = fmt.Errorf("server %s: %s: %w", name, field, err)
+		}
+		return r
+	}
+	out.Command = expand("command", s.Command)
+	out.URL = expand("url", s.URL)
+	if len(s.Args) > 0 {
+		out.Args = make([]string, len(s.Args))
+		for i, a := range s.Args {
+			out.Args[i] = expand(fmt.Sprintf("args[%d]", i), a)
+		}
+	}
+	if len(s.Headers) > 0 {
+		out.Headers = make(map[string]string, len(s.Headers))
+		for k, v := range s.Headers {
+			out.Headers[k] = expand("headers."+k, v)
+			if strings.Contains(v, "${") {
+				registerSecret(out.Headers[k]) // e.g. "Bearer ${TOKEN}": scrub the whole value too
+			}
+		}
+	}
+	return out, err
+}
+
+// materializeConfig writes the resolved config mcpo is started with and returns its path.
+func materializeConfig(inst *Instance) (string, error) {
+	cfg, err := loadConfig(inst.ConfigPath)
+	if err != nil {
+		return "", err
+	}
+	relDir := filepath.Dir(inst.ConfigPath)
+	resolved := MCPConfig{MCPServers: make(map[string]MCPServer, len(cfg.MCPServers))}
+	for name, s := range cfg.enabled().MCPServers {
+		rs, err := resolveServer(name, s, relDir)
+		if err != nil {
+			return "", err
+		}
+		resolved.MCPServers[name] = rs
+	}
+	out, _ := json.MarshalIndent(resolved, "", "  ")
+	p := runtimeConfigPath(inst.Name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return "", err
+	}
+	// Write-then-rename so mcpo's hot reload never sees a half-written file.
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, out, 0o600); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+// removeRuntimeConfigs deletes the resolved configs (they contain secrets) once mcpo is gone.
+func removeRuntimeConfigs() {
+	_ = os.RemoveAll(filepath.Join(getStateDir(), runDirName))
+}
+
+// ---------- secret scrubbing ----------
+
+// Values shorter than this are too likely to collide with ordinary text to scrub.
+const minSecretLen = 6
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string // longest first, so overlapping values are scrubbed whole
+)
+
+func registerSecret(v string) {
+	if len(v) < minSecretLen {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	if slices.Contains(secrets, v) {
+		return
+	}
+	secrets = append(secrets, v)
+	slices.SortFunc(secrets, func(a, b string) int { return len(b) - len(a) })
+}
+
+// redactSecrets replaces every resolved secret in s with [REDACTED].
+func redactSecrets(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, v := range secrets {
+		if strings.Contains(s, v) {
+			s = strings.ReplaceAll(s, v, redactedValue)
+		}
+	}
+	return s
+}
+
+// redactSecretsJSON scrubs secrets from JSON, including their escaped string form.
+func redactSecretsJSON(b []byte) []byte {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, v := range secrets {
+		b = bytes.ReplaceAll(b, []byte(v), []byte(redactedValue))
+		if enc, _ := json.Marshal(v); len(enc) > 2 && string(enc[1:len(enc)-1]) != v {
+			b = bytes.ReplaceAll(b, enc[1:len(enc)-1], []byte(redactedValue))
+		}
+	}
+	return b
+}
@@ -0,0 +1,127 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token.txt"), []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]string{"HOME": "/home/me", "EMPTY": "", "TZ": "Europe/Paris"}
+	lookup := func(k string) (string, bool) { v, ok := vars[k]; return v, ok }
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"plain", "plain", false},
+		{"${HOME}/x", "/home/me/x", false},
+		{"a${HOME}b${TZ}c", "a/home/mebEurope/Parisc", false},
+		{"${EMPTY}", "", false},                   // set but empty
+		{"${EMPTY:-fallback}", "fallback", false}, // :- also covers empty
+		{"${UNSET:-fallback}", "fallback", false},
+		{"${UNSET:-}", "", false},
+		{"${TZ:-UTC}", "Europe/Paris", false},
+		{"${UNSET}", "", true},
+		{"${}", "", true},
+		{"${HOME", "", true},
+		{"$${HOME}", "${HOME}", false},
+		{"${file:token.txt}", "from-file", false},
+		{"${file:" + filepath.Join(dir, "token.txt") + "}", "from-file", false},
+		{"${file:missing.txt}", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := interpolate(tt.in, lookup, dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("interpolate(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("interpolate(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveRef(t *testing.T) {
+	lookup := func(k string) (string, bool) {
+		v, ok := map[string]string{"SET": "value", "EMPTY": ""}[k]
+		return v, ok
+	}
+	tests := []struct {
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{"SET", "value", false},
+		{"EMPTY", "", false},
+		{"EMPTY:-d", "d", false},
+		{"UNSET:-d", "d", false},
+		{"SET:-d", "value", false},
+		{"UNSET", "", true},
+		{":-d", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := resolveRef(tt.expr, lookup, ".")
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("resolveRef(%q) = %q, %v; want %q, error %v", tt.expr, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Every variable value and file content a server is resolved with is scrubbed from logs;
+// :- defaults are not.
+func TestResolveServerRegistersSecrets(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "key"), []byte("file-secret-4711"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MCPL_TEST_TOKEN", "header-token-4711")
+	t.Setenv("MCPL_TEST_ENV", "env-secret-4711")
+	t.Setenv("MCPL_TEST_URL_TOKEN", "url-token-4711")
+	t.Setenv("MCPL_TEST_ARG_KEY", "arg-key-4711")
+	s := MCPServer{
+		Command: "run",
+		URL:     "https://host.example/sse?token=${MCPL_TEST_URL_TOKEN}",
+		Args:    []string{"--api-key=${MCPL_TEST_ARG_KEY}", "--tz", "${MCPL_TEST_TZ:-Plain/Zone-4711}", "--key", "${file:key}"},
+		Env:     map[string]string{"API_KEY": "${MCPL_TEST_ENV}"},
+		Headers: map[string]string{"Authorization": "Bearer ${MCPL_TEST_TOKEN}"},
+	}
+	if _, err := resolveServer("srv", s, dir); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value  string
+		secret bool
+	}{
+		{"env-secret-4711", true},
+		{"header-token-4711", true},
+		{"Bearer header-token-4711", true},
+		{"file-secret-4711", true},
+		{"url-token-4711", true},
+		{"arg-key-4711", true},
+		{"Plain/Zone-4711", false},
+	}
+	for _, tt := range tests {
+		if got := redactSecrets(tt.value) == redactedValue; got != tt.secret {
+			t.Errorf("%q scrubbed = %v, want %v", tt.value, got, tt.secret)
+		}
+	}
+	// As they would appear in a connection error and a recorded command line.
+	if got := redactSecrets(`GET https://host.example/sse?token=url-token-4711: 401`); strings.Contains(got, "url-token-4711") {
+		t.Errorf("url token not scrubbed: %s", got)
+	}
+	if got := scrubCmd("server --api-key=arg-key-4711 --other --api-key upstream-key"); got != "server --api-key=[REDACTED] --other --api-key [REDACTED]" {
+		t.Errorf("scrubCmd = %s", got)
+	}
+}
@@ -0,0 +1,383 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ---------- `mcp-launch server` ----------
+//
+// server add|rm|enable|disable|list edit a config in place. Edits go through a temp file and a
+// rename, so mcpo's hot reload and `up`'s config watcher never read a half-written file; the
+// watcher then re-materializes the config and re-merges the stack's /openapi.json.
+
+// enabled returns the config without servers marked "disabled": true.
+func (c MCPConfig) enabled() MCPConfig {
+	out := c
+	out.MCPServers = make(map[string]MCPServer, len(c.MCPServers))
+	for name, s := range c.MCPServers {
+		if !s.Disabled {
+			out.MCPServers[name] = s
+		}
+	}
+	return out
+}
+
+func cmdServer() {
+	if len(os.Args) < 3 {
+		helpTopic("server")
+		os.Exit(2)
+	}
+	args := os.Args[3:]
+	switch os.Args[2] {
+	case "list", "ls":
+		cmdServerList(args)
+	case "add":
+		cmdServerAdd(args)
+	case "rm", "remove":
+		cmdServerEdit("rm", args)
+	case "enable":
+		cmdServerEdit("enable", args)
+	case "disable":
+		cmdServerEdit("disable", args)
+	default:
+		helpTopic("server")
+		os.Exit(2)
+	}
+}
+
+// parseInterspersed parses flags that may come before, between or after positional args.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var pos []string
+	for {
+		_ = fs.Parse(args)
+		if fs.NArg() == 0 {
+			return pos
+		}
+		pos = append(pos, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// editConfigServers applies fn to the config's "mcpServers" table and writes the file back
+// atomically in its own format. Other keys in the file are kept.
+func editConfigServers(path string, create bool, fn func(servers map[string]any) error) error {
+	doc := map[string]any{}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		d, err := decodeConfigDoc(data, configFormat(path))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		m, ok := d.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an object at the top level", path)
+		}
+		doc = m
+	case errors.Is(err, os.ErrNotExist) && create:
+	default:
+		return err
+	}
+	servers, _ := doc["mcpServers"].(map[string]any)
+	if servers == nil {
+		servers = map[string]any{}
+	}
+	if err := fn(servers); err != nil {
+		return err
+	}
+	doc["mcpServers"] = servers
+	out, err := encodeConfigDoc(doc, configFormat(path))
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, out, 0644)
+}
+
+// checkServerName applies the same rules as validate to a new server name.
+func checkServerName(name string) error {
+	switch {
+	case !serverNameRe.MatchString(name):
+		return fmt.Errorf("invalid server name %q (use letters, digits, '-' and '_')", name)
+	case strings.Contains(name, "__"):
+		return fmt.Errorf("server name %q must not contain \"__\"", name)
+	case slices.Contains(reservedServerNames, strings.ToLower(name)):
+		return fmt.Errorf("server name %q clashes with the front proxy's /%s endpoint", name, name)
+	}
+	return nil
+}
+
+func parseKV(pairs []string, flagName string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(pairs))
+	for _, kv := range pairs {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("--%s %q: expected KEY=VALUE", flagName, kv)
+		}
+		out[k] = v
+	}
+	return out, nil
+}
+
+func cmdServerList(args []string) {
+	fs := flag.NewFlagSet("server list", flag.ExitOnError)
+	fs.Usage = func() { helpTopic("server") }
+	config := fs.String("config", "", "Config file (default: mcp.config.json / .yaml / .toml)")
+	_ = fs.Parse(args)
+	if *config == "" {
+		*config = defaultConfigPath()
+	}
+	cfg, err := loadConfig(*config)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	names := make([]string, 0, len(cfg.MCPServers))
+	for name := range cfg.MCPServers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	fmt.Printf("%s (%d server(s)):\n", *config, len(names))
+	for _, name := range names {
+		s := cfg.MCPServers[name]
+		state := "enabled"
+		if s.Disabled {
+			state = "disabled"
+		}
+		what := strings.TrimSpace(s.Command + " " + strings.Join(s.Args, " "))
+		if s.Type != "" && s.Type != "stdio" {
+			what = s.Type + " " + s.URL
+		}
+		fmt.Printf("  %-20s %-8s  %s\n", name, state, what)
+	}
+}
+
+func cmdServerAdd(args []string) {
+	fs := flag.NewFlagSet("server add", flag.ExitOnError)
+	fs.Usage = func() { helpTopic("server") }
+	config := fs.String("config", "", "Config file (default: mcp.config.json / .yaml / .toml; created if missing)")
+	command := fs.String("command", "", "Executable for a stdio server")
+	var argv, envs, headers, sets stringSlice
+	fs.Var(&argv, "arg", "Argument for --command (repeatable)")
+	url := fs.String("url", "", "URL of a remote server")
+	typ := fs.String("type", "", "sse | streamable-http (default for --url: streamable-http)")
+	fs.Var(&envs, "env", "KEY=VALUE for the server process (repeatable; values may use ${VAR})")
+	fs.Var(&headers, "header", "KEY=VALUE header for a remote server (repeatable)")
+	envFile := fs.String("env-file", "", "KEY=VALUE file merged under env")
+	fs.Var(&sets, "set", "Catalog parameter key=val when NAME is a catalog server (repeatable)")
+	disabled := fs.Bool("disabled", false, "Add the server disabled")
+	force := fs.Bool("force", false, "Replace a server with the same name")
+
+	// Everything after "--" is the command line: server add time -- uvx mcp-server-time
+	var cmdline []string
+	if i := slices.Index(args, "--"); i >= 0 {
+		args, cmdline = args[:i], args[i+1:]
+	}
+	pos := parseInterspersed(fs, args)
+	if len(pos) != 1 {
+		helpTopic("server")
+		os.Exit(2)
+	}
+	name := pos[0]
+	if err := checkServerName(name); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if *config == "" {
+		*config = defaultConfigPath()
+	}
+
+	var s MCPServer
+	switch {
+	case len(cmdline) > 0 || *command != "":
+		if len(cmdline) > 0 && *command != "" {
+			fmt.Println("Use either --command or '-- CMD ARGS...', not both")
+			os.Exit(2)
+		}
+		if len(cmdline) > 0 {
+			s.Command, s.Args = cmdline[0], cmdline[1:]
+		} else {
+			s.Command, s.Args = *command, argv
+		}
+	case *url != "":
+		s.URL = *url
+		s.Type = *typ
+		if s.Type == "" {
+			s.Type = "streamable-http"
+		}
+		if s.Type != "sse" && s.Type != "streamable-http" {
+			fmt.Printf("Unknown --type %q (expected sse or streamable-http)\n", s.Type)
+			os.Exit(2)
+		}
+	case catalogLookup(name) != nil:
+		vals, err := parseSets(sets, []string{name})
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		e := catalogLookup(name)
+		for _, p := range e.Params {
+			if _, ok := vals[name][p.Key]; !ok {
+				vals[name][p.Key] = p.Default()
+			}
+		}
+		s = e.build(vals[name])
+	default:
+		fmt.Printf("Give the server's command (-- CMD ARGS... or --command) or --url; %q is not in the catalog (%s).\n", name, strings.Join(catalogNames(), ", "))
+		os.Exit(2)
+	}
+	var err error
+	if s.Env, err = mergeKV(s.Env, envs, "env"); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if s.Headers, err = mergeKV(s.Headers, headers, "header"); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	s.EnvFile = *envFile
+	s.Disabled = *disabled
+
+	replaced := false
+	err = editConfigServers(*config, true, func(servers map[string]any) error {
+		if _, ok := servers[name]; ok {
+			if !*force {
+				return fmt.Errorf("server %q already exists in %s (use --force to replace it)", name, *config)
+			}
+			replaced = true
+		}
+		var entry any
+		_ = json.Unmarshal(mustJSON(s), &entry)
+		servers[name] = entry
+		return nil
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if replaced {
+		fmt.Printf("Replaced server %s in %s.\n", name, *config)
+	} else {
+		fmt.Printf("Added server %s to %s.\n", name, *config)
+	}
+	notifyRunningStacks(*config)
+}
+
+func mergeKV(base map[string]string, pairs []string, flagName string) (map[string]string, error) {
+	kv, err := parseKV(pairs, flagName)
+	if err != nil || len(kv) == 0 {
+		return base, err
+	}
+	if base == nil {
+		base = map[string]string{}
+	}
+	for k, v := range kv {
+		base[k] = v
+	}
+	return base, nil
+}
+
+// cmdServerEdit handles rm, enable and disable for one or more servers.
+func cmdServerEdit(op string, args []string) {
+	fs := flag.NewFlagSet("server "+op, flag.ExitOnError)
+	fs.Usage = func() { helpTopic("server") }
+	config := fs.String("config", "", "Config file (default: mcp.config.json / .yaml / .toml)")
+	names := parseInterspersed(fs, args)
+	if len(names) == 0 {
+		helpTopic("server")
+		os.Exit(2)
+	}
+	if *config == "" {
+		*config = defaultConfigPath()
+	}
+
+	var changed, unchanged []string
+	allDisabled := false
+	err := editConfigServers(*config, false, func(servers map[string]any) error {
+		var err error
+		if changed, unchanged, err = applyServerEdit(op, names, servers); err != nil {
+			return fmt.Errorf("%w in %s", err, *config)
+		}
+		allDisabled = true
+		for _, s := range servers {
+			if e, _ := s.(map[string]any); e == nil || e["disabled"] != true {
+				allDisabled = false
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	past := map[string]string{"rm": "Removed", "enable": "Enabled", "disable": "Disabled"}[op]
+	if len(unchanged) > 0 {
+		fmt.Printf("Already %s: %s\n", strings.ToLower(past), strings.Join(unchanged, ", "))
+	}
+	if len(changed) == 0 {
+		return
+	}
+	fmt.Printf("%s %s in %s.\n", past, strings.Join(changed, ", "), *config)
+	if allDisabled {
+		fmt.Println("Note: no enabled servers are left; 'up' will refuse to start this config.")
+	}
+	notifyRunningStacks(*config)
+}
+
+// applyServerEdit removes, enables or disables names in a config's "mcpServers" table. Nothing
+// changes unless every name exists; names already in the wanted state are reported as unchanged.
+func applyServerEdit(op string, names []string, servers map[string]any) (changed, unchanged []string, err error) {
+	for _, name := range names {
+		if _, ok := servers[name]; !ok {
+			return nil, nil, fmt.Errorf("no server %q", name)
+		}
+	}
+	for _, name := range names {
+		entry, _ := servers[name].(map[string]any)
+		wasDisabled := entry != nil && entry["disabled"] == true
+		switch {
+		case op == "rm":
+			delete(servers, name)
+		case op == "enable" && wasDisabled:
+			delete(entry, "disabled")
+		case op == "disable" && !wasDisabled && entry != nil:
+			entry["disabled"] = true
+		default:
+			unchanged = append(unchanged, name)
+			continue
+		}
+		changed = append(changed, name)
+	}
+	return changed, unchanged, nil
+}
+
+// notifyRunningStacks tells the user how a running stack on this config picks up the edit.
+func notifyRunningStacks(config string) {
+	abs, _ := filepath.Abs(config)
+	st, _ := currentState()
+	for _, inst := range st.Instances {
+		p, _ := filepath.Abs(inst.ConfigPath)
+		if p != abs || inst.SplitOf != "" || inst.McpoPID <= 0 {
+			continue
+		}
+		if state, _ := checkProc(inst.McpoPID, inst.McpoProc); state != procRunning {
+			continue
+		}
+		if inst.Reload {
+			fmt.Printf("Stack %s is running: mcpo reloads the config and /openapi.json is re-merged in a few seconds.\n", inst.Name)
+		} else {
+			fmt.Printf("Stack %s is running with --reload=false; restart it to apply the change.\n", inst.Name)
+		}
+	}
+}
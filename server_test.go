@@ -0,0 +1,126 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestEditConfigServers(t *testing.T) {
+	for _, ext := range []string{".json", ".yaml", ".toml"} {
+		t.Run(ext, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "mcp.config"+ext)
+			if err := editConfigServers(path, false, func(map[string]any) error { return nil }); err == nil {
+				t.Fatal("edited a missing config without create")
+			}
+			add := func(name string, s MCPServer) {
+				t.Helper()
+				err := editConfigServers(path, true, func(servers map[string]any) error {
+					var entry any
+					_ = json.Unmarshal(mustJSON(s), &entry)
+					servers[name] = entry
+					return nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			add("time", MCPServer{Command: "uvx", Args: []string{"mcp-server-time"}, Env: map[string]string{"TZ": "UTC"}})
+			add("remote", MCPServer{Type: "streamable-http", URL: "https://example.com/mcp", Headers: map[string]string{"Authorization": "Bearer ${TOKEN}"}})
+
+			steps := []struct {
+				op            string
+				names         []string
+				wantChanged   []string
+				wantUnchanged []string
+				wantErr       bool
+				wantEnabled   []string
+				wantAll       []string
+			}{
+				{"disable", []string{"time"}, []string{"time"}, nil, false, []string{"remote"}, []string{"remote", "time"}},
+				{"disable", []string{"time"}, nil, []string{"time"}, false, []string{"remote"}, []string{"remote", "time"}},
+				{"enable", []string{"time", "remote"}, []string{"time"}, []string{"remote"}, false, []string{"remote", "time"}, []string{"remote", "time"}},
+				{"rm", []string{"remote", "nope"}, nil, nil, true, []string{"remote", "time"}, []string{"remote", "time"}},
+				{"rm", []string{"remote"}, []string{"remote"}, nil, false, []string{"time"}, []string{"time"}},
+			}
+			for _, st := range steps {
+				before, _ := os.ReadFile(path)
+				var changed, unchanged []string
+				err := editConfigServers(path, false, func(servers map[string]any) error {
+					var err error
+					changed, unchanged, err = applyServerEdit(st.op, st.names, servers)
+					return err
+				})
+				if (err != nil) != st.wantErr {
+					t.Fatalf("%s %v: error = %v, wantErr %v", st.op, st.names, err, st.wantErr)
+				}
+				if err != nil {
+					if after, _ := os.ReadFile(path); string(after) != string(before) {
+						t.Errorf("%s %v: a failed edit rewrote the file", st.op, st.names)
+					}
+				} else if !reflect.DeepEqual(changed, st.wantChanged) || !reflect.DeepEqual(unchanged, st.wantUnchanged) {
+					t.Errorf("%s %v: changed %q unchanged %q, want %q %q", st.op, st.names, changed, unchanged, st.wantChanged, st.wantUnchanged)
+				}
+
+				cfg, err := loadConfig(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := sortedServerNames(cfg); !reflect.DeepEqual(got, st.wantAll) {
+					t.Errorf("%s %v: servers = %q, want %q", st.op, st.names, got, st.wantAll)
+				}
+				if got := sortedServerNames(cfg.enabled()); !reflect.DeepEqual(got, st.wantEnabled) {
+					t.Errorf("%s %v: enabled = %q, want %q", st.op, st.names, got, st.wantEnabled)
+				}
+			}
+
+			cfg, _ := loadConfig(path)
+			if s := cfg.MCPServers["time"]; s.Command != "uvx" || s.Env["TZ"] != "UTC" || s.Disabled {
+				t.Errorf("time after the round trip = %+v", s)
+			}
+			// Writes go through a temp file and a rename; nothing is left beside the config.
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != 1 {
+				t.Errorf("files in the config dir: %v", entries)
+			}
+			if fi, _ := os.Stat(path); fi.Mode().Perm() != 0o644 {
+				t.Errorf("config mode = %v, want 0644", fi.Mode().Perm())
+			}
+		})
+	}
+}
+
+// Keys other than mcpServers survive an edit.
+func TestEditConfigServersKeepsOtherKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp.config.json")
+	if err := os.WriteFile(path, []byte(`{"mcpServers":{"a":{"command":"x"}},"note":"keep me"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := editConfigServers(path, false, func(servers map[string]any) error {
+		_, _, err := applyServerEdit("disable", []string{"a"}, servers)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &doc); err != nil || doc["note"] != "keep me" {
+		t.Errorf("config after edit = %s", data)
+	}
+}
+
+func sortedServerNames(cfg MCPConfig) []string {
+	var names []string
+	for name := range cfg.MCPServers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,579 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ---------- spec history and `mcp-launch openapi diff` ----------
+//
+// Every merged spec a front proxy starts serving is kept as .mcp-launch/specs/<stack>/<UTC
+// time>.json, unless it equals the previous revision apart from servers[] (quick tunnel URLs
+// change on every run). `openapi diff` compares two revisions operation by operation and flags
+// the changes that break a Custom GPT built on the older one: removed, renamed or moved
+// operations, new required inputs, changed input types, and outputs that disappeared or changed.
+
+const (
+	specsDirName    = "specs"
+	specHistoryKeep = 100 // revisions kept per stack
+	specRevLayout   = "20060102T150405.000Z"
+)
+
+type specRevision struct {
+	ID   string // file name without .json, a sortable UTC timestamp
+	Path string
+}
+
+func specHistoryDir(stack string) string {
+	return filepath.Join(getStateDir(), specsDirName, stack)
+}
+
+// specRevisions lists a stack's saved specs, oldest first.
+func specRevisions(stack string) []specRevision {
+	entries, _ := os.ReadDir(specHistoryDir(stack))
+	var out []specRevision
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		out = append(out, specRevision{ID: id, Path: filepath.Join(specHistoryDir(stack), e.Name())})
+	}
+	slices.SortFunc(out, func(a, b specRevision) int { return strings.Compare(a.ID, b.ID) })
+	return out
+}
+
+// saveSpecRevision records spec as the stack's newest revision unless it is unchanged, and prunes
+// the oldest ones beyond specHistoryKeep.
+func saveSpecRevision(stack string, spec []byte) error {
+	revs := specRevisions(stack)
+	if n := len(revs); n > 0 {
+		if prev, err := os.ReadFile(revs[n-1].Path); err == nil && sameSpec(prev, spec) {
+			return nil
+		}
+	}
+	dir := specHistoryDir(stack)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	id := time.Now().UTC().Format(specRevLayout)
+	if err := writeFileAtomic(filepath.Join(dir, id+".json"), spec, 0o644); err != nil {
+		return err
+	}
+	for len(revs)+1 > specHistoryKeep {
+		_ = os.Remove(revs[0].Path)
+		revs = revs[1:]
+	}
+	return nil
+}
+
+// sameSpec compares two specs ignoring servers[].
+func sameSpec(a, b []byte) bool {
+	var da, db map[string]any
+	if json.Unmarshal(a, &da) != nil || json.Unmarshal(b, &db) != nil {
+		return false
+	}
+	delete(da, "servers")
+	delete(db, "servers")
+	return reflect.DeepEqual(da, db)
+}
+
+// loadSpecRev resolves a revision reference for a stack: latest, previous, -N (N-th newest),
+// live (what the running front proxy serves), a unique prefix of a revision ID, or a file path.
+func loadSpecRev(stack, ref string) (label string, spec *stackSpec, err error) {
+	var data []byte
+	revs := specRevisions(stack)
+	pick := func(fromEnd int) error {
+		if fromEnd < 1 || fromEnd > len(revs) {
+			return fmt.Errorf("stack %s has %d saved revision(s); %q is not one of them (see: mcp-launch openapi history %s)", stack, len(revs), ref, stack)
+		}
+		r := revs[len(revs)-fromEnd]
+		label = r.ID
+		data, err = os.ReadFile(r.Path)
+		return err
+	}
+	n, numErr := strconv.Atoi(ref)
+	switch {
+	case ref == "latest":
+		err = pick(1)
+	case ref == "previous":
+		err = pick(2)
+	case numErr == nil && n < 0:
+		err = pick(-n)
+	case ref == "live":
+		st, _ := currentState()
+		var inst Instance
+		if inst, err = findStack(st.Instances, stack); err == nil {
+			label = "live"
+			data, err = fetchFrontSpec(inst)
+		}
+	default:
+		if fi, serr := os.Stat(ref); serr == nil && !fi.IsDir() {
+			label = ref
+			data, err = os.ReadFile(ref)
+			break
+		}
+		var match []specRevision
+		for _, r := range revs {
+			if strings.HasPrefix(r.ID, ref) {
+				match = append(match, r)
+			}
+		}
+		switch len(match) {
+		case 0:
+			err = fmt.Errorf("no revision %q for stack %s (see: mcp-launch openapi history %s)", ref, stack, stack)
+		case 1:
+			label = match[0].ID
+			data, err = os.ReadFile(match[0].Path)
+		default:
+			err = fmt.Errorf("revision %q is ambiguous for stack %s (%d matches)", ref, stack, len(match))
+		}
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	spec, err = parseStackSpec(data)
+	return label, spec, err
+}
+
+// ---------- diffing ----------
+
+// specChange is one difference between two specs.
+type specChange struct {
+	Op       string `json:"operation"`
+	Kind     string `json:"kind"`            // added | removed | renamed | moved | changed
+	Where    string `json:"where,omitempty"` // query.x, body.a.b, response.items[], description
+	Detail   string `json:"detail,omitempty"`
+	Breaking bool   `json:"breaking"`
+}
+
+func (c specChange) String() string {
+	sym := "~"
+	switch c.Kind {
+	case "added":
+		sym = "+"
+	case "removed":
+		sym = "-"
+	}
+	line := fmt.Sprintf("  %s %s", sym, c.Op)
+	if c.Where != "" {
+		line += ": " + c.Where
+	}
+	if c.Detail != "" {
+		line += ": " + c.Detail
+	}
+	if c.Breaking {
+		line += "   [BREAKING]"
+	}
+	return line
+}
+
+// fieldInfo describes one input or output field, keyed by its dotted location.
+type fieldInfo struct {
+	Type     string
+	Required bool
+	Enum     []string
+}
+
+// opShape is what a client of an operation depends on.
+type opShape struct {
+	op      specOperation
+	inputs  map[string]fieldInfo // path.x, query.x, header.x, body, body.a, body.a.b, body.list[]
+	outputs map[string]fieldInfo // response, response.a, ...
+}
+
+func (s *stackSpec) shape(op specOperation) opShape {
+	sh := opShape{op: op, inputs: map[string]fieldInfo{}, outputs: map[string]fieldInfo{}}
+	for _, p := range op.Params {
+		sh.inputs[p.In+"."+p.Name] = s.fieldOf(p.Schema, p.Required)
+	}
+	if op.Body != nil {
+		s.flatten("body", op.Body, true, sh.inputs, 0)
+	}
+	if op.Response != nil {
+		s.flatten("response", op.Response, true, sh.outputs, 0)
+	}
+	return sh
+}
+
+func (s *stackSpec) fieldOf(schema map[string]any, required bool) fieldInfo {
+	schema = s.resolve(schema)
+	fi := fieldInfo{Type: s.typeName(schema, 0), Required: required}
+	if enum, ok := schema["enum"].([]any); ok {
+		for _, e := range enum {
+			fi.Enum = append(fi.Enum, string(mustJSON(e)))
+		}
+		slices.Sort(fi.Enum)
+	}
+	return fi
+}
+
+// flatten records schema and its properties (and array items) under dotted keys.
+func (s *stackSpec) flatten(at string, schema map[string]any, required bool, out map[string]fieldInfo, depth int) {
+	schema = s.resolve(schema)
+	out[at] = s.fieldOf(schema, required)
+	if depth >= 6 { // recursive models
+		return
+	}
+	props, _ := schema["properties"].(map[string]any)
+	req := stringList(schema["required"])
+	for k, p := range props {
+		s.flatten(at+"."+k, asObject(p), slices.Contains(req, k), out, depth+1)
+	}
+	if items := asObject(schema["items"]); items != nil {
+		s.flatten(at+"[]", items, true, out, depth+1)
+	}
+}
+
+// diffSpecs lists the changes from old to new, sorted by operation and location.
+func diffSpecs(old, new *stackSpec) []specChange {
+	oldOps := map[string]opShape{}
+	for _, op := range old.ops {
+		oldOps[op.ID] = old.shape(op)
+	}
+	newOps := map[string]opShape{}
+	for _, op := range new.ops {
+		newOps[op.ID] = new.shape(op)
+	}
+
+	var changes []specChange
+	var removed, added []string
+	for id, o := range oldOps {
+		n, ok := newOps[id]
+		if !ok {
+			removed = append(removed, id)
+			continue
+		}
+		changes = append(changes, diffShapes(id, o, n)...)
+	}
+	for id := range newOps {
+		if _, ok := oldOps[id]; !ok {
+			added = append(added, id)
+		}
+	}
+	slices.Sort(removed)
+	slices.Sort(added)
+
+	// A removed and an added operation are a rename when they have the same route, or failing
+	// that the same server and exactly the same inputs and outputs.
+	renamedTo := map[string]string{}
+	for _, sameRoute := range []bool{true, false} {
+		for _, r := range removed {
+			if _, done := renamedTo[r]; done {
+				continue
+			}
+			var cands []string
+			for _, a := range added {
+				if slices.Contains(mapValues(renamedTo), a) {
+					continue
+				}
+				if sameRoute && routeOf(oldOps[r].op) == routeOf(newOps[a].op) ||
+					!sameRoute && serverOf(oldOps[r].op) == serverOf(newOps[a].op) && sameShape(oldOps[r], newOps[a]) {
+					cands = append(cands, a)
+				}
+			}
+			if len(cands) == 1 {
+				renamedTo[r] = cands[0]
+			}
+		}
+	}
+	for _, r := range removed {
+		if a, ok := renamedTo[r]; ok {
+			detail := "was " + r
+			if o, n := routeOf(oldOps[r].op), routeOf(newOps[a].op); o != n {
+				detail += ", " + o + " → " + n
+			}
+			changes = append(changes, specChange{Op: a, Kind: "renamed", Detail: detail, Breaking: true})
+			for _, c := range diffShapes(a, oldOps[r], newOps[a]) {
+				if c.Kind != "moved" { // already in the rename
+					changes = append(changes, c)
+				}
+			}
+			continue
+		}
+		changes = append(changes, specChange{Op: r, Kind: "removed", Detail: routeOf(oldOps[r].op), Breaking: true})
+	}
+	for _, a := range added {
+		if !slices.Contains(mapValues(renamedTo), a) {
+			changes = append(changes, specChange{Op: a, Kind: "added", Detail: routeOf(newOps[a].op)})
+		}
+	}
+	slices.SortStableFunc(changes, func(a, b specChange) int {
+		if c := strings.Compare(a.Op, b.Op); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Where, b.Where)
+	})
+	return changes
+}
+
+// diffShapes compares one operation's route, description, inputs and outputs.
+func diffShapes(id string, o, n opShape) []specChange {
+	var out []specChange
+	if routeOf(o.op) != routeOf(n.op) {
+		out = append(out, specChange{Op: id, Kind: "moved", Detail: routeOf(o.op) + " → " + routeOf(n.op), Breaking: true})
+	}
+	if o.op.Summary != n.op.Summary {
+		out = append(out, specChange{Op: id, Kind: "changed", Where: "description", Detail: "text changed"})
+	}
+	out = append(out, diffFields(id, o.inputs, n.inputs, true)...)
+	out = append(out, diffFields(id, o.outputs, n.outputs, false)...)
+	return out
+}
+
+// diffFields compares inputs (what clients send) or outputs (what they read back). For inputs,
+// anything a client could no longer send as before is breaking; for outputs, anything it could
+// no longer rely on reading.
+func diffFields(id string, old, new map[string]fieldInfo, input bool) []specChange {
+	var out []specChange
+	change := func(where, detail string, breaking bool) {
+		out = append(out, specChange{Op: id, Kind: "changed", Where: where, Detail: detail, Breaking: breaking})
+	}
+	for _, k := range sortedKeys(old) {
+		o := old[k]
+		n, ok := new[k]
+		if !ok {
+			if _, parentKept := new[parentField(k)]; parentKept || parentField(k) == "" {
+				change(k, "removed", true)
+			}
+			continue
+		}
+		if o.Type != n.Type {
+			// Narrowing an untyped output or widening an input to any breaks nobody.
+			widened := input && n.Type == "any" || !input && o.Type == "any"
+			change(k, fmt.Sprintf("type %s → %s", o.Type, n.Type), !widened)
+		}
+		switch {
+		case !o.Required && n.Required:
+			change(k, "now required", input)
+		case o.Required && !n.Required:
+			change(k, "now optional", !input)
+		}
+		if len(o.Enum) == 0 && len(n.Enum) > 0 {
+			change(k, "now limited to: "+strings.Join(n.Enum, ", "), input)
+		}
+		if gone := without(o.Enum, n.Enum); len(gone) > 0 && len(n.Enum) > 0 {
+			change(k, "enum values removed: "+strings.Join(gone, ", "), input)
+		}
+		if extra := without(n.Enum, o.Enum); len(extra) > 0 && len(o.Enum) > 0 {
+			change(k, "enum values added: "+strings.Join(extra, ", "), !input)
+		}
+	}
+	for _, k := range sortedKeys(new) {
+		if _, ok := old[k]; ok {
+			continue
+		}
+		parent := parentField(k)
+		if _, parentExisted := old[parent]; parent != "" && !parentExisted {
+			continue // reported with its parent
+		}
+		n := new[k]
+		if input && n.Required {
+			change(k, "added, required "+n.Type, true)
+		} else {
+			change(k, "added "+n.Type, false)
+		}
+	}
+	return out
+}
+
+// parentField is the enclosing location of a dotted key ("body.a.b" → "body.a", "body" → "").
+func parentField(k string) string {
+	if strings.HasSuffix(k, "[]") {
+		return strings.TrimSuffix(k, "[]")
+	}
+	if i := strings.LastIndexByte(k, '.'); i >= 0 {
+		p := k[:i]
+		if p == "path" || p == "query" || p == "header" {
+			return ""
+		}
+		return p
+	}
+	return ""
+}
+
+func sameShape(a, b opShape) bool {
+	return a.op.Method == b.op.Method && reflect.DeepEqual(a.inputs, b.inputs) && reflect.DeepEqual(a.outputs, b.outputs)
+}
+
+func routeOf(op specOperation) string { return op.Method + " " + op.Path }
+
+func serverOf(op specOperation) string {
+	seg, _, _ := strings.Cut(strings.TrimPrefix(op.Path, "/"), "/")
+	return seg
+}
+
+func sortedKeys(m map[string]fieldInfo) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// without returns the items of a that are not in b.
+func without(a, b []string) []string {
+	var out []string
+	for _, x := range a {
+		if !slices.Contains(b, x) {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// ---------- commands ----------
+
+func cmdOpenAPIHistory(args []string) {
+	fs := flag.NewFlagSet("openapi history", flag.ExitOnError)
+	fs.Usage = func() { helpTopic("openapi") }
+	_ = fs.Parse(args)
+	stacks := historyStacks(fs.Arg(0))
+	if len(stacks) == 0 {
+		fmt.Println("No saved specs under", filepath.Join(getStateDir(), specsDirName), "(they are recorded while `mcp-launch up` runs).")
+		return
+	}
+	for i, stack := range stacks {
+		if i > 0 {
+			fmt.Println()
+		}
+		revs := specRevisions(stack)
+		fmt.Printf("%s (%d revision(s)):\n", stack, len(revs))
+		for j, r := range revs {
+			ops := "?"
+			if data, err := os.ReadFile(r.Path); err == nil {
+				if spec, err := parseStackSpec(data); err == nil {
+					ops = strconv.Itoa(len(spec.ops))
+				}
+			}
+			fmt.Printf("  %-3d %s  %s operation(s)\n", j-len(revs), r.ID, ops)
+		}
+	}
+}
+
+// historyStacks is the named stack, or every stack with saved specs.
+func historyStacks(name string) []string {
+	if name != "" {
+		return []string{name}
+	}
+	entries, _ := os.ReadDir(filepath.Join(getStateDir(), specsDirName))
+	var out []string
+	for _, e := range entries {
+		if e.IsDir() {
+			out = append(out, e.Name())
+		}
+	}
+	return out
+}
+
+func cmdOpenAPIDiff(args []string) {
+	fs := flag.NewFlagSet("openapi diff", flag.ExitOnError)
+	fs.Usage = func() { helpTopic("openapi") }
+	from := fs.String("from", "previous", "Older revision: latest | previous | -N | live | ID prefix | FILE")
+	to := fs.String("to", "latest", "Newer revision (same forms as --from)")
+	asJSON := fs.Bool("json", false, "Print the changes as JSON")
+	stack := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		stack, args = args[0], args[1:]
+	}
+	_ = fs.Parse(args)
+	if stack == "" {
+		stack = fs.Arg(0)
+	}
+
+	stacks := historyStacks(stack)
+	switch {
+	case stack == "" && isFile(*from) && isFile(*to):
+		stacks = []string{""} // two spec files (e.g. in CI); no history needed
+	case len(stacks) == 0:
+		fmt.Println("No saved specs under", filepath.Join(getStateDir(), specsDirName), "(they are recorded while `mcp-launch up` runs).")
+		os.Exit(2)
+	}
+
+	type stackDiff struct {
+		Stack    string       `json:"stack,omitempty"`
+		From     string       `json:"from"`
+		To       string       `json:"to"`
+		Changes  []specChange `json:"changes"`
+		Breaking int          `json:"breaking"`
+	}
+	var results []stackDiff
+	breaking, failed := 0, false
+	for _, st := range stacks {
+		fromLabel, oldSpec, err := loadSpecRev(st, *from)
+		if err == nil {
+			var toLabel string
+			var newSpec *stackSpec
+			if toLabel, newSpec, err = loadSpecRev(st, *to); err == nil {
+				d := stackDiff{Stack: st, From: fromLabel, To: toLabel, Changes: diffSpecs(oldSpec, newSpec)}
+				for _, c := range d.Changes {
+					if c.Breaking {
+						d.Breaking++
+					}
+				}
+				breaking += d.Breaking
+				results = append(results, d)
+				continue
+			}
+		}
+		// Without a named stack, a stack with a single revision has nothing to compare yet.
+		if stack == "" && len(specRevisions(st)) < 2 {
+			continue
+		}
+		fmt.Println(err)
+		failed = true
+	}
+
+	if *asJSON {
+		if results == nil {
+			results = []stackDiff{}
+		}
+		out, _ := json.MarshalIndent(results, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		for i, d := range results {
+			if i > 0 {
+				fmt.Println()
+			}
+			name := d.Stack
+			if name == "" {
+				name = "spec"
+			}
+			fmt.Printf("%s: %s → %s\n", name, d.From, d.To)
+			for _, c := range d.Changes {
+				fmt.Println(c)
+			}
+			switch {
+			case len(d.Changes) == 0:
+				fmt.Println("  No changes.")
+			default:
+				fmt.Printf("  %d change(s), %d breaking.\n", len(d.Changes), d.Breaking)
+			}
+		}
+		if len(results) == 0 && !failed {
+			fmt.Println("Nothing to compare yet: each stack needs two saved revisions (see: mcp-launch openapi history).")
+		}
+	}
+	switch {
+	case failed:
+		os.Exit(2)
+	case breaking > 0:
+		os.Exit(1)
+	}
+}
+
+func isFile(p string) bool {
+	fi, err := os.Stat(p)
+	return err == nil && !fi.IsDir()
+}
@@ -0,0 +1,128 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffFields(t *testing.T) {
+	str := fieldInfo{Type: "string"}
+	req := fieldInfo{Type: "string", Required: true}
+	tests := []struct {
+		name     string
+		old, new map[string]fieldInfo
+		input    bool
+		want     []string // Where: Detail, with a trailing ! when breaking
+	}{
+		{"unchanged", map[string]fieldInfo{"query.q": str}, map[string]fieldInfo{"query.q": str}, true, nil},
+		{"input removed", map[string]fieldInfo{"query.q": str}, map[string]fieldInfo{}, true, []string{"query.q: removed!"}},
+		{"output removed", map[string]fieldInfo{"response": {Type: "object"}, "response.id": str}, map[string]fieldInfo{"response": {Type: "object"}}, false,
+			[]string{"response.id: removed!"}},
+		{"child of a removed field is not repeated", map[string]fieldInfo{"body": {Type: "object"}, "body.a": {Type: "object"}, "body.a.b": str},
+			map[string]fieldInfo{"body": {Type: "object"}}, true, []string{"body.a: removed!"}},
+		{"input now required", map[string]fieldInfo{"query.q": str}, map[string]fieldInfo{"query.q": req}, true, []string{"query.q: now required!"}},
+		{"input now optional", map[string]fieldInfo{"query.q": req}, map[string]fieldInfo{"query.q": str}, true, []string{"query.q: now optional"}},
+		{"output now optional", map[string]fieldInfo{"response.id": req}, map[string]fieldInfo{"response.id": str}, false, []string{"response.id: now optional!"}},
+		{"input type changed", map[string]fieldInfo{"query.n": {Type: "integer"}}, map[string]fieldInfo{"query.n": str}, true,
+			[]string{"query.n: type integer → string!"}},
+		{"input widened to any", map[string]fieldInfo{"query.n": {Type: "integer"}}, map[string]fieldInfo{"query.n": {Type: "any"}}, true,
+			[]string{"query.n: type integer → any"}},
+		{"output narrowed from any", map[string]fieldInfo{"response": {Type: "any"}}, map[string]fieldInfo{"response": {Type: "object"}}, false,
+			[]string{"response: type any → object"}},
+		{"output widened to any", map[string]fieldInfo{"response": {Type: "object"}}, map[string]fieldInfo{"response": {Type: "any"}}, false,
+			[]string{"response: type object → any!"}},
+		{"new optional input", map[string]fieldInfo{}, map[string]fieldInfo{"query.q": str}, true, []string{"query.q: added string"}},
+		{"new required input", map[string]fieldInfo{}, map[string]fieldInfo{"query.q": req}, true, []string{"query.q: added, required string!"}},
+		{"new output", map[string]fieldInfo{"response": {Type: "object"}}, map[string]fieldInfo{"response": {Type: "object"}, "response.x": req}, false,
+			[]string{"response.x: added string"}},
+		{"input enum introduced", map[string]fieldInfo{"query.u": str}, map[string]fieldInfo{"query.u": {Type: "string", Enum: []string{`"c"`}}}, true,
+			[]string{`query.u: now limited to: "c"!`}},
+		{"input enum value removed", map[string]fieldInfo{"query.u": {Type: "string", Enum: []string{`"c"`, `"f"`}}},
+			map[string]fieldInfo{"query.u": {Type: "string", Enum: []string{`"c"`}}}, true, []string{`query.u: enum values removed: "f"!`}},
+		{"input enum value added", map[string]fieldInfo{"query.u": {Type: "string", Enum: []string{`"c"`}}},
+			map[string]fieldInfo{"query.u": {Type: "string", Enum: []string{`"c"`, `"f"`}}}, true, []string{`query.u: enum values added: "f"`}},
+		{"output enum value added", map[string]fieldInfo{"response.s": {Type: "string", Enum: []string{`"ok"`}}},
+			map[string]fieldInfo{"response.s": {Type: "string", Enum: []string{`"ok"`, `"late"`}}}, false, []string{`response.s: enum values added: "late"!`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range diffFields("op", tt.old, tt.new, tt.input) {
+				s := c.Where + ": " + c.Detail
+				if c.Breaking {
+					s += "!"
+				}
+				got = append(got, s)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// diffTestSpec wraps paths in a minimal merged spec.
+func diffTestSpec(t *testing.T, paths string) *stackSpec {
+	t.Helper()
+	spec, err := parseStackSpec([]byte(`{"openapi":"3.1.0","paths":{` + paths + `}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestDiffSpecs(t *testing.T) {
+	const body = `"requestBody":{"content":{"application/json":{"schema":{"type":"object","properties":{"x":{"type":"string"}}}}}}`
+	tests := []struct {
+		name     string
+		old, new string
+		want     []string
+	}{
+		{"no change",
+			`"/a/one":{"post":{"operationId":"a__one","summary":"One"}}`,
+			`"/a/one":{"post":{"operationId":"a__one","summary":"One"}}`,
+			nil},
+		{"removed and added",
+			`"/a/one":{"post":{"operationId":"a__one"}}`,
+			`"/b/two":{"post":{"operationId":"b__two",` + body + `}}`,
+			[]string{"  - a__one: POST /a/one   [BREAKING]", "  + b__two: POST /b/two"}},
+		{"renamed on the same route",
+			`"/a/one":{"post":{"operationId":"a__one"}}`,
+			`"/a/one":{"post":{"operationId":"a__uno"}}`,
+			[]string{"  ~ a__uno: was a__one   [BREAKING]"}},
+		{"renamed and moved with the same shape",
+			`"/a/one":{"post":{"operationId":"a__one",` + body + `}}`,
+			`"/a/uno":{"post":{"operationId":"a__uno",` + body + `}}`,
+			[]string{"  ~ a__uno: was a__one, POST /a/one → POST /a/uno   [BREAKING]"}},
+		{"same shape on another server is not a rename",
+			`"/a/one":{"post":{"operationId":"a__one",` + body + `}}`,
+			`"/b/one":{"post":{"operationId":"b__one",` + body + `}}`,
+			[]string{"  - a__one: POST /a/one   [BREAKING]", "  + b__one: POST /b/one"}},
+		{"moved",
+			`"/a/one":{"post":{"operationId":"a__one"}}`,
+			`"/a/one":{"put":{"operationId":"a__one"}}`,
+			[]string{"  ~ a__one: POST /a/one → PUT /a/one   [BREAKING]"}},
+		{"description and new optional field",
+			`"/a/one":{"post":{"operationId":"a__one","summary":"One",` + body + `}}`,
+			`"/a/one":{"post":{"operationId":"a__one","summary":"The one",` + strings.Replace(body, `"x":`, `"y":{"type":"integer"},"x":`, 1) + `}}`,
+			[]string{"  ~ a__one: body.y: added integer", "  ~ a__one: description: text changed"}},
+		{"body added to an operation without one",
+			`"/a/one":{"post":{"operationId":"a__one"}}`,
+			`"/a/one":{"post":{"operationId":"a__one",` + body + `}}`,
+			[]string{"  ~ a__one: body: added, required object   [BREAKING]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range diffSpecs(diffTestSpec(t, tt.old), diffTestSpec(t, tt.new)) {
+				got = append(got, c.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
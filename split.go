@@ -0,0 +1,160 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ---------- auto-split (Custom GPT action limit) ----------
+
+const defaultSplitLimit = 30
+
+// autoSplitFlag is --auto-split[=N]: bare flag means the default limit, 0 disables.
+type autoSplitFlag int
+
+func (a *autoSplitFlag) String() string   { return strconv.Itoa(int(*a)) }
+func (a *autoSplitFlag) IsBoolFlag() bool { return true }
+func (a *autoSplitFlag) Set(v string) error {
+	switch v {
+	case "true":
+		*a = defaultSplitLimit
+	case "false":
+		*a = 0
+	default:
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("want a non-negative operation limit, got %q", v)
+		}
+		*a = autoSplitFlag(n)
+	}
+	return nil
+}
+
+// splitPart is one virtual stack's share of a merged spec.
+type splitPart struct {
+	Routes  []string       // routeKey values served by this part
+	Servers map[string]int // MCP server → operations it contributes
+}
+
+// serverNames lists the servers in the part, sorted.
+func (p splitPart) serverNames() []string {
+	names := make([]string, 0, len(p.Servers))
+	for n := range p.Servers {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// describe renders e.g. "serena (18), time (2)".
+func (p splitPart) describe() string {
+	var parts []string
+	for _, n := range p.serverNames() {
+		parts = append(parts, fmt.Sprintf("%s (%d)", n, p.Servers[n]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// planSplit bin-packs the operations of a merged spec into parts of at most limit operations.
+// Servers are kept whole when they fit; a server larger than limit is cut into chunks.
+// It returns a single part when the spec already fits.
+func planSplit(spec []byte, servers []string, limit int) ([]splitPart, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, err
+	}
+	paths, _ := doc["paths"].(map[string]any)
+
+	byServer := map[string][]string{}
+	for p, v := range paths {
+		item, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		srv := serverForPath(p, servers)
+		for method := range item {
+			if _, ok := httpMethods[strings.ToLower(method)]; ok {
+				byServer[srv] = append(byServer[srv], routeKey(method, p))
+			}
+		}
+	}
+
+	type chunk struct {
+		server string
+		routes []string
+	}
+	srvNames := make([]string, 0, len(byServer))
+	for srv := range byServer {
+		srvNames = append(srvNames, srv)
+	}
+	slices.Sort(srvNames)
+	var chunks []chunk
+	for _, srv := range srvNames {
+		routes := byServer[srv]
+		slices.Sort(routes)
+		for len(routes) > limit {
+			chunks = append(chunks, chunk{srv, routes[:limit]})
+			routes = routes[limit:]
+		}
+		chunks = append(chunks, chunk{srv, routes})
+	}
+	// First-fit decreasing.
+	slices.SortStableFunc(chunks, func(a, b chunk) int { return len(b.routes) - len(a.routes) })
+	var bins []splitPart
+	for _, c := range chunks {
+		placed := false
+		for i := range bins {
+			if len(bins[i].Routes)+len(c.routes) <= limit {
+				bins[i].Routes = append(bins[i].Routes, c.routes...)
+				bins[i].Servers[c.server] += len(c.routes)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			bins = append(bins, splitPart{
+				Routes:  slices.Clone(c.routes),
+				Servers: map[string]int{c.server: len(c.routes)},
+			})
+		}
+	}
+	return bins, nil
+}
+
+// serverForPath maps a merged path (/<server>/...) to its server, preferring the longest name.
+func serverForPath(p string, servers []string) string {
+	best := ""
+	for _, s := range servers {
+		if strings.HasPrefix(p, "/"+s+"/") && len(s) > len(best) {
+			best = s
+		}
+	}
+	return best
+}
+
+// splitPublicURL derives the public URL of auto-split part n (2, 3, ...) on a named tunnel from
+// its parent's by suffixing the first hostname label: https://tools.example.com becomes
+// https://tools-2.example.com. It is "" when the parent has no URL to derive from.
+func splitPublicURL(parent string, n int) string {
+	u, err := url.Parse(parent)
+	if parent == "" || err != nil || u.Hostname() == "" {
+		return ""
+	}
+	label, rest, _ := strings.Cut(u.Hostname(), ".")
+	host := fmt.Sprintf("%s-%d", label, n)
+	if rest != "" {
+		host += "." + rest
+	}
+	if port := u.Port(); port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+	u.Host = host
+	return strings.TrimRight(u.String(), "/")
+}
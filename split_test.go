@@ -0,0 +1,79 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// splitTestSpec builds a merged spec with ops[server] POST operations under /<server>/.
+func splitTestSpec(ops map[string]int) []byte {
+	paths := map[string]any{}
+	for srv, n := range ops {
+		for i := 0; i < n; i++ {
+			paths[fmt.Sprintf("/%s/op%02d", srv, i)] = map[string]any{"post": map[string]any{}, "parameters": []any{}}
+		}
+	}
+	return mustJSON(map[string]any{"openapi": "3.1.0", "paths": paths})
+}
+
+func TestPlanSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		ops   map[string]int
+		limit int
+		want  []map[string]int // Servers of each part, in order
+	}{
+		{"fits", map[string]int{"a": 3, "b": 2}, 10, []map[string]int{{"a": 3, "b": 2}}},
+		{"servers kept whole", map[string]int{"a": 6, "b": 5, "c": 4}, 10, []map[string]int{{"a": 6, "c": 4}, {"b": 5}}},
+		{"large server cut", map[string]int{"big": 25, "small": 3}, 10, []map[string]int{{"big": 10}, {"big": 10}, {"big": 5, "small": 3}}},
+		{"prefix names", map[string]int{"git": 2, "github": 2}, 2, []map[string]int{{"git": 2}, {"github": 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var servers []string
+			for s := range tt.ops {
+				servers = append(servers, s)
+			}
+			parts, err := planSplit(splitTestSpec(tt.ops), servers, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []map[string]int
+			for _, p := range parts {
+				if len(p.Routes) > tt.limit {
+					t.Errorf("part %v has %d routes, limit %d", p.Servers, len(p.Routes), tt.limit)
+				}
+				got = append(got, p.Servers)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := planSplit([]byte("not json"), nil, 10); err == nil {
+		t.Error("planSplit accepted an invalid spec")
+	}
+}
+
+func TestSplitPublicURL(t *testing.T) {
+	tests := []struct {
+		parent string
+		n      int
+		want   string
+	}{
+		{"https://tools.example.com", 2, "https://tools-2.example.com"},
+		{"https://tools.example.com/", 3, "https://tools-3.example.com"},
+		{"https://tools.example.com:8443", 2, "https://tools-2.example.com:8443"},
+		{"https://localhost", 2, "https://localhost-2"},
+		{"", 2, ""},
+		{"not a url", 2, ""},
+	}
+	for _, tt := range tests {
+		if got := splitPublicURL(tt.parent, tt.n); got != tt.want {
+			t.Errorf("splitPublicURL(%q, %d) = %q, want %q", tt.parent, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ---------- state store ----------
+//
+// .mcp-launch/state.json belongs to one `up` at a time: it holds owner.lock for as long as it
+// runs, keeps the only in-memory copy and is the only process that records PIDs. Every write,
+// from `up` or from commands like `down`, takes state.lock and replaces the file by renaming a
+// temp file, so readers never see a torn file and read-modify-write cycles cannot interleave.
+
+const (
+	stateVersion  = 2 // 1: single-instance fields at the top level
+	stateLockName = "state.lock"
+	ownerLockName = "owner.lock"
+)
+
+type State struct {
+	Version   int        `json:"version"`
+	OwnerPID  int        `json:"owner_pid,omitempty"` // the `up` (or daemon) running Instances
+	APIKey    string     `json:"api_key,omitempty"`   // last --shared-key, reused by the next run
+	Instances []Instance `json:"instances"`
+	StartedAt string     `json:"started_at"`
+}
+
+// legacyState is the version-1 single-instance layout, migrated into Instances on load.
+type legacyState struct {
+	ConfigPath     string   `json:"config_path"`
+	FrontPort      int      `json:"front_port"`
+	McpoPort       int      `json:"mcpo_port"`
+	PublicURL      string   `json:"public_url"`
+	TunnelMode     string   `json:"tunnel_mode"`
+	TunnelName     string   `json:"tunnel_name"`
+	CloudflaredPID int      `json:"cloudflared_pid"`
+	McpoPID        int      `json:"mcpo_pid"`
+	ToolNames      []string `json:"tool_names"`
+}
+
+func ensureStateDir() string {
+	dir := getStateDir()
+	_ = os.MkdirAll(dir, 0o755)
+	return dir
+}
+
+func getStateDir() string { return filepath.Join(".", stateDirName) }
+func statePath() string   { return filepath.Join(getStateDir(), stateFileName) }
+
+// lockStateFile opens (creating) a lock file in the state dir and flocks it.
+func lockStateFile(name string, how int) (*os.File, error) {
+	ensureStateDir()
+	f, err := os.OpenFile(filepath.Join(getStateDir(), name), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), how); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func unlockStateFile(f *os.File) {
+	_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	_ = f.Close()
+}
+
+// claimState makes this process the state's owner until release is called (or it exits).
+func claimState() (release func(), err error) {
+	f, err := lockStateFile(ownerLockName, syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		owner := "another process"
+		if st, _ := readState(); st.OwnerPID > 0 {
+			owner = fmt.Sprintf("pid %d", st.OwnerPID)
+		}
+		return nil, fmt.Errorf("another `mcp-launch up` (%s) is running in this directory; stop it with `mcp-launch down` first", owner)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func() { unlockStateFile(f) }, nil
+}
+
+// stopOwner asks a running foreground `up` to shut down (SIGTERM) and waits until it has let go
+// of the state; it then holds ownership itself until release is called.
+func stopOwner() (release func(), err error) {
+	release, err = claimState()
+	if err == nil {
+		return release, nil
+	}
+	st, _ := readState()
+	if st.OwnerPID <= 0 {
+		return nil, err
+	}
+	if err := syscall.Kill(st.OwnerPID, syscall.SIGTERM); err != nil {
+		return nil, fmt.Errorf("could not signal mcp-launch up (pid %d): %w", st.OwnerPID, err)
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		time.Sleep(200 * time.Millisecond)
+		if release, err = claimState(); err == nil {
+			for _, inst := range st.Instances {
+				fmt.Println("Stopped stack", inst.Name)
+			}
+			fmt.Println("Stopped mcp-launch up (pid", st.OwnerPID, ")")
+			return release, nil
+		}
+	}
+	return nil, fmt.Errorf("mcp-launch up (pid %d) did not stop within 30s", st.OwnerPID)
+}
+
+// decodeState parses state.json, migrating older schema versions.
+func decodeState(data []byte) (State, error) {
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return State{}, err
+	}
+	if st.Version > stateVersion {
+		return State{}, fmt.Errorf("schema version %d is newer than this mcp-launch understands (%d)", st.Version, stateVersion)
+	}
+	if st.Version < 2 {
+		var old legacyState
+		_ = json.Unmarshal(data, &old)
+		if len(st.Instances) == 0 && (old.FrontPort > 0 || old.McpoPID > 0 || old.CloudflaredPID > 0) {
+			st.Instances = []Instance{{
+				Name:           nameFromPath(old.ConfigPath, 0),
+				ConfigPath:     old.ConfigPath,
+				FrontPort:      old.FrontPort,
+				McpoPort:       old.McpoPort,
+				APIKey:         st.APIKey,
+				PublicURL:      old.PublicURL,
+				TunnelMode:     old.TunnelMode,
+				TunnelName:     old.TunnelName,
+				CloudflaredPID: old.CloudflaredPID,
+				McpoPID:        old.McpoPID,
+				ToolNames:      old.ToolNames,
+			}}
+		}
+	}
+	st.Version = stateVersion
+	return st, nil
+}
+
+// readState reads state.json without locking; a missing file is an empty state.
+func readState() (State, error) {
+	data, err := os.ReadFile(statePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return State{Version: stateVersion, StartedAt: time.Now().Format(time.RFC3339)}, nil
+	}
+	if err != nil {
+		return State{}, err
+	}
+	st, err := decodeState(data)
+	if err != nil {
+		return State{}, fmt.Errorf("%s: %w", statePath(), err)
+	}
+	return st, nil
+}
+
+// writeState replaces state.json atomically; the caller holds state.lock.
+func writeState(st *State) error {
+	st.Version = stateVersion
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	// The file holds API keys.
+	return writeFileAtomic(statePath(), append(data, '\n'), 0o600)
+}
+
+func loadState() State {
+	f, err := lockStateFile(stateLockName, syscall.LOCK_SH)
+	if err == nil {
+		defer unlockStateFile(f)
+	}
+	st, err := readState()
+	if err != nil {
+		fmt.Println("Could not read state:", err)
+		return State{Version: stateVersion, StartedAt: time.Now().Format(time.RFC3339)}
+	}
+	return st
+}
+
+func saveState(st *State) {
+	f, err := lockStateFile(stateLockName, syscall.LOCK_EX)
+	if err != nil {
+		fmt.Println("Could not lock state:", err)
+		return
+	}
+	defer unlockStateFile(f)
+	if err := writeState(st); err != nil {
+		fmt.Println("Could not save state:", err)
+	}
+}
+
+func saveStateMulti(st *State, instances []Instance) {
+	st.Instances = instances
+	saveState(st)
+}
+
+// updateState applies fn to the current state.json under the lock (for commands other than
+// the owning `up`, which saves its own copy).
+func updateState(fn func(*State)) error {
+	f, err := lockStateFile(stateLockName, syscall.LOCK_EX)
+	if err != nil {
+		return err
+	}
+	defer unlockStateFile(f)
+	st, err := readState()
+	if err != nil {
+		return err
+	}
+	fn(&st)
+	return writeState(&st)
+}
+
+// ---------- `mcp-launch gc` ----------
+
+// instanceAlive reports whether any process recorded for inst may still be running. Split
+// parts have no processes of their own and live as long as their parent stack.
+func instanceAlive(inst Instance, all []Instance) bool {
+	for _, p := range []struct {
+		pid int
+		rec *procStamp
+	}{{inst.McpoPID, inst.McpoProc}, {inst.CloudflaredPID, inst.CloudflaredProc}} {
+		if p.pid <= 0 {
+			continue
+		}
+		if state, _ := checkProc(p.pid, p.rec); state == procRunning || state == procUnverified {
+			return true
+		}
+	}
+	if inst.SplitOf != "" {
+		for _, parent := range all {
+			if parent.Name == inst.SplitOf && parent.SplitOf == "" {
+				return instanceAlive(parent, all)
+			}
+		}
+	}
+	return false
+}
+
+func cmdGC() {
+	fs := flag.NewFlagSet("gc", flag.ExitOnError)
+	fs.Usage = func() { helpTopic("gc") }
+	dryRun := fs.Bool("dry-run", false, "Only show what would be removed")
+	_ = fs.Parse(os.Args[2:])
+
+	if ds, err := daemonState(); err == nil {
+		fmt.Printf("A detached mcp-launch (pid %d) owns the state; nothing to collect.\n", ds.PID)
+		return
+	}
+	release, err := claimState()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer release()
+
+	st := loadState()
+	var kept []Instance
+	for _, inst := range st.Instances {
+		if instanceAlive(inst, st.Instances) {
+			kept = append(kept, inst)
+			continue
+		}
+		fmt.Printf("Pruned stack %s (%s; no recorded process is running)\n", inst.Name, filepath.Base(inst.ConfigPath))
+	}
+	if len(kept) > 0 {
+		names := make([]string, len(kept))
+		for i, inst := range kept {
+			names[i] = inst.Name
+		}
+		fmt.Printf("Kept %s: processes are still running (stop them with `mcp-launch down`).\n", strings.Join(names, ", "))
+	}
+
+	// Files a crashed run leaves behind: the control socket, daemon.pid and resolved configs.
+	var stale []string
+	for _, p := range []string{controlSockPath(), daemonPIDPath()} {
+		if _, err := os.Stat(p); err == nil {
+			stale = append(stale, p)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(getStateDir(), runDirName)); err == nil && len(kept) == 0 {
+		stale = append(stale, filepath.Join(getStateDir(), runDirName))
+	}
+	for _, p := range stale {
+		fmt.Println("Removed", p)
+	}
+	if *dryRun {
+		fmt.Println("Dry run: nothing was changed.")
+		return
+	}
+	for _, p := range stale {
+		_ = os.RemoveAll(p)
+	}
+	if len(kept) == len(st.Instances) && st.OwnerPID == 0 {
+		return
+	}
+	err = updateState(func(s *State) {
+		s.Instances = kept
+		s.OwnerPID = 0
+	})
+	if err != nil {
+		fmt.Println("Could not update state:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeState(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []Instance
+		wantKey string
+		wantErr bool
+	}{
+		{"v1 running stack", `{"api_key":"k","config_path":"/srv/mcp.config.json","front_port":8000,"mcpo_port":8800,
+			"public_url":"https://t.example.com","tunnel_mode":"named","tunnel_name":"t","cloudflared_pid":11,"mcpo_pid":12,
+			"tool_names":["time"],"started_at":"2026-01-01T00:00:00Z"}`,
+			[]Instance{{
+				Name: "mcp-config", ConfigPath: "/srv/mcp.config.json", FrontPort: 8000, McpoPort: 8800, APIKey: "k",
+				PublicURL: "https://t.example.com", TunnelMode: "named", TunnelName: "t", CloudflaredPID: 11, McpoPID: 12,
+				ToolNames: []string{"time"},
+			}}, "k", false},
+		{"v1 after down", `{"version":1,"api_key":"k","config_path":"/srv/mcp.config.json","tunnel_mode":"quick"}`, nil, "k", false},
+		{"v1 with instances keeps them", `{"instances":[{"name":"a","front_port":8000}],"front_port":9000}`,
+			[]Instance{{Name: "a", FrontPort: 8000}}, "", false},
+		{"v2", `{"version":2,"instances":[{"name":"a","front_port":8000,"mcpo_pid":5}]}`,
+			[]Instance{{Name: "a", FrontPort: 8000, McpoPID: 5}}, "", false},
+		{"v2 ignores top-level fields", `{"version":2,"instances":[],"front_port":9000,"mcpo_pid":5}`, []Instance{}, "", false},
+		{"newer version", `{"version":3,"instances":[]}`, nil, "", true},
+		{"not json", `{"version":`, nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, err := decodeState([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeState error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if st.Version != stateVersion {
+				t.Errorf("version = %d, want %d", st.Version, stateVersion)
+			}
+			if st.APIKey != tt.wantKey {
+				t.Errorf("api key = %q, want %q", st.APIKey, tt.wantKey)
+			}
+			if !reflect.DeepEqual(st.Instances, tt.want) {
+				t.Errorf("instances = %+v\nwant        %+v", st.Instances, tt.want)
+			}
+		})
+	}
+}
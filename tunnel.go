@@ -0,0 +1,203 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ---------- named tunnels ----------
+//
+// All stacks on one named tunnel share a single cloudflared. `up` writes an ingress config to
+// .mcp-launch/cloudflared/<tunnel>.yml that routes each stack's --public-url hostname to its
+// front port, instead of running one `cloudflared tunnel run NAME` per stack: those would all
+// register as connectors of the same tunnel and serve requests for each other's hostnames.
+
+const tunnelDirName = "cloudflared"
+
+type ingressRule struct {
+	Hostname string `yaml:"hostname,omitempty"`
+	Service  string `yaml:"service"`
+}
+
+// cloudflaredConfig is the subset of cloudflared's config file that mcp-launch reads and writes.
+type cloudflaredConfig struct {
+	Tunnel          string        `yaml:"tunnel"`
+	CredentialsFile string        `yaml:"credentials-file,omitempty"`
+	Ingress         []ingressRule `yaml:"ingress"`
+}
+
+// userCloudflaredConfig returns the tunnel and credentials file named in cloudflared's own
+// default config, if there is one.
+func userCloudflaredConfig() (tunnel, creds string) {
+	home, _ := os.UserHomeDir()
+	for _, p := range []string{
+		filepath.Join(home, ".cloudflared", "config.yml"),
+		filepath.Join(home, ".cloudflared", "config.yaml"),
+		"/etc/cloudflared/config.yml",
+	} {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		var c cloudflaredConfig
+		if yaml.Unmarshal(data, &c) == nil && c.Tunnel != "" {
+			return c.Tunnel, c.CredentialsFile
+		}
+	}
+	return "", ""
+}
+
+// writeIngressConfig generates the cloudflared config for one tunnel and the stacks on it.
+func writeIngressConfig(name string, group []*Instance, verbose bool) (string, error) {
+	cfg := cloudflaredConfig{Tunnel: name}
+	userTunnel, userCreds := userCloudflaredConfig()
+	if cfg.Tunnel == "" {
+		cfg.Tunnel = userTunnel
+	}
+	if cfg.Tunnel == "" {
+		return "", errors.New("no tunnel name (pass --tunnel-name)")
+	}
+	if userTunnel == cfg.Tunnel {
+		// Otherwise cloudflared finds <tunnel-id>.json in its default directories.
+		cfg.CredentialsFile = userCreds
+	}
+
+	routed := map[string]string{} // hostname → stack
+	var unrouted []string
+	for _, inst := range group {
+		u, err := url.Parse(inst.PublicURL)
+		if inst.PublicURL == "" || err != nil || u.Hostname() == "" {
+			unrouted = append(unrouted, inst.Name)
+			continue
+		}
+		host := u.Hostname()
+		if other, ok := routed[host]; ok {
+			fmt.Printf("[tunnel#%s] %s is already routed to stack %s; not routing it here\n", inst.Name, host, other)
+			continue
+		}
+		routed[host] = inst.Name
+		cfg.Ingress = append(cfg.Ingress, ingressRule{Hostname: host, Service: fmt.Sprintf("http://127.0.0.1:%d", inst.FrontPort)})
+	}
+	if len(cfg.Ingress) == 0 {
+		return "", errors.New("no stack has a --public-url to route")
+	}
+	for _, n := range unrouted {
+		fmt.Printf("[tunnel#%s] no --public-url; tunnel %s does not route to this stack. Pass --public-url https://your.host\n", n, cfg.Tunnel)
+	}
+	cfg.Ingress = append(cfg.Ingress, ingressRule{Service: "http_status:404"})
+
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by `mcp-launch up` on every run; edits are overwritten.\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return "", err
+	}
+	dir := filepath.Join(ensureStateDir(), tunnelDirName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, badNameRe.ReplaceAllString(cfg.Tunnel, "-")+".yml")
+	if err := writeFileAtomic(path, buf.Bytes(), 0o644); err != nil {
+		return "", err
+	}
+	if verbose {
+		fmt.Printf("[tunnel] %s: %d hostname(s) → %s\n", cfg.Tunnel, len(routed), path)
+	}
+	return path, nil
+}
+
+// startNamedTunnels starts one cloudflared per tunnel name and records its PID on every stack
+// it serves.
+func startNamedTunnels(insts []*Instance, stream bool, logFile *os.File, verbose bool) {
+	var names []string
+	groups := map[string][]*Instance{}
+	for _, inst := range insts {
+		if inst.TunnelMode != "named" {
+			continue
+		}
+		if _, ok := groups[inst.TunnelName]; !ok {
+			names = append(names, inst.TunnelName)
+		}
+		groups[inst.TunnelName] = append(groups[inst.TunnelName], inst)
+	}
+	for _, name := range names {
+		group := groups[name]
+		cfgPath, err := writeIngressConfig(name, group, verbose)
+		if err != nil && verbose {
+			// A single stack without --public-url: cloudflared routes by its own config, as before.
+			fmt.Printf("[tunnel#%s] %v; cloudflared uses its own config\n", group[0].Name, err)
+		}
+		tags := make([]string, len(group))
+		for i, inst := range group {
+			tags[i] = "cloudflared#" + inst.Name
+		}
+		pid, err := startNamedTunnel(tags, name, cfgPath, stream, logFile)
+		if err != nil {
+			fmt.Printf("[tunnel#%s] Failed to start cloudflared: %v\n", group[0].Name, err)
+			continue
+		}
+		for _, inst := range group {
+			inst.setCloudflaredPID(pid)
+		}
+	}
+}
+
+// startNamedTunnel runs `cloudflared tunnel [--config FILE] run [NAME]` and returns its PID for
+// the caller to record. Its output goes to the log of every stack in tags.
+func startNamedTunnel(tags []string, name, configPath string, stream bool, logFile *os.File) (int, error) {
+	args := []string{"tunnel"}
+	if configPath != "" {
+		args = append(args, "--config", configPath)
+	}
+	args = append(args, "run")
+	if name != "" {
+		args = append(args, name)
+	}
+	cmd := exec.Command(findBinary("cloudflared"), args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	tee := func(line string) {
+		for _, t := range tags[1:] {
+			logProcLine(t, line)
+		}
+	}
+	go scanAndMaybeStream(tags[0], stdout, stream, logFile, tee)
+	go scanAndMaybeStream(tags[0], stderr, stream, logFile, tee)
+	return cmd.Process.Pid, nil
+}
+
+// tunnelPeers lists the other stacks served by the same cloudflared as inst.
+func tunnelPeers(inst Instance, all []Instance) []string {
+	var out []string
+	for _, o := range all {
+		if o.Name != inst.Name && inst.CloudflaredPID > 0 && o.CloudflaredPID == inst.CloudflaredPID {
+			out = append(out, o.Name)
+		}
+	}
+	return out
+}
+
+// tunnelStacks names every stack served by the cloudflared recorded on inst.
+func tunnelStacks(inst Instance, all []Instance) string {
+	return strings.Join(append([]string{inst.Name}, tunnelPeers(inst, all)...), ", ")
+}
@@ -0,0 +1,423 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ---------- `mcp-launch validate` ----------
+
+// configIssue is one problem found in a config file; Line/Col are 1-based (0 = whole file).
+type configIssue struct {
+	File    string
+	Line    int
+	Col     int
+	Warning bool
+	Msg     string
+}
+
+func (i configIssue) String() string {
+	loc := i.File
+	switch {
+	case i.Line > 0 && i.Col > 0:
+		loc = fmt.Sprintf("%s:%d:%d", i.File, i.Line, i.Col)
+	case i.Line > 0:
+		loc = fmt.Sprintf("%s:%d", i.File, i.Line)
+	}
+	level := "error"
+	if i.Warning {
+		level = "warning"
+	}
+	return fmt.Sprintf("%s: %s: %s", loc, level, i.Msg)
+}
+
+var (
+	knownConfigFields = []string{"mcpServers", "tools"}
+	knownServerFields = []string{"command", "args", "type", "url", "headers", "env", "envFile", "tools", "disabled", "connectTimeout", "connectRetries"}
+	knownFilterFields = []string{"include", "exclude"}
+
+	// Server names become /<name>/ path prefixes and <name>__ operationId prefixes.
+	serverNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+	// Front-proxy endpoints a server name must not shadow.
+	reservedServerNames = []string{"docs", "healthz", "readyz", "metrics", "mcp"}
+)
+
+// filePos is a 1-based line and column; the zero value means "somewhere in the file".
+type filePos struct{ line, col int }
+
+// configValidator collects issues for one file.
+type configValidator struct {
+	file   string
+	data   []byte
+	keys   map[string]filePos // "/mcpServers/time/command" → where that key is written
+	issues []configIssue
+}
+
+func (v *configValidator) atPos(p filePos, warning bool, format string, args ...any) {
+	v.issues = append(v.issues, configIssue{File: v.file, Line: p.line, Col: p.col, Warning: warning, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *configValidator) at(off int64, warning bool, format string, args ...any) {
+	line, col := lineCol(v.data, off)
+	v.atPos(filePos{line, col}, warning, format, args...)
+}
+
+func (v *configValidator) atKey(key string, warning bool, format string, args ...any) {
+	v.atPos(v.keys[key], warning, format, args...)
+}
+
+// validateConfigFile checks one config; it returns the enabled server names it declares.
+func validateConfigFile(file string) ([]configIssue, []string) {
+	v := &configValidator{file: file}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return []configIssue{{File: file, Msg: err.Error()}}, nil
+	}
+	v.data = data
+	format := configFormat(file)
+
+	js, err := configJSON(file, data)
+	if err != nil {
+		var tpe toml.ParseError
+		switch {
+		case errors.As(err, &tpe):
+			v.atPos(filePos{tpe.Position.Line, tpe.Position.Col}, false, "invalid TOML: %s", tpe.Message)
+		case format == formatYAML:
+			pos := filePos{}
+			if m := yamlLineRe.FindStringSubmatch(err.Error()); m != nil {
+				pos.line, _ = strconv.Atoi(m[1])
+			}
+			v.atPos(pos, false, "invalid YAML: %s", strings.TrimPrefix(err.Error(), "yaml: "))
+		default:
+			v.reportJSONError(err, "")
+		}
+		return v.issues, nil
+	}
+	v.keys = keyPositions(data, format)
+	var cfg MCPConfig
+	if err := json.Unmarshal(js, &cfg); err != nil {
+		v.reportJSONError(err, format)
+		return v.issues, nil
+	}
+
+	var doc any
+	_ = json.Unmarshal(js, &doc)
+	for _, k := range docKeys(doc, "") {
+		parts := strings.Split(strings.TrimPrefix(k, "/"), "/")
+		switch {
+		case len(parts) == 1 && !slices.Contains(knownConfigFields, parts[0]):
+			v.atKey(k, true, "unknown field %q (ignored)", parts[0])
+		case len(parts) == 3 && parts[0] == "mcpServers" && !slices.Contains(knownServerFields, parts[2]):
+			v.atKey(k, true, "server %q: unknown field %q (ignored)", parts[1], parts[2])
+		case len(parts) == 2 && parts[0] == "tools" && !slices.Contains(knownFilterFields, parts[1]):
+			v.atKey(k, true, "tools: unknown field %q", parts[1])
+		case len(parts) == 4 && parts[0] == "mcpServers" && parts[2] == "tools" && !slices.Contains(knownFilterFields, parts[3]):
+			v.atKey(k, true, "server %q: tools: unknown field %q", parts[1], parts[3])
+		}
+	}
+
+	if len(cfg.MCPServers) == 0 {
+		v.atKey("/mcpServers", false, "no servers: \"mcpServers\" is missing or empty")
+	} else if len(cfg.enabled().MCPServers) == 0 {
+		v.atKey("/mcpServers", false, "no servers: every server is disabled")
+	}
+	v.checkFilter("/tools", "tools", cfg.Tools)
+
+	names := make([]string, 0, len(cfg.MCPServers))
+	for name := range cfg.MCPServers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	relDir := filepath.Dir(file)
+	var enabled []string
+	for _, name := range names {
+		v.checkServer(name, cfg.MCPServers[name], relDir)
+		if !cfg.MCPServers[name].Disabled {
+			enabled = append(enabled, name)
+		}
+	}
+
+	slices.SortStableFunc(v.issues, func(a, b configIssue) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Col - b.Col
+	})
+	return v.issues, enabled
+}
+
+func (v *configValidator) checkServer(name string, s MCPServer, relDir string) {
+	key := "/mcpServers/" + name
+	switch {
+	case !serverNameRe.MatchString(name):
+		v.atKey(key, false, "server %q: invalid name (use letters, digits, '-' and '_'; it becomes the /%s/ path and operationId prefix)", name, name)
+	case strings.Contains(name, "__"):
+		v.atKey(key, false, "server %q: name must not contain \"__\" (the operationId separator)", name)
+	case slices.Contains(reservedServerNames, strings.ToLower(name)):
+		v.atKey(key, false, "server %q: name clashes with the front proxy's /%s endpoint", name, name)
+	}
+	if s.Disabled {
+		return // not started, so its command, env and url need not resolve yet
+	}
+
+	resolved, err := resolveServer(name, s, relDir)
+	if err != nil {
+		v.atKey(key, false, "%v", err)
+		resolved = s
+	}
+	switch s.Type {
+	case "", "stdio":
+		if s.Command == "" {
+			v.atKey(key, false, "server %q: \"command\" is required for stdio servers (or set \"type\": \"sse\" | \"streamable-http\" with a \"url\")", name)
+		} else if err == nil && !strings.Contains(resolved.Command, "${") {
+			if _, lerr := exec.LookPath(resolved.Command); lerr != nil {
+				v.atKey(key+"/command", false, "server %q: executable %q not found in PATH", name, resolved.Command)
+			}
+		}
+		if s.URL != "" {
+			v.atKey(key+"/url", true, "server %q: \"url\" is ignored for stdio servers", name)
+		}
+	case "sse", "streamable-http":
+		if s.URL == "" {
+			v.atKey(key, false, "server %q: \"url\" is required for type %q", name, s.Type)
+		} else if err == nil {
+			if u, perr := url.Parse(resolved.URL); perr != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.atKey(key+"/url", false, "server %q: \"url\" must be an absolute http(s) URL", name)
+			}
+		}
+		if s.Command != "" {
+			v.atKey(key+"/command", true, "server %q: \"command\" is ignored for type %q", name, s.Type)
+		}
+	default:
+		v.atKey(key+"/type", false, "server %q: unknown type %q (expected stdio, sse or streamable-http)", name, s.Type)
+	}
+	if s.ConnectTimeout != "" {
+		if d, derr := time.ParseDuration(s.ConnectTimeout); derr != nil || d <= 0 {
+			v.atKey(key+"/connectTimeout", false, "server %q: connectTimeout %q is not a positive duration (e.g. \"10s\")", name, s.ConnectTimeout)
+		}
+	}
+	if s.ConnectRetries != nil && *s.ConnectRetries < 0 {
+		v.atKey(key+"/connectRetries", false, "server %q: connectRetries must not be negative", name)
+	}
+	v.checkFilter(key+"/tools", fmt.Sprintf("server %q: tools", name), s.Tools)
+}
+
+func (v *configValidator) checkFilter(key, label string, f *ToolFilter) {
+	if f == nil {
+		return
+	}
+	for _, p := range append(slices.Clone(f.Include), f.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			v.atKey(key, false, "%s: bad pattern %q: %v", label, p, err)
+		}
+	}
+}
+
+// validateConfigs checks every config of one run, including server names repeated across them.
+func validateConfigs(files []string) []configIssue {
+	var all []configIssue
+	seen := map[string]string{} // server → first config declaring it
+	for _, f := range files {
+		issues, names := validateConfigFile(f)
+		all = append(all, issues...)
+		for _, n := range names {
+			if first, dup := seen[n]; dup {
+				all = append(all, configIssue{File: f, Warning: true, Msg: fmt.Sprintf("server %q is also defined in %s (each stack runs its own copy)", n, first)})
+				continue
+			}
+			seen[n] = f
+		}
+	}
+	return all
+}
+
+func countIssues(issues []configIssue) (errs, warns int) {
+	for _, i := range issues {
+		if i.Warning {
+			warns++
+		} else {
+			errs++
+		}
+	}
+	return errs, warns
+}
+
+func cmdValidate() {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Usage = func() { helpTopic("validate") }
+	var configs stringSlice
+	fs.Var(&configs, "config", "Config file to check (repeatable; default mcp.config.json)")
+	strict := fs.Bool("strict", false, "Exit non-zero on warnings too")
+	_ = fs.Parse(os.Args[2:])
+	configs = append(configs, fs.Args()...)
+	if len(configs) == 0 {
+		configs = append(configs, defaultConfigPath())
+	}
+
+	issues := validateConfigs(configs)
+	for _, i := range issues {
+		fmt.Println(i)
+	}
+	errs, warns := countIssues(issues)
+	if errs == 0 && warns == 0 {
+		fmt.Printf("OK: %d config(s) valid.\n", len(configs))
+		return
+	}
+	fmt.Printf("%d error(s), %d warning(s) in %d config(s).\n", errs, warns, len(configs))
+	if errs > 0 || *strict {
+		os.Exit(1)
+	}
+}
+
+// reportJSONError locates syntax/type errors; for YAML/TOML (format != "") type errors are
+// placed at the offending key, since offsets refer to the translated JSON.
+func (v *configValidator) reportJSONError(err error, format string) {
+	var syn *json.SyntaxError
+	var typ *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syn):
+		// Offset counts the bytes read, including the offending one.
+		v.at(syn.Offset-1, false, "invalid JSON: %v", syn)
+	case errors.As(err, &typ) && format != "" && format != formatJSON:
+		v.atKey("/"+strings.ReplaceAll(typ.Field, ".", "/"), false, "%s: expected %s, got %s", typ.Field, typ.Type, typ.Value)
+	case errors.As(err, &typ):
+		// Offset is past the value; the key is where the user looks.
+		if p, ok := v.keys["/"+strings.ReplaceAll(typ.Field, ".", "/")]; ok {
+			v.atPos(p, false, "%s: expected %s, got %s", typ.Field, typ.Type, typ.Value)
+			break
+		}
+		v.at(typ.Offset, false, "%s: expected %s, got %s", typ.Field, typ.Type, typ.Value)
+	default:
+		v.atPos(filePos{}, false, "invalid config: %v", err)
+	}
+}
+
+var yamlLineRe = regexp.MustCompile(`line (\d+)`)
+
+// keyPositions locates every key path in a config. TOML keys have no positions (file-level issues).
+func keyPositions(data []byte, format string) map[string]filePos {
+	out := map[string]filePos{}
+	switch format {
+	case formatJSON:
+		for k, off := range keyOffsets(data) {
+			line, col := lineCol(data, off)
+			out[k] = filePos{line, col}
+		}
+	case formatYAML:
+		var root yaml.Node
+		if yaml.Unmarshal(data, &root) == nil {
+			yamlKeyPositions(&root, "", out)
+		}
+	}
+	return out
+}
+
+func yamlKeyPositions(n *yaml.Node, prefix string, out map[string]filePos) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			yamlKeyPositions(c, prefix, out)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i]
+			p := prefix + "/" + k.Value
+			out[p] = filePos{k.Line, k.Column}
+			yamlKeyPositions(n.Content[i+1], p, out)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			yamlKeyPositions(c, fmt.Sprintf("%s/%d", prefix, i), out)
+		}
+	}
+}
+
+// docKeys lists the object key paths of a decoded config ("/mcpServers/time/args").
+func docKeys(doc any, prefix string) []string {
+	var out []string
+	switch n := doc.(type) {
+	case map[string]any:
+		for k, c := range n {
+			p := prefix + "/" + k
+			out = append(out, p)
+			out = append(out, docKeys(c, p)...)
+		}
+	case []any:
+		for i, c := range n {
+			out = append(out, docKeys(c, fmt.Sprintf("%s/%d", prefix, i))...)
+		}
+	}
+	return out
+}
+
+// keyOffsets maps every object key path ("/a/b/0/c") in valid JSON to the key's byte offset.
+func keyOffsets(data []byte) map[string]int64 {
+	out := map[string]int64{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var walk func(prefix string) error
+	walk = func(prefix string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		d, ok := tok.(json.Delim)
+		if !ok {
+			return nil
+		}
+		for i := 0; dec.More(); i++ {
+			p := fmt.Sprintf("%s/%d", prefix, i)
+			if d == '{' {
+				off := skipSeparators(data, dec.InputOffset())
+				kt, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				k, _ := kt.(string)
+				p = prefix + "/" + k
+				out[p] = off
+			}
+			if err := walk(p); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token() // closing delimiter
+		return err
+	}
+	_ = walk("")
+	return out
+}
+
+// skipSeparators advances past whitespace and commas to the next token.
+func skipSeparators(data []byte, off int64) int64 {
+	for off < int64(len(data)) && strings.IndexByte(" \t\r\n,", data[off]) >= 0 {
+		off++
+	}
+	return off
+}
+
+// lineCol converts a byte offset to a 1-based line and column.
+func lineCol(data []byte, off int64) (int, int) {
+	off = min(max(off, 0), int64(len(data)))
+	before := data[:off]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(off) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
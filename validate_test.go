@@ -0,0 +1,74 @@
+// Copyright
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateConfigFilePositions(t *testing.T) {
+	tests := []struct {
+		name, file, data string
+		want             []string
+	}{
+		{"json syntax", "c.json", "{\n  \"mcpServers\": {\n    \"a\": {\"command\": \"sh\",}\n  }\n}\n",
+			[]string{"c.json:3:27: error: invalid JSON: invalid character '}' looking for beginning of object key string"}},
+		{"json type", "c.json", "{\n  \"mcpServers\": {\n    \"a\": {\"command\": \"sh\", \"args\": \"-c\"}\n  }\n}\n",
+			[]string{"c.json:3:28: error: mcpServers.a.args: expected []string, got string"}},
+		{"json unknown fields", "c.json", "{\n  \"mcpServers\": {\n    \"a\": {\"command\": \"sh\",\n          \"cmd\": \"x\"}\n  },\n  \"extra\": 1\n}\n",
+			[]string{
+				`c.json:4:11: warning: server "a": unknown field "cmd" (ignored)`,
+				`c.json:6:3: warning: unknown field "extra" (ignored)`,
+			}},
+		{"json bad url", "c.json", "{\"mcpServers\": {\n  \"r\": {\n    \"type\": \"sse\",\n    \"url\": \"ftp://x\"\n  }\n}}\n",
+			[]string{`c.json:4:5: error: server "r": "url" must be an absolute http(s) URL`}},
+		{"yaml syntax", "c.yaml", "mcpServers:\n  a:\n    command: sh\n  b: @x\n",
+			[]string{"c.yaml:4: error: invalid YAML: line 4: found character that cannot start any token"}},
+		{"yaml duplicate key", "c.yaml", "mcpServers:\n  a:\n    command: sh\n    command: sh\n",
+			[]string{"c.yaml:4: error: invalid YAML: unmarshal errors:\n  line 4: mapping key \"command\" already defined at line 3"}},
+		{"yaml unknown field", "c.yaml", "mcpServers:\n  a:\n    command: sh\n    tools:\n      only: [x]\n",
+			[]string{`c.yaml:5:7: warning: server "a": tools: unknown field "only"`}},
+		{"yaml type", "c.yaml", "mcpServers:\n  a:\n    command: sh\n    args: -c\n",
+			[]string{"c.yaml:4:5: error: mcpServers.a.args: expected []string, got string"}},
+		{"toml syntax", "c.toml", "[mcpServers.a]\ncommand = \"sh\"\nargs = [\n",
+			[]string{"c.toml:3:9: error: invalid TOML: unexpected EOF; expected value"}},
+		{"toml keys have no position", "c.toml", "[mcpServers.a]\ncommand = \"sh\"\ncmd = \"x\"\n",
+			[]string{`c.toml: warning: server "a": unknown field "cmd" (ignored)`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(file, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			issues, _ := validateConfigFile(file)
+			var got []string
+			for _, i := range issues {
+				i.File = tt.file
+				got = append(got, i.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issues:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineCol(t *testing.T) {
+	data := []byte("ab\ncd\n\nef")
+	tests := []struct {
+		off       int64
+		line, col int
+	}{
+		{0, 1, 1}, {1, 1, 2}, {3, 2, 1}, {4, 2, 2}, {6, 3, 1}, {7, 4, 1}, {-5, 1, 1}, {100, 4, 3},
+	}
+	for _, tt := range tests {
+		if line, col := lineCol(data, tt.off); line != tt.line || col != tt.col {
+			t.Errorf("lineCol(%d) = %d:%d, want %d:%d", tt.off, line, col, tt.line, tt.col)
+		}
+	}
+}